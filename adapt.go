@@ -0,0 +1,48 @@
+package caddyunmarshal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+)
+
+// AdaptedJSON renders the given module as the JSON fragment that the
+// Caddyfile adapter would place into the config, indented the same way as
+// "caddy adapt --pretty" does. This allows tests to verify the adapted output
+// of a parsed struct without adapting a whole Caddyfile.
+//
+// If inlineKey is not empty, then the module name is inlined into the object
+// under that key, e.g. "handler" for HTTP handlers, which is what
+// caddyconfig.JSONModuleObject does. Otherwise, the module is wrapped into a
+// module map keyed by its name, which is how matchers and encoders are
+// placed.
+func AdaptedJSON(m caddy.Module, inlineKey string) ([]byte, error) {
+	name := m.CaddyModule().ID.Name()
+
+	var warnings []caddyconfig.Warning
+	var raw json.RawMessage
+	if inlineKey != "" {
+		raw = caddyconfig.JSONModuleObject(m, inlineKey, name, &warnings)
+	} else {
+		raw = caddyconfig.JSON(caddy.ModuleMap{name: caddyconfig.JSON(m, &warnings)}, &warnings)
+	}
+
+	if len(warnings) > 0 {
+		msgs := make([]string, len(warnings))
+		for i, warning := range warnings {
+			msgs[i] = warning.Message
+		}
+		return nil, fmt.Errorf("caddyunmarshal: cannot encode module %s: %s", name, strings.Join(msgs, "; "))
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "\t"); err != nil {
+		return nil, fmt.Errorf("caddyunmarshal: cannot indent module %s: %w", name, err)
+	}
+
+	return buf.Bytes(), nil
+}
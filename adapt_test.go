@@ -0,0 +1,39 @@
+package caddyunmarshal
+
+import "testing"
+
+func TestAdaptedJSON(t *testing.T) {
+	h, err := unmarshalString[testHandler](`caddyunmarshal_test "hello world" 201`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := AdaptedJSON(&h, "handler")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = `{
+	"body": "hello world",
+	"handler": "caddyunmarshal_test",
+	"status": 201
+}`
+	if string(b) != expect {
+		t.Errorf("unexpected JSON:\n%s\nwant:\n%s", b, expect)
+	}
+
+	b, err = AdaptedJSON(&h, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expectMap = `{
+	"caddyunmarshal_test": {
+		"body": "hello world",
+		"status": 201
+	}
+}`
+	if string(b) != expectMap {
+		t.Errorf("unexpected JSON:\n%s\nwant:\n%s", b, expectMap)
+	}
+}
@@ -0,0 +1,29 @@
+package caddyunmarshal
+
+import "github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+
+// UnmarshalAll unmarshals each occurrence of the directive in the remaining
+// tokens of the given dispenser into its own T, in order. It replaces the
+// usual loop over d.Next() of directives that may be given several times,
+// e.g.
+//
+//	log access {
+//		output stdout
+//	}
+//	log errors {
+//		output stderr
+//	}
+//
+// returns one T for each log. Unmarshaling stops at the first error. See
+// UnmarshalParallel for unmarshaling very large Caddyfiles.
+func UnmarshalAll[T any](d *caddyfile.Dispenser) ([]T, error) {
+	var values []T
+	for d.Next() {
+		var v T
+		if err := Unmarshal(d, &v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
@@ -0,0 +1,51 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type allLog struct {
+	Name   string `caddyfile:"$1"`
+	Output string `caddyfile:"output"`
+	Level  string `caddyfile:"level"`
+}
+
+func TestUnmarshalAll(t *testing.T) {
+	const input = "log access {\n\toutput stdout\n}\nlog errors {\n\toutput stderr\n\tlevel error\n}\nlog debug"
+
+	logs, err := UnmarshalAll[allLog](caddyfile.NewTestDispenser(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []allLog{
+		{Name: "access", Output: "stdout"},
+		{Name: "errors", Output: "stderr", Level: "error"},
+		{Name: "debug"},
+	}
+	if !reflect.DeepEqual(logs, expect) {
+		t.Errorf("expected %+v, got %+v", expect, logs)
+	}
+
+	parallel, err := UnmarshalParallel[allLog](caddyfile.NewTestDispenser(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parallel, logs) {
+		t.Errorf("expected UnmarshalParallel to agree, got %+v", parallel)
+	}
+
+	logs, err = UnmarshalAll[allLog](caddyfile.NewTestDispenser(""))
+	if err != nil || len(logs) != 0 {
+		t.Errorf("expected no logs, got %+v, %v", logs, err)
+	}
+
+	_, err = UnmarshalAll[allLog](caddyfile.NewTestDispenser("log access\nlog"))
+	if !errors.Is(err, ErrMissingRequired) {
+		t.Errorf("expected missing required error, got %v", err)
+	}
+}
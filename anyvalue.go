@@ -0,0 +1,65 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+// defaultTry is the order in which values of any-typed fields are parsed if
+// the try option isn't given.
+var defaultTry = []string{"int", "float", "duration", "bool", "string"}
+
+// anyParsers parses a value for any-typed fields. Each parser is named after
+// the type it produces in the try option: int, float64, time.Duration (using
+// the caddy syntax, which allows days), bool and string.
+var anyParsers = map[string]func(raw string) (any, error){
+	"int": func(raw string) (any, error) {
+		return strconv.Atoi(raw)
+	},
+	"float": func(raw string) (any, error) {
+		return strconv.ParseFloat(raw, 64)
+	},
+	"duration": func(raw string) (any, error) {
+		return caddy.ParseDuration(raw)
+	},
+	"bool": func(raw string) (any, error) {
+		return parseBool(raw, nil)
+	},
+	"string": func(raw string) (any, error) {
+		return raw, nil
+	},
+}
+
+// isAny returns true if the given type is the empty interface.
+func isAny(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface && t.NumMethod() == 0
+}
+
+// parseAny parses the value of an any-typed field by trying each parser
+// listed in the try option in order, e.g. try=duration|string, and returns the
+// first successfully parsed value. The list may also be separated by commas if
+// it's quoted, e.g. try='int,string'. Without the try option, defaultTry is
+// used.
+func parseAny(raw string, opts []string) (any, error) {
+	try := defaultTry
+	if v, ok := optValue(opts, "try"); ok {
+		try = strings.FieldsFunc(v, func(r rune) bool { return r == '|' || r == ',' })
+	}
+
+	for _, name := range try {
+		parse, ok := anyParsers[name]
+		if !ok {
+			return nil, fmt.Errorf("caddyunmarshal: unknown type %q in try option", name)
+		}
+
+		if v, err := parse(raw); err == nil {
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot parse %q as any of: %s", raw, strings.Join(try, ", "))
+}
@@ -0,0 +1,43 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalAny(t *testing.T) {
+	type cert struct {
+		Lifetime any   `caddyfile:"lifetime,try=duration|string"`
+		Quoted   any   `caddyfile:"quoted,try='bool,int'"`
+		Guess    []any `caddyfile:"guess"`
+	}
+
+	v, err := unmarshalString[cert]("cert {\n lifetime 5m\n quoted 1\n guess 10 1.5 1h on hello\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := cert{
+		Lifetime: 5 * time.Minute,
+		Quoted:   true,
+		Guess:    []any{10, 1.5, time.Hour, true, "hello"},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %#v\nwant %#v", v, expect)
+	}
+
+	v, err = unmarshalString[cert]("cert {\n lifetime unlimited\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Lifetime != "unlimited" {
+		t.Errorf("unexpected lifetime: %#v", v.Lifetime)
+	}
+
+	_, err = unmarshalString[cert]("cert {\n quoted maybe\n}")
+	if err == nil || !strings.Contains(err.Error(), `cannot parse "maybe" as any of: bool, int`) {
+		t.Errorf("expected error, got %v", err)
+	}
+}
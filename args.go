@@ -0,0 +1,46 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+// importArgRe matches the placeholders of import arguments, which are written
+// as {args[0]} or, in older Caddyfiles, as {args.0}.
+var importArgRe = regexp.MustCompile(`\{args(?:\[(\d+)\]|\.(\d+))\}`)
+
+// replaceImportArgs replaces the placeholders of the given import arguments
+// within raw. Placeholders of missing arguments are left as-is.
+func replaceImportArgs(raw string, args []string) string {
+	if len(args) == 0 {
+		return raw
+	}
+
+	return importArgRe.ReplaceAllStringFunc(raw, func(placeholder string) string {
+		m := importArgRe.FindStringSubmatch(placeholder)
+		ix, err := strconv.Atoi(m[1] + m[2])
+		if err != nil || ix >= len(args) {
+			return placeholder
+		}
+		return args[ix]
+	})
+}
+
+// checkImportArgs returns an error if raw still has the placeholder of an
+// import argument while the value isn't a string, which would otherwise fail
+// to parse with a confusing error. Such placeholders are left in tokens whose
+// snippet is imported without the argument, and are only kept as-is in
+// strings, e.g. for ReplacerFields.
+func checkImportArgs(r reflectValue, raw string) error {
+	if r.v.Kind() == reflect.String || isAny(r.t) {
+		return nil
+	}
+
+	if placeholder := importArgRe.FindString(raw); placeholder != "" {
+		return fmt.Errorf("%w %s, which the import doesn't pass", ErrUnresolvedImportArg, placeholder)
+	}
+
+	return nil
+}
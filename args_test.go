@@ -0,0 +1,66 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type argsUpstream struct {
+	Host    string        `caddyfile:"$1"`
+	Port    int           `caddyfile:"port"`
+	Timeout time.Duration `caddyfile:"timeout"`
+}
+
+func TestUnmarshalImportArgs(t *testing.T) {
+	const input = "upstream {args[0]} {\n port {args.1}\n timeout {args[2]}\n}"
+
+	v, err := unmarshalStringWithOptions[argsUpstream](input, Options{
+		ImportArgs: []string{"localhost", "8080", "5s"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := argsUpstream{Host: "localhost", Port: 8080, Timeout: 5 * time.Second}
+	if v != expect {
+		t.Errorf("expected %+v, got %+v", expect, v)
+	}
+
+	_, err = unmarshalStringWithOptions[argsUpstream](input, Options{
+		ImportArgs: []string{"localhost"},
+	})
+	if !errors.Is(err, ErrUnresolvedImportArg) || !strings.Contains(err.Error(), "unresolved import argument {args.1}") {
+		t.Errorf("expected unresolved import argument error, got %v", err)
+	}
+
+	v, err = unmarshalString[argsUpstream]("upstream {args[0]}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Host != "{args[0]}" {
+		t.Errorf("expected placeholder to be kept in string, got %q", v.Host)
+	}
+}
+
+func TestUnmarshalCaddyfileImportArgs(t *testing.T) {
+	type config struct {
+		Upstreams []argsUpstream `caddyfile:"upstream"`
+	}
+
+	const snippet = "(upstream) {\n\tupstream {args.0} {\n\t\tport {args.1}\n\t}\n}\n"
+
+	var v config
+	if err := UnmarshalCaddyfile("Caddyfile", []byte(snippet+"import upstream a 80\n"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Upstreams) != 1 || v.Upstreams[0] != (argsUpstream{Host: "a", Port: 80}) {
+		t.Errorf("unexpected upstreams %+v", v.Upstreams)
+	}
+
+	err := UnmarshalCaddyfile("Caddyfile", []byte(snippet+"import upstream a\n"), &v)
+	if !errors.Is(err, ErrUnresolvedImportArg) {
+		t.Errorf("expected unresolved import argument error, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package caddyunmarshal
+
+import "fmt"
+
+// unmarshalArray unmarshals the current argument and the ones following it on
+// the same line into the fixed-size array r, e.g. "range 1 10" into a [2]int.
+// The array takes exactly as many arguments as its length.
+func unmarshalArray(d dispenser, r reflectValue, opts []string) error {
+	n := r.t.Len()
+	for i := 0; i < n; i++ {
+		if i > 0 && !d.NextArg() {
+			return d.WrapErr(fmt.Errorf("expected %d arguments, got %d", n, i))
+		}
+
+		elem := reflectValue{r.v.Index(i), r.t.Elem()}
+		if err := unmarshalValue(d, elem, d.Val(), opts); err != nil {
+			return errorAtIndex(i, err)
+		}
+	}
+
+	return nil
+}
+
+// unmarshalArrayLine unmarshals the remaining arguments on the current line
+// into the fixed-size array r.
+func unmarshalArrayLine(d dispenser, r reflectValue, opts []string) error {
+	n := r.t.Len()
+	if !d.NextArg() {
+		return d.WrapErr(fmt.Errorf("expected %d arguments, got 0", n))
+	}
+
+	if err := unmarshalArray(d, r, opts); err != nil {
+		return err
+	}
+
+	if extra := len(d.RemainingArgs()); extra > 0 {
+		return d.WrapErr(fmt.Errorf("expected %d arguments, got %d", n, n+extra))
+	}
+
+	return nil
+}
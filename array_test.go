@@ -0,0 +1,70 @@
+package caddyunmarshal
+
+import (
+	"strings"
+	"testing"
+)
+
+type arrayTarget struct {
+	Pair  [2]string `caddyfile:"$1"`
+	Name  string    `caddyfile:"$2,optional"`
+	Range [2]int    `caddyfile:"range"`
+	RGB   [3]uint8  `caddyfile:"rgb"`
+}
+
+func TestUnmarshalArray(t *testing.T) {
+	v, err := unmarshalString[arrayTarget](`
+		target a b name {
+			range 1 10
+			rgb 255 128 0
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := arrayTarget{
+		Pair:  [2]string{"a", "b"},
+		Name:  "name",
+		Range: [2]int{1, 10},
+		RGB:   [3]uint8{255, 128, 0},
+	}
+	if v != expect {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+}
+
+func TestUnmarshalArrayErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"too few", "target a b {\n range 1\n}", "expected 2 arguments, got 1"},
+		{"too many", "target a b {\n range 1 2 3 4\n}", "expected 2 arguments, got 4"},
+		{"none", "target a b {\n range\n}", "expected 2 arguments, got 0"},
+		{"positional too few", "target a", "expected 2 arguments, got 1"},
+		{"invalid element", "target a b {\n rgb 1 2 300\n}", "target > rgb > [2]: Testfile:2 - Error during parsing: cannot parse uint: 300 is out of range"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := unmarshalString[arrayTarget](test.input)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("unexpected error:\ngot  %v\nwant %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestUsageArray(t *testing.T) {
+	usage, err := Usage[arrayTarget]("target")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "target <pair> [<name>] {\n\trange <int> <int>\n\trgb <uint> <uint> <uint>\n}\n"
+	if usage != expect {
+		t.Errorf("unexpected usage:\n%s", usage)
+	}
+}
@@ -0,0 +1,38 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// boolLiterals maps the boolean spellings commonly used in Caddyfiles to their
+// values, on top of what strconv.ParseBool accepts.
+var boolLiterals = map[string]bool{
+	"on":       true,
+	"off":      false,
+	"yes":      true,
+	"no":       false,
+	"enable":   true,
+	"disable":  false,
+	"enabled":  true,
+	"disabled": false,
+}
+
+// parseBool parses a boolean argument. Both the spellings accepted by
+// strconv.ParseBool and the ones in boolLiterals are allowed, unless the bool
+// option restricts them, e.g. bool=on|off only allows "on" and "off".
+func parseBool(raw string, opts []string) (bool, error) {
+	if allowed, ok := optValue(opts, "bool"); ok {
+		forms := strings.Split(allowed, "|")
+		if !hasOpt(forms, raw) {
+			return false, fmt.Errorf("expected one of: %s", strings.Join(forms, ", "))
+		}
+	}
+
+	if v, ok := boolLiterals[strings.ToLower(raw)]; ok {
+		return v, nil
+	}
+
+	return strconv.ParseBool(raw)
+}
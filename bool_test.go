@@ -0,0 +1,97 @@
+package caddyunmarshal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBool(t *testing.T) {
+	tests := []struct {
+		in     string
+		opts   []string
+		expect bool
+		err    bool
+	}{
+		{"true", nil, true, false},
+		{"0", nil, false, false},
+		{"on", nil, true, false},
+		{"Off", nil, false, false},
+		{"yes", nil, true, false},
+		{"no", nil, false, false},
+		{"enable", nil, true, false},
+		{"disabled", nil, false, false},
+		{"maybe", nil, false, true},
+		{"on", []string{"bool=on|off"}, true, false},
+		{"off", []string{"bool=on|off"}, false, false},
+		{"true", []string{"bool=on|off"}, false, true},
+	}
+
+	for _, test := range tests {
+		v, err := parseBool(test.in, test.opts)
+		if test.err {
+			if err == nil {
+				t.Errorf("parseBool(%q, %q): expected error, got %v", test.in, test.opts, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseBool(%q, %q): %v", test.in, test.opts, err)
+			continue
+		}
+		if v != test.expect {
+			t.Errorf("parseBool(%q, %q) = %v, want %v", test.in, test.opts, v, test.expect)
+		}
+	}
+}
+
+func TestUnmarshalBoolArgument(t *testing.T) {
+	type toggle struct {
+		Enabled bool `caddyfile:"$1"`
+		Strict  bool `caddyfile:"$2,optional,bool=on|off"`
+	}
+
+	v, err := unmarshalString[toggle]("toggle enable on")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != (toggle{true, true}) {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	if _, err := unmarshalString[toggle]("toggle yes true"); err == nil {
+		t.Error("expected error for restricted bool spelling")
+	}
+}
+
+func TestUnmarshalBoolSubdirective(t *testing.T) {
+	type server struct {
+		Compress bool `caddyfile:"compress"`
+		Strict   bool `caddyfile:"strict,bool=on|off"`
+	}
+
+	tests := []struct {
+		input  string
+		expect server
+	}{
+		{"server {\n compress\n}", server{Compress: true}},
+		{"server {\n compress true\n}", server{Compress: true}},
+		{"server {\n compress false\n}", server{}},
+		{"server {\n compress off\n strict on\n}", server{Strict: true}},
+	}
+
+	for _, test := range tests {
+		v := server{Compress: true}
+		if err := UnmarshalString("server", test.input, &v); err != nil {
+			t.Fatalf("%q: %v", test.input, err)
+		}
+		if v != test.expect {
+			t.Errorf("%q: unexpected value: %+v", test.input, v)
+		}
+	}
+
+	_, err := unmarshalString[server]("server {\n strict yes\n}")
+	if err == nil || !strings.Contains(err.Error(), "expected one of: on, off") {
+		t.Errorf("expected restricted bool error, got %v", err)
+	}
+}
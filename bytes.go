@@ -0,0 +1,91 @@
+package caddyunmarshal
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// decodeBytes decodes the raw value of a []byte field according to the
+// encoding option, which is either base64 or hex, e.g.
+// `caddyfile:"key,encoding=base64"`. Without the option,
+// the value is taken as is. The value itself is left out of errors, since
+// encoded values are often secrets.
+func decodeBytes(raw string, opts []string) ([]byte, error) {
+	encoding, _ := optValue(opts, "encoding")
+
+	switch encoding {
+	case "":
+		return []byte(raw), nil
+
+	case "base64":
+		// Accept both the standard and the URL-safe alphabet, with or without
+		// padding.
+		enc := base64.StdEncoding
+		if strings.ContainsAny(raw, "-_") {
+			enc = base64.URLEncoding
+		}
+		if !strings.HasSuffix(raw, "=") {
+			enc = enc.WithPadding(base64.NoPadding)
+		}
+
+		b, err := enc.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 value: %w", err)
+		}
+		return b, nil
+
+	case "hex":
+		b, err := hex.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex value: %w", err)
+		}
+		return b, nil
+
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// encodeBytes is the inverse of decodeBytes. Base64 values are encoded using
+// the standard alphabet with padding.
+func encodeBytes(b []byte, opts []string) (string, error) {
+	encoding, _ := optValue(opts, "encoding")
+
+	switch encoding {
+	case "":
+		return string(b), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(b), nil
+	case "hex":
+		return hex.EncodeToString(b), nil
+	default:
+		return "", fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// isEncodedArray returns true if t is a fixed-size byte array with the
+// encoding option, e.g. a [32]byte key. Unlike other arrays, it takes a single
+// encoded argument.
+func isEncodedArray(t reflect.Type, opts []string) bool {
+	_, ok := optValue(opts, "encoding")
+	return ok && t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8
+}
+
+// decodeArray decodes the raw value into the fixed-size byte array r, whose
+// length the decoded value must match.
+func decodeArray(r reflectValue, raw string, opts []string) error {
+	b, err := decodeBytes(raw, opts)
+	if err != nil {
+		return err
+	}
+
+	if len(b) != r.t.Len() {
+		return fmt.Errorf("invalid length: expected %d bytes, got %d", r.t.Len(), len(b))
+	}
+
+	reflect.Copy(r.v, reflect.ValueOf(b))
+	return nil
+}
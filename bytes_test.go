@@ -0,0 +1,117 @@
+package caddyunmarshal
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeBytes(t *testing.T) {
+	tests := []struct {
+		raw    string
+		opts   []string
+		expect string
+	}{
+		{"plain", nil, "plain"},
+		{"aGVsbG8=", []string{"encoding=base64"}, "hello"},
+		{"aGVsbG8", []string{"encoding=base64"}, "hello"},
+		{"-_8=", []string{"encoding=base64"}, "\xfb\xff"},
+		{"+/8", []string{"encoding=base64"}, "\xfb\xff"},
+	}
+
+	for _, test := range tests {
+		b, err := decodeBytes(test.raw, test.opts)
+		if err != nil {
+			t.Errorf("decodeBytes(%q): %v", test.raw, err)
+			continue
+		}
+		if string(b) != test.expect {
+			t.Errorf("decodeBytes(%q) = %q, want %q", test.raw, b, test.expect)
+		}
+	}
+
+	if _, err := decodeBytes("x", []string{"encoding=rot13"}); err == nil {
+		t.Error("expected error for unknown encoding")
+	}
+}
+
+func TestUnmarshalBytes(t *testing.T) {
+	type target struct {
+		Key    []byte          `caddyfile:"$1,encoding=base64"`
+		Secret []byte          `caddyfile:"secret,encoding=base64"`
+		Raw    json.RawMessage `caddyfile:"raw"`
+		Keys   [][]byte        `caddyfile:"keys,encoding=base64"`
+	}
+
+	v, err := unmarshalString[target](`
+		target aGVsbG8= {
+			secret c2VjcmV0
+			raw {"a":1}
+			keys YQ== Yg==
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(v.Key) != "hello" || string(v.Secret) != "secret" || string(v.Raw) != `{"a":1}` {
+		t.Errorf("unexpected value: %+v", v)
+	}
+	if !bytes.Equal(bytes.Join(v.Keys, nil), []byte("ab")) {
+		t.Errorf("unexpected keys: %q", v.Keys)
+	}
+
+	_, err = unmarshalString[target]("target aGVsbG8= {\n secret not*base64\n}")
+	const expect = "Testfile:2 - Error during parsing: invalid base64 value: illegal base64 data at input byte 3"
+	if err == nil || !strings.HasSuffix(err.Error(), expect) {
+		t.Errorf("unexpected error:\ngot  %v\nwant %s", err, expect)
+	}
+}
+
+func TestUnmarshalHex(t *testing.T) {
+	type target struct {
+		Key    [4]byte `caddyfile:"$1,encoding=hex"`
+		Salt   []byte  `caddyfile:"salt,encoding=hex"`
+		Signer [3]byte `caddyfile:"signer,encoding=base64"`
+	}
+
+	v, err := unmarshalString[target](`
+		target deadbeef {
+			salt 00ff
+			signer AQID
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := target{
+		Key:    [4]byte{0xde, 0xad, 0xbe, 0xef},
+		Salt:   []byte{0x00, 0xff},
+		Signer: [3]byte{1, 2, 3},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"short key", "target dead", "Testfile:1 - Error during parsing: invalid length: expected 4 bytes, got 2"},
+		{"long key", "target deadbeef00", "Testfile:1 - Error during parsing: invalid length: expected 4 bytes, got 5"},
+		{"invalid hex", "target deadbeeg", "Testfile:1 - Error during parsing: invalid hex value: encoding/hex: invalid byte: U+0067 'g'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := unmarshalString[target](test.input)
+			if err == nil || !strings.HasSuffix(err.Error(), test.err) {
+				t.Errorf("unexpected error:\ngot  %v\nwant %s", err, test.err)
+			}
+		})
+	}
+}
@@ -1,13 +1,16 @@
 package caddyunmarshal
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/caddyserver/caddy/v2"
 	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
@@ -15,13 +18,70 @@ import (
 )
 
 // Unmarshal unmarshals the given Caddyfile dispenser into the given struct
-// value.
+// value. If the struct or any nested struct implements caddy.Validator (i.e.
+// has a Validate() error method), then it is validated once all of its fields
+// are set.
+//
+// The value may also be a schemaless map[string]any, see TypeSchemaless.
 func Unmarshal[T any](d *caddyfile.Dispenser, v *T) error {
 	r, err := newReflectValue(v)
 	if err != nil {
 		return err
 	}
-	return unmarshal(dispenser{d, nil}, r)
+	return withPath(d.Val(), unmarshal(dispenser{Dispenser: d}, r))
+}
+
+// UnmarshalString unmarshals the given Caddyfile snippet, which must consist
+// of a single occurrence of the given directive, into the given struct value.
+// It is a shorthand for tokenizing the snippet and calling Unmarshal, e.g.
+//
+//	var v MyDirective
+//	err := UnmarshalString("my_directive", "my_directive arg {\n\tflag\n}", &v)
+func UnmarshalString[T any](directive, snippet string, v *T) error {
+	tokens, err := caddyfile.Tokenize([]byte(snippet), "Caddyfile")
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("cannot tokenize snippet: %w", err)
+	}
+
+	d := caddyfile.NewDispenser(tokens)
+	if !d.Next() {
+		return fmt.Errorf("caddyunmarshal: expected directive %s, got empty snippet", directive)
+	}
+	if d.Val() != directive {
+		return d.Errf("expected directive %s, got %s", directive, d.Val())
+	}
+
+	if err := Unmarshal(d, v); err != nil {
+		return err
+	}
+
+	if d.Next() {
+		return d.Errf("unexpected %s after directive %s", d.Val(), directive)
+	}
+
+	return nil
+}
+
+// UnmarshalTokens unmarshals the given tokens, which must consist of a single
+// occurrence of a directive, into the given struct value. It is a shorthand
+// for creating a dispenser of the tokens and calling Unmarshal, e.g. for the
+// segment returned by Dispenser.NextSegment.
+func UnmarshalTokens[T any](tokens []caddyfile.Token, v *T) error {
+	d := caddyfile.NewDispenser(tokens)
+	if !d.Next() {
+		return errors.New("caddyunmarshal: expected directive, got no tokens")
+	}
+
+	directive := d.Val()
+	if err := Unmarshal(d, v); err != nil {
+		return err
+	}
+
+	if d.Next() {
+		return d.Errf("unexpected %s after directive %s", d.Val(), directive)
+	}
+
+	return nil
 }
 
 // UnmarshalForHTTP unmarshals the given HTTP Caddyfile helper into the given
@@ -31,12 +91,90 @@ func UnmarshalForHTTP[T any](d *httpcaddyfile.Helper, v *T) error {
 	if err != nil {
 		return err
 	}
-	return unmarshal(dispenser{d.Dispenser, d}, r)
+	return withPath(d.Val(), unmarshal(dispenser{Dispenser: d.Dispenser, http: d}, r))
 }
 
 type dispenser struct {
 	*caddyfile.Dispenser
-	http *httpcaddyfile.Helper
+	http    *httpcaddyfile.Helper
+	session *Session
+	options *Options
+	plans   *fieldPlans   // fields extracted by a Decoder, if any
+	owner   reflectValue  // struct containing the current field, for parser=
+	self    reflect.Value // value given to UnmarshalDefault
+}
+
+// fields extracts the fields of the given struct value, or reuses those
+// already extracted by a Decoder.
+func (d dispenser) fields(r reflectValue) (structInfo, error) {
+	if d.plans != nil {
+		return d.plans.fields(r)
+	}
+	return extractFields(r, d.options)
+}
+
+// openBlock consumes the opening brace of a block if it is the next token on
+// the current line. Unlike Dispenser.NextBlock, the nesting is left to the
+// callers to track, which allows a closing brace to be followed by more
+// tokens on the same line (e.g. "} arg {").
+//
+// An unquoted "{}" token is consumed as an empty block, in which case the
+// token is both the opening and the closing brace.
+func (d dispenser) openBlock() bool {
+	if d.NextArg() {
+		if d.inEmptyBlock() {
+			return true
+		}
+		d.Prev()
+		return false
+	}
+
+	prev := d.Token()
+	if !d.Next() {
+		return false
+	}
+
+	if d.Val() == "{" && onSameLine(prev, d.Token()) {
+		return true
+	}
+
+	d.Prev()
+	return false
+}
+
+// nextInBlock loads the next token within a block opened by openBlock. It
+// returns false once the closing brace is loaded.
+func (d dispenser) nextInBlock() bool {
+	return d.Next() && d.Val() != "}"
+}
+
+// inEmptyBlock returns true if the current token is an unquoted "{}", which
+// is an empty block.
+func (d dispenser) inEmptyBlock() bool {
+	return d.Val() == "{}" && !d.Token().Quoted()
+}
+
+// skipEmptyBlock skips over the block opened by openBlock if it has no
+// subdirectives. It returns false without moving the cursor otherwise.
+func (d dispenser) skipEmptyBlock() bool {
+	if d.inEmptyBlock() {
+		return true
+	}
+
+	if d.Next() {
+		if d.Val() == "}" {
+			return true
+		}
+		d.Prev()
+	}
+
+	return false
+}
+
+// onSameLine returns true if the next token is on the same line as where the
+// previous token ends.
+func onSameLine(prev, next caddyfile.Token) bool {
+	return prev.File == next.File && prev.Line+strings.Count(prev.Text, "\n") == next.Line
 }
 
 // TODO: UnmarshalForJSON
@@ -49,7 +187,7 @@ type reflectValue struct {
 func newReflectValue(v any) (reflectValue, error) {
 	rv := reflect.ValueOf(v)
 	rv = reflect.Indirect(rv)
-	if rv.Kind() != reflect.Struct {
+	if rv.Kind() != reflect.Struct && rv.Type() != TypeSchemaless {
 		return reflectValue{}, fmt.Errorf("caddyunmarshal: expected struct value, got %T", v)
 	}
 
@@ -60,71 +198,249 @@ func newReflectValue(v any) (reflectValue, error) {
 // unmarshal unmarshals a list of arguments or blocks. Note that for a typical
 // block (e.g. "handle a b c"), the function assumes that the first argument,
 // which is the directive name, has already been consumed.
+//
+// Once all fields are set, the PostUnmarshaler hook of the struct is called,
+// then the struct is validated if it implements caddy.Validator.
 func unmarshal(d dispenser, r reflectValue) error {
-	info, err := extractFields(r)
+	if r.t == TypeSchemaless {
+		return unmarshalSchemaless(d, r)
+	}
+
+	info, err := d.fields(r)
 	if err != nil {
 		return fmt.Errorf("cannot extract fields: %w", err)
 	}
 
-	// If we expect a matcher, then the user MUST have called UnmarshalForHTTP,
-	// because we need the httpcaddyfile.Helper instance.
-	if info.matcher != nil {
-		if d.http == nil {
-			return fmt.Errorf("cannot unmarshal matcher: UnmarshalForHTTP was not called")
-		}
+	var seg caddyfile.Segment
+	if hasPostUnmarshaler(r.t) {
+		seg = d.segment()
+	}
 
-		// Matchers must be of type caddy.ModuleMap.
-		if !r.t.AssignableTo(TypeCaddyModuleMap) {
-			return fmt.Errorf("cannot unmarshal matcher: expected caddy.ModuleMap, got %T", r.v.Interface())
-		}
+	pos := tokenPosition(d.Dispenser)
+	if err := unmarshalInfo(d, info); err != nil {
+		return err
+	}
 
-		moduleMap, ok, err := d.http.MatcherToken()
-		if err != nil {
-			return fmt.Errorf("cannot get module map: %w", err)
+	if seg != nil {
+		if err := postUnmarshal(r, seg); err != nil {
+			return err
 		}
+	}
+
+	return validateValue(r, pos)
+}
+
+// unmarshalInfo is like unmarshal, except the fields are given by info, which
+// may be composed from several structs.
+func unmarshalInfo(d dispenser, info structInfo) error {
+	// If we expect a matcher, then the user MUST have called UnmarshalForHTTP,
+	// because we need the httpcaddyfile.Helper instance.
+	given := make(map[string]bool) // fields that were given, see fieldInfo.key
 
-		if ok {
-			// We matched a matcher, so we can set the value.
-			r.v.Set(reflect.ValueOf(moduleMap))
+	if info.matcher != nil {
+		ok, err := d.matcherToken(*info.matcher)
+		if err != nil {
+			return err
 		}
+		given[info.matcher.key()] = ok
 	}
 
 	var hadBlock bool
+	var blocks int
+	var primaryArgs int
+	var repeated *fieldInfo // variadic block field taking the blocks that follow
+	var repeatedAt int
+	var variadic *fieldInfo // variadic argument field, see unmarshalVariadic
 
 	var i int
 loop:
 	for {
-		nesting := d.Nesting()
 		switch {
-		case d.NextArg():
+		case d.openBlock():
+			ix := i
 			field, ok := info.otherFieldAt(i)
-			if !ok {
-				return d.WrapErr(fmt.Errorf("unexpected argument at [%d]: %s", i, d.Val()))
+			if !ok && repeated != nil {
+				ix, field, ok = repeatedAt, *repeated, true
 			}
 
-			if err := unmarshalValue(d, field.value, d.Val()); err != nil {
-				return fmt.Errorf("error at [%d]: %w", i, err)
-			}
+			if ok && isBlockKind(field.kind) {
+				d.trace("block", &field)
+				value := field.value
+
+				if repeated == nil {
+					d.merge(field, false)
+				}
+
+				if isVariadicBlock(field) {
+					if err := unmarshalVariadicBlock(d, value); err != nil {
+						return errorAtIndex(ix, err)
+					}
+					blocks++
+					if repeated != nil {
+						// Further blocks don't take up positions.
+						continue
+					}
+					repeated, repeatedAt = &field, i
+					break
+				}
+
+				if isSubroute(field.opts) {
+					if err := unmarshalSubroute(d, value); err != nil {
+						return errorAtIndex(i, err)
+					}
+					blocks++
+					break
+				}
 
-		case d.NextBlock(nesting):
-			var value reflectValue
-			if field, ok := info.otherFieldAt(i); ok {
-				value = field.value
+				// Structs used as indexed blocks only have subdirectives,
+				// since there's no line for positional arguments.
+				if value.v.Kind() == reflect.Struct {
+					blockInfo, err := d.fields(value)
+					if err != nil {
+						return fmt.Errorf("cannot extract fields: %w", err)
+					}
+					if len(blockInfo.otherFields) > 0 || blockInfo.matcher != nil {
+						return fmt.Errorf(
+							"block [%d] of type %s cannot have positional fields", i, value.t)
+					}
+				}
+
+				if err := unmarshalBlock(d, value); err != nil {
+					return errorAtIndex(i, err)
+				}
 			} else {
 				// Field not found, so check if we parsed a block already.
 				// If not, then we can assume that we want this. Otherwise,
 				// error out.
-				if hadBlock {
-					return d.WrapErr(fmt.Errorf("unexpected block at [%d]: %s", i, d.Val()))
+				if hadBlock || (blocks > 0 && !info.hasBlock()) {
+					return info.withExamples(d.WrapErr(fmt.Errorf(
+						"second block not allowed at [%d]; did you mean to put these in one block?", i)))
 				}
-				// value is kept the same, meaning we'll unmarshal into the
-				// current struct.
-				value = r
+				// The block belongs to the current struct, so its
+				// subdirectives are our block fields.
 				hadBlock = true
+				d.trace("block", nil)
+
+				if !info.hasBlock() {
+					// An empty block is harmless even if we have nothing
+					// to put in it.
+					if !d.skipEmptyBlock() {
+						return info.withExamples(d.WrapErr(fmt.Errorf("%w at [%d]", ErrUnexpectedBlock, i)))
+					}
+				} else if err := unmarshalBlockInfo(d, reflectValue{}, info, given); err != nil {
+					return errorInBlock(i, err)
+				}
+
+				// The block doesn't take up a position, so that missing
+				// required fields are still reported.
+				blocks++
+				continue
+			}
+			blocks++
+
+		case d.NextArg():
+			if field, raw, ok := info.keyValueField(d); ok {
+				// key=value arguments don't take up positions.
+				d.trace("keyvalue", &field)
+				d.warnDeprecated(field)
+				d.owner = field.owner
+				d.merge(field, given[field.key()])
+				if err := unmarshalNamedArg(d, field, raw, field.key()+"=<value>"); err != nil {
+					return err
+				}
+				given[field.key()] = true
+				continue
+			}
+
+			if info.isEndOfFlags(d) {
+				info = info.withoutFlags()
+				continue
 			}
 
-			if err := unmarshalBlock(d, nesting, value); err != nil {
-				return fmt.Errorf("error at [%d]: %w", i, err)
+			if ok, err := unmarshalFlag(d, info, given); ok {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			field, ok := info.otherFieldAt(i)
+			if !ok && variadic != nil {
+				// Arguments following flags or key=value arguments
+				// continue the variadic field.
+				if err := unmarshalVariadic(d, info, variadic.value, variadic.opts); err != nil {
+					return errorAtIndex(i-1, redact(err, d.argSecrets(*variadic)))
+				}
+				continue
+			}
+			if !ok && info.primary != nil {
+				// Arguments past the positional fields are the shortcut for
+				// the primary subdirective.
+				d.trace("primary", info.primary)
+				if primaryArgs == 0 {
+					d.merge(*info.primary, given[info.primary.key()])
+				}
+				if err := unmarshalPrimary(d, *info.primary, primaryArgs); err != nil {
+					return errorAtIndex(i, redact(err, d.argSecrets(*info.primary)))
+				}
+				given[info.primary.key()] = true
+				primaryArgs++
+				continue
+			}
+			if !ok {
+				return info.withExamples(d.WrapErr(fmt.Errorf("%w at [%d]: %s", ErrUnexpectedArgument, i, d.Val())))
+			}
+
+			if field.kind.(argumentKind).matcher {
+				// Matcher positions are only taken up by matcher tokens;
+				// any other argument belongs to the next position.
+				d.Prev()
+				ok, err := d.matcherToken(field)
+				if err != nil {
+					return errorAtIndex(i, err)
+				}
+				if !ok {
+					i++
+				}
+				continue
+			}
+
+			d.trace("argument", &field)
+			d.warnDeprecated(field)
+			d.owner = field.owner
+			d.merge(field, false)
+			secrets := d.argSecrets(field)
+
+			if kind, ok := optValue(field.opts, "ref"); ok {
+				if err := d.reference(kind, field.value, d.Val()); err != nil {
+					return errorAtIndex(i, err)
+				}
+				break
+			}
+
+			if d.isNone(field.opts) {
+				clearValue(field.value)
+			} else if field.value.v.Kind() == reflect.Array && !isEncodedArray(field.value.t, field.opts) {
+				// Arrays take up one position, but as many arguments as
+				// their length.
+				if err := unmarshalArray(d, field.value, field.opts); err != nil {
+					return errorAtIndex(i, redact(err, secrets))
+				}
+			} else if isVariadic(field) {
+				// Slices take up the last position, and all arguments
+				// that are left.
+				if err := unmarshalVariadic(d, info, field.value, field.opts); err != nil {
+					return errorAtIndex(i, redact(err, secrets))
+				}
+				variadic = &field
+			} else if err := unmarshalValue(d, field.value, d.Val(), field.opts); err != nil {
+				return errorAtIndex(i, redact(err, secrets))
+			}
+
+			if kind, ok := optValue(field.opts, "def"); ok {
+				if err := d.define(kind, field.owner); err != nil {
+					return err
+				}
 			}
 
 		default:
@@ -138,115 +454,660 @@ loop:
 	if i < len(info.otherFields) {
 		for j, field := range info.otherFields[i:] {
 			if !field.optional() {
-				return d.WrapErr(fmt.Errorf("missing required field [%d]", i+j))
+				return info.withExamples(d.WrapErr(fmt.Errorf("%w field [%d]", ErrMissingRequired, i+j)))
+			}
+		}
+	}
+
+	for j, field := range info.otherFields {
+		if j < i {
+			given[field.key()] = true
+		}
+	}
+
+	info.markPresent(given)
+	return info.checkGiven(d, given)
+}
+
+// unmarshalPrimary unmarshals the nth argument that was given as the shortcut
+// for the primary field. Slice fields take any number of arguments, while
+// other fields only take one.
+func unmarshalPrimary(d dispenser, field fieldInfo, n int) error {
+	d.owner = field.owner
+	r := field.value
+	if r.v.Kind() == reflect.Slice && !isScalar(r.t) && isScalar(r.t.Elem()) {
+		return appendElems(d, r, d.Val(), field.opts)
+	}
+
+	if n > 0 {
+		return d.WrapErr(fmt.Errorf("%w: %s", ErrUnexpectedArgument, d.Val()))
+	}
+
+	return unmarshalValue(d, r, d.Val(), field.opts)
+}
+
+func unmarshalBlock(d dispenser, r reflectValue) error {
+	if r.t == TypeSchemaless {
+		if r.v.IsNil() {
+			r.v.Set(reflect.MakeMap(r.t))
+		}
+		return parseSchemalessBlock(d, r.v.Interface().(map[string]any))
+	}
+
+	if r.v.Kind() != reflect.Struct {
+		return unmarshalBlockInfo(d, r, structInfo{}, nil)
+	}
+
+	info, err := d.fields(r)
+	if err != nil {
+		return fmt.Errorf("cannot extract fields: %w", err)
+	}
+
+	pos := tokenPosition(d.Dispenser)
+	given := make(map[string]bool)
+	if err := unmarshalBlockInfo(d, r, info, given); err != nil {
+		return err
+	}
+	info.markPresent(given)
+	if err := info.checkGiven(d, given); err != nil {
+		return err
+	}
+
+	return validateValue(r, pos)
+}
+
+// unmarshalBlockInfo is like unmarshalBlock, except struct blocks are
+// unmarshaled into the block fields of info, which may be composed from
+// several structs. If r is the zero value, then it is treated as a struct. The
+// subdirectives found are recorded into given, which may be nil for maps.
+func unmarshalBlockInfo(d dispenser, r reflectValue, info structInfo, given map[string]bool) error {
+	// We expect either a struct, a map[K]V or a []KV[V] for each struct field
+	// value. If it's anything else, then it doesn't match a block.
+	var isMap bool
+	var isKV bool
+	switch {
+	case r.t == nil:
+	case isKVSlice(r.t):
+		isKV = true
+	default:
+		switch r.v.Kind() {
+		case reflect.Struct:
+		case reflect.Map:
+			isMap = true
+			if r.v.IsNil() {
+				r.v.Set(reflect.MakeMap(r.t))
+			}
+		default:
+			return fmt.Errorf("expected struct or map, got %T", r.v.Interface())
+		}
+	}
+
+	parse := func() (err error) {
+		name := d.Val()
+		if err := d.checkLength(name, nil); err != nil {
+			return d.WrapErr(err)
+		}
+
+		var value reflectValue
+		var opts []string
+		var secrets []string
+
+		switch {
+		case isKV:
+			// If it's a []KV, then the name is the key, and the rest of the
+			// line is the value. The pair is appended once it's parsed.
+			d.trace("entry", nil)
+			elem := reflect.New(r.t.Elem()).Elem()
+			elem.Field(0).SetString(name)
+
+			val := elem.Field(1)
+			value = reflectValue{val, val.Type()}
+
+			defer func() { r.v.Set(reflect.Append(r.v, elem)) }()
+
+		case isMap:
+			// If it's a map, then we need to create a new value for the
+			// map key, and then unmarshal into that.
+			d.trace("entry", nil)
+			pos, raw := tokenPosition(d.Dispenser), d.lineArgs()
+			key := reflect.New(r.t.Key()).Elem()
+			if err := unmarshalMapKey(d, reflectValue{key, key.Type()}, name); err != nil {
+				return newMapEntryError(pos, name, raw, err)
+			}
+
+			// Create a new value for the map value. The existing value is
+			// copied over, so that repeated keys append to slice values.
+			val := reflect.New(r.t.Elem()).Elem()
+			if existing := r.v.MapIndex(key); existing.IsValid() {
+				val.Set(existing)
+			}
+			value = reflectValue{val, val.Type()}
+
+			// At the end, set the map value, unless it's invalid.
+			defer func() {
+				if err != nil {
+					err = newMapEntryError(pos, name, raw, err)
+					return
+				}
+				r.v.SetMapIndex(key, val)
+			}()
+
+		case strings.HasPrefix(name, "@"):
+			// Named matcher definitions go into the field tagged "@".
+			field, ok := info.blockFieldNamed("@")
+			if !ok {
+				return d.WrapErr(fmt.Errorf(
+					"named matcher definition %s is not supported within this block; "+
+						"define it outside of the directive instead", name))
+			}
+
+			d.trace("matcher_definition", &field)
+
+			return unmarshalMatcherDefinition(d, field.value)
+
+		default:
+			field, ok := info.blockFieldNamed(name)
+			if !ok && info.remain != nil {
+				d.trace("remain", info.remain)
+				return unmarshalRemain(d, info.remain.value)
 			}
+			if !ok && info.extras != nil {
+				d.trace("extras", info.extras)
+				return unmarshalExtras(d, info.extras.value)
+			}
+			if !ok {
+				if d.options.strict() {
+					return info.withExamples(d.WrapErr(fmt.Errorf("%w %q", ErrUnknownSubdirective, name)))
+				}
+
+				// Fields are optional, so we can just skip over them.
+				// I think this is the right skip? TODO: check.
+				d.trace("skip", nil)
+				d.NextSegment()
+				return nil
+			}
+			value = field.value
+			opts = field.opts
+			secrets = d.segmentSecrets(field)
+			d.owner = field.owner
+			d.merge(field, given[field.key()])
+			given[field.key()] = true
+			d.trace("subdirective", &field)
+			d.warnDeprecated(field)
+
+			if kind, ok := optValue(field.opts, "ref"); ok {
+				if !d.NextArg() {
+					return d.ArgErr()
+				}
+				if err := d.reference(kind, value, d.Val()); err != nil {
+					return errorAtName(name, err)
+				}
+				if d.NextArg() {
+					return d.WrapErr(fmt.Errorf("%w at %q: %s", ErrUnexpectedArgument, name, d.Val()))
+				}
+				return nil
+			}
+
+			if kind, ok := optValue(field.opts, "def"); ok {
+				// The definition name is the argument that unmarshalLine
+				// leaves the cursor at.
+				defer func() {
+					if err == nil {
+						err = d.define(kind, field.owner)
+					}
+				}()
+			}
+		}
+
+		if err := unmarshalLine(d, value, opts); err != nil {
+			if isMap {
+				return err // wrapped into a MapEntryError
+			}
+			return errorAtName(name, redact(err, secrets))
+		}
+
+		return nil
+	}
+
+	// Note that if we're in this function, then we've already consumed the
+	// opening brace. We shall iterate over the fields within it, unless the
+	// brace was an empty "{}" block.
+	if d.inEmptyBlock() {
+		return nil
+	}
+
+	for d.nextInBlock() {
+		if err := parse(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unmarshalLine unmarshals the remaining arguments on the current line, as
+// well as the block that follows them, into the given value. The cursor is
+// expected to be at the subdirective name.
+func unmarshalLine(d dispenser, r reflectValue, opts []string) error {
+	name := d.Val()
+
+	// A line with only the sentinel clears the value, e.g. "encode none".
+	if unmarshalNone(d, r, opts) {
+		return nil
+	}
+
+	if isOptional(r.t) {
+		value, markSet := unwrapOptional(r)
+		if err := unmarshalLine(d, value, opts); err != nil {
+			return err
+		}
+		markSet()
+		return nil
+	}
+
+	// Subroutes take a block of directives, see isSubroute.
+	if isSubroute(opts) {
+		return unmarshalSubrouteLine(d, r)
+	}
+
+	// Struct pointers are allocated on first use, which allows recursive
+	// types.
+	if isStructPointer(r.t) {
+		return unmarshalLine(d, allocPointer(r), opts)
+	}
+
+	// Scalar pointers are allocated once the value is parsed, so that a bare
+	// *bool subdirective is still true.
+	if isScalarPointer(r.t) {
+		elem := zeroValue(r.t.Elem())
+		if err := unmarshalLine(d, elem, opts); err != nil {
+			return err
+		}
+		r.v.Set(elem.v.Addr())
+		return nil
+	}
+
+	// Types implementing caddyfile.Unmarshaler get the whole segment, just
+	// like a directive would.
+	if d.usesUnmarshaler(r) {
+		return d.callUnmarshaler(r, d.NewFromNextSegment())
+	}
+
+	switch {
+	case r.v.Kind() == reflect.Bool:
+		// A bare boolean subdirective is true, but the value may also be
+		// given explicitly, e.g. to disable a flag that defaults to true.
+		if !d.NextArg() {
+			r.v.SetBool(true)
+			return nil
+		}
+
+		if err := unmarshalValue(d, r, d.Val(), opts); err != nil {
+			return err
+		}
+
+		if d.NextArg() {
+			return d.WrapErr(fmt.Errorf("%w at %q: %s", ErrUnexpectedArgument, name, d.Val()))
+		}
+
+		return nil
+
+	case isCountFlag(r.t, opts):
+		// Counting flags are incremented on every occurrence.
+		return unmarshalCountFlag(d, r)
+
+	case hasVariants(r.t):
+		// Interfaces with registered variants are selected by the first
+		// argument.
+		return unmarshalVariant(d, r)
+
+	case r.t == TypeSchemaless:
+		// Schemaless maps take anything that follows.
+		return unmarshalSchemaless(d, r)
+
+	case r.v.Kind() == reflect.Slice && !isKVSlice(r.t) && !isScalar(r.t):
+		// Slices are appended to on every occurrence of the subdirective.
+		return unmarshalSliceLine(d, r, opts)
+
+	case r.v.Kind() == reflect.Array && !isEncodedArray(r.t, opts):
+		// Arrays take exactly as many arguments as their length.
+		return unmarshalArrayLine(d, r, opts)
+
+	case r.v.Kind() == reflect.Map && hasOpt(opts, "keyed"):
+		// Keyed maps take the first argument as the key, and the rest of the
+		// line and its block as the value.
+		return unmarshalKeyedLine(d, r)
+
+	case r.v.Kind() == reflect.Map || isKVSlice(r.t):
+		// Maps and []KVs are unmarshaled from the block following the
+		// subdirective.
+		if d.openBlock() {
+			return unmarshalBlock(d, r)
+		}
+
+		if d.NextArg() {
+			return d.WrapErr(fmt.Errorf("%w at %q: %s", ErrUnexpectedArgument, name, d.Val()))
+		}
+
+		return nil
+
+	case r.v.Kind() == reflect.Struct && !isScalar(r.t):
+		// Otherwise, delegate this list of values to the unmarshal function.
+		return unmarshal(d, r)
+	}
+
+	// Scalar values take exactly one argument.
+	if !d.NextArg() {
+		return d.ArgErr()
+	}
+
+	if err := unmarshalValue(d, r, d.Val(), opts); err != nil {
+		return err
+	}
+
+	if d.NextArg() {
+		return d.WrapErr(fmt.Errorf("%w at %q: %s", ErrUnexpectedArgument, name, d.Val()))
+	}
+
+	return nil
+}
+
+// unmarshalSliceLine appends the current line to the given slice. Scalar
+// elements are appended once per argument, while other elements are
+// unmarshaled from the whole line.
+func unmarshalSliceLine(d dispenser, r reflectValue, opts []string) error {
+	elemType := r.t.Elem()
+
+	if !isScalar(elemType) || isUnmarshaler(elemType) {
+		elem := reflect.New(elemType).Elem()
+		if err := unmarshalLine(d, reflectValue{elem, elemType}, opts); err != nil {
+			return err
+		}
+
+		r.v.Set(reflect.Append(r.v, elem))
+		return nil
+	}
+
+	var n int
+	for ; d.NextArg(); n++ {
+		if err := appendElems(d, r, d.Val(), opts); err != nil {
+			return errorAtIndex(n, err)
+		}
+	}
+
+	if n == 0 {
+		return d.ArgErr()
+	}
+
+	return nil
+}
+
+// Explicitly supported value types:
+var (
+	TypeCaddyModuleMap      = reflect.TypeOf(caddy.ModuleMap{}) // matcher only
+	TypeCaddyAddress        = reflect.TypeOf(httpcaddyfile.Address{})
+	TypeCaddyNetworkAddress = reflect.TypeOf(caddy.NetworkAddress{})
+	TypeCaddyDuration       = reflect.TypeOf(caddy.Duration(0))
+	TypeDuration            = reflect.TypeOf(time.Duration(0))
+	TypeBytes               = reflect.TypeOf([]byte(nil))
+	TypeLocation            = reflect.TypeOf((*time.Location)(nil))
+)
+
+var scalarTypes = []reflect.Type{
+	TypeCaddyAddress,
+	TypeCaddyNetworkAddress,
+	TypeCaddyDuration,
+	TypeDuration,
+	TypeBytes,
+	TypeTLSCurve,
+	TypeTLSPublicKeyAlgorithm,
+	TypeSchedule,
+	TypeLocation,
+}
+
+var typeUnmarshaler = reflect.TypeOf((*caddyfile.Unmarshaler)(nil)).Elem()
+
+// isUnmarshaler returns true if a pointer to the given type implements
+// caddyfile.Unmarshaler or Unmarshaler.
+func isUnmarshaler(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	return pt.Implements(typeUnmarshaler) || pt.Implements(typeUnmarshalerWith)
+}
+
+// isScalar returns true if the given type is unmarshaled from a single
+// argument by unmarshalValue.
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case
+		reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	if isAny(t) || hasValueParser(t) {
+		return true
+	}
+
+	for _, scalarType := range scalarTypes {
+		if t.AssignableTo(scalarType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isScalarPointer returns true if the given type is a pointer to a scalar, such
+// as *string or *int. The pointer is only allocated once a valid value is
+// given, so that nil tells a value that was left out or cleared using the
+// allownone option apart from its zero value.
+func isScalarPointer(t reflect.Type) bool {
+	return t.Kind() == reflect.Pointer && !isScalar(t) && isScalar(t.Elem())
+}
+
+// derefScalarPointer returns the scalar type that t points to if it's a scalar
+// pointer, or t itself otherwise.
+func derefScalarPointer(t reflect.Type) reflect.Type {
+	if isScalarPointer(t) {
+		return t.Elem()
+	}
+	return t
+}
+
+func unmarshalValue(d dispenser, r reflectValue, raw string, opts []string) error {
+	if isSecret(opts) {
+		return unmarshalSecretValue(d, r, raw, opts)
+	}
+
+	if ok, err := unmarshalFastValue(d, r, raw, opts); ok {
+		return err
+	}
+
+	if isOptional(r.t) {
+		value, markSet := unwrapOptional(r)
+		if err := unmarshalValue(d, value, raw, opts); err != nil {
+			return err
+		}
+		markSet()
+		return nil
+	}
+
+	if isScalarPointer(r.t) {
+		elem := zeroValue(r.t.Elem())
+		if err := unmarshalValue(d, elem, raw, opts); err != nil {
+			return err
+		}
+		r.v.Set(elem.v.Addr())
+		return nil
+	}
+
+	if err := d.checkLength(raw, opts); err != nil {
+		return d.WrapErr(err)
+	}
+
+	raw = d.options.replace(raw)
+
+	if err := checkImportArgs(r, raw); err != nil {
+		return d.WrapErr(err)
+	}
+
+	if hasOpt(opts, "allowfile") {
+		contents, err := readFileValue(raw, r.t, opts)
+		if err != nil {
+			return d.WrapErr(err)
+		}
+		if err := d.checkLength(contents, opts); err != nil {
+			return d.WrapErr(err)
+		}
+		raw = contents
+	}
+
+	if name, ok := optValue(opts, "parser"); ok {
+		return unmarshalParserValue(d, r, raw, name)
+	}
+
+	// Does this type implement caddyfile.Unmarshaler? If so, we can allow some
+	// overriding.
+	if d.usesUnmarshaler(r) {
+		return d.callUnmarshaler(r, d.Dispenser)
+	}
+
+	if ok, err := parseRegisteredValue(d, r, raw); ok {
+		return err
+	}
+
+	if d.options.weaklyTyped() {
+		if ok, err := unmarshalWeakValue(d, r, raw, opts); ok {
+			return err
+		}
+	}
+
+	// Handle explicitly supported types. These go first, since some of them,
+	// like durations, are also of primitive kinds.
+	switch {
+	case r.t.AssignableTo(TypeCaddyAddress):
+		addr, err := httpcaddyfile.ParseAddress(raw)
+		if err != nil {
+			return d.WrapErr(fmt.Errorf("cannot parse address: %w", err))
+		}
+
+		r.v.Set(reflect.ValueOf(addr))
+		return nil
+
+	case r.t.AssignableTo(TypeCaddyNetworkAddress):
+		addr, err := parseNetworkAddress(raw, opts)
+		if err != nil {
+			return d.WrapErr(fmt.Errorf("cannot parse network address: %w", err))
+		}
+
+		r.v.Set(reflect.ValueOf(addr))
+		return nil
+
+	case r.t.AssignableTo(TypeCaddyDuration):
+		dura, err := caddy.ParseDuration(raw)
+		if err != nil {
+			return d.WrapErr(durationError(err, raw, d.peekArg(), true))
+		}
+		if err := checkDurationRange(dura, opts); err != nil {
+			return d.WrapErr(err)
 		}
-	}
 
-	return nil
-}
+		r.v.SetInt(int64(dura))
+		return nil
 
-func unmarshalBlock(d dispenser, nesting int, r reflectValue) error {
-	// We expect either a struct or a map[K]V for each struct field value.
-	// If it's anything else, then it doesn't match a block.
-	var isMap bool
-	var info structInfo
-	switch r.v.Kind() {
-	case reflect.Struct:
-		i, err := extractFields(r)
+	case r.t.AssignableTo(TypeDuration):
+		dura, err := time.ParseDuration(raw)
 		if err != nil {
-			return fmt.Errorf("cannot extract fields: %w", err)
+			return d.WrapErr(durationError(err, raw, d.peekArg(), false))
+		}
+		if err := checkDurationRange(dura, opts); err != nil {
+			return d.WrapErr(err)
 		}
-		info = i
-	case reflect.Map:
-		isMap = true
-	default:
-		return fmt.Errorf("expected struct or map, got %T", r.v.Interface())
-	}
-
-	parse := func() error {
-		name := d.Val()
-		var value reflectValue
 
-		if isMap {
-			// If it's a map, then we need to create a new value for the
-			// map key, and then unmarshal into that.
-			key := reflect.New(r.t.Key()).Elem()
-			if err := unmarshalValue(d, reflectValue{key, key.Type()}, name); err != nil {
-				return fmt.Errorf("error unmarshaling map key %q: %w", name, err)
-			}
+		r.v.Set(reflect.ValueOf(dura))
+		return nil
 
-			// Create a new value for the map value.
-			val := reflect.New(r.t.Elem()).Elem()
-			value = reflectValue{val, val.Type()}
+	case r.t.AssignableTo(TypeTLSCurve), r.t.AssignableTo(TypeTLSPublicKeyAlgorithm):
+		if err := parseTLSValue(r, raw); err != nil {
+			return d.WrapErr(err)
+		}
+		return nil
 
-			// At the end, set the map value.
-			defer func() { r.v.SetMapIndex(key, val) }()
-		} else {
-			field, ok := info.blockFieldNamed(name)
-			if !ok {
-				// Fields are optional, so we can just skip over them.
-				// I think this is the right skip? TODO: check.
-				d.NextSegment()
-				return nil
-			}
-			value = field.value
+	case r.t.AssignableTo(TypeLocation):
+		loc, err := time.LoadLocation(raw)
+		if err != nil {
+			return d.WrapErr(fmt.Errorf("cannot load time zone: %w", err))
 		}
 
-		// If this field is a boolean, then we are immediately done and don't
-		// expect any more fields.
-		if value.v.Kind() == reflect.Bool {
-			if d.CountRemainingArgs() > 0 {
-				return d.WrapErr(fmt.Errorf("unexpected argument at %q: %s", name, d.Val()))
-			}
+		r.v.Set(reflect.ValueOf(loc))
+		return nil
 
-			value.v.SetBool(true)
-			return nil
+	case r.t.AssignableTo(TypeSchedule):
+		schedule, err := ParseSchedule(raw)
+		if err != nil {
+			return d.WrapErr(fmt.Errorf("cannot parse schedule: %w", err))
 		}
 
-		// Otherwise, delegate this list of values to the unmarshal function.
-		if err := unmarshal(d, value); err != nil {
-			return fmt.Errorf("error at %q: %w", name, err)
-		}
+		r.v.Set(reflect.ValueOf(schedule))
+		return nil
 
+	case isEncodedArray(r.t, opts):
+		if err := decodeArray(r, raw, opts); err != nil {
+			return d.WrapErr(err)
+		}
 		return nil
-	}
 
-	// Note that if we're in this function, then we've already entered the
-	// child. We shall iterate over the fields within it.
-	for ok := true; ok; ok = d.NextBlock(nesting) {
-		if err := parse(); err != nil {
-			return nil
+	case r.t.AssignableTo(TypeBytes):
+		b, err := decodeBytes(raw, opts)
+		if err != nil {
+			return d.WrapErr(err)
 		}
-	}
 
-	return nil
-}
+		r.v.SetBytes(b)
+		return nil
 
-// Explicitly supported value types:
-var (
-	TypeCaddyModuleMap      = reflect.TypeOf(caddy.ModuleMap{}) // matcher only
-	TypeCaddyAddress        = reflect.TypeOf(httpcaddyfile.Address{})
-	TypeCaddyNetworkAddress = reflect.TypeOf(caddy.NetworkAddress{})
-	TypeCaddyDuration       = reflect.TypeOf(caddy.Duration(0))
-	TypeDuration            = reflect.TypeOf(time.Duration(0))
-)
+	case isAny(r.t):
+		v, err := parseAny(raw, opts)
+		if err != nil {
+			return d.WrapErr(err)
+		}
 
-func unmarshalValue(d dispenser, r reflectValue, raw string) error {
-	// Does this type implement caddyfile.Unmarshaler? If so, we can allow some
-	// overriding.
-	if unmarshaler, ok := r.v.Addr().Interface().(caddyfile.Unmarshaler); ok {
-		return unmarshaler.UnmarshalCaddyfile(d.Dispenser)
+		r.v.Set(reflect.ValueOf(v))
+		return nil
 	}
 
 	// Handle primitive types.
 	switch r.v.Kind() {
 	case reflect.String:
+		if allowed, ok := optValue(opts, "enum"); ok {
+			values := strings.Split(allowed, "|")
+			if !hasOpt(values, raw) {
+				return d.WrapErr(fmt.Errorf(
+					"invalid value %q, expected one of: %s", raw, strings.Join(values, ", ")))
+			}
+		}
+
+		if err := validateModuleValue(raw, opts); err != nil {
+			return d.WrapErr(err)
+		}
+
+		if err := validateTLSValue(raw, opts); err != nil {
+			return d.WrapErr(err)
+		}
+
 		r.v.SetString(raw)
 		return nil
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		i, err := strconv.ParseInt(raw, 10, r.t.Bits())
+		var i int64
+		var err error
+		if hasOpt(opts, "si") {
+			i, err = parseSIInt(raw, r.t.Bits())
+		} else {
+			i, err = parseInt(raw, r.t.Bits())
+		}
 		if err != nil {
 			return d.WrapErr(fmt.Errorf("cannot parse int: %w", err))
 		}
@@ -254,8 +1115,14 @@ func unmarshalValue(d dispenser, r reflectValue, raw string) error {
 		r.v.SetInt(i)
 		return nil
 
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		u, err := strconv.ParseUint(raw, 10, r.t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		var err error
+		if hasOpt(opts, "si") {
+			u, err = parseSIUint(raw, r.t.Bits())
+		} else {
+			u, err = parseUint(raw, r.t.Bits())
+		}
 		if err != nil {
 			return d.WrapErr(fmt.Errorf("cannot parse uint: %w", err))
 		}
@@ -264,7 +1131,17 @@ func unmarshalValue(d dispenser, r reflectValue, raw string) error {
 		return nil
 
 	case reflect.Float32, reflect.Float64:
-		f, err := strconv.ParseFloat(raw, r.t.Bits())
+		scale, isPercent, err := percentScale(opts)
+		if err != nil {
+			return err
+		}
+
+		var f float64
+		if isPercent {
+			f, err = parsePercent(raw, scale, r.t.Bits())
+		} else {
+			f, err = strconv.ParseFloat(raw, r.t.Bits())
+		}
 		if err != nil {
 			return d.WrapErr(fmt.Errorf("cannot parse float: %w", err))
 		}
@@ -273,7 +1150,7 @@ func unmarshalValue(d dispenser, r reflectValue, raw string) error {
 		return nil
 
 	case reflect.Bool:
-		v, err := strconv.ParseBool(raw)
+		v, err := parseBool(raw, opts)
 		if err != nil {
 			return d.WrapErr(fmt.Errorf("cannot parse boolean value %q: %w", raw, err))
 		}
@@ -281,45 +1158,7 @@ func unmarshalValue(d dispenser, r reflectValue, raw string) error {
 		return nil
 	}
 
-	switch {
-	case r.t.AssignableTo(TypeCaddyAddress):
-		addr, err := httpcaddyfile.ParseAddress(raw)
-		if err != nil {
-			return d.WrapErr(fmt.Errorf("cannot parse address: %w", err))
-		}
-
-		r.v.Set(reflect.ValueOf(addr))
-		return nil
-
-	case r.t.AssignableTo(TypeCaddyNetworkAddress):
-		addr, err := caddy.ParseNetworkAddress(raw)
-		if err != nil {
-			return d.WrapErr(fmt.Errorf("cannot parse network address: %w", err))
-		}
-
-		r.v.Set(reflect.ValueOf(addr))
-		return nil
-
-	case r.t.AssignableTo(TypeCaddyDuration):
-		dura, err := caddy.ParseDuration(raw)
-		if err != nil {
-			return d.WrapErr(fmt.Errorf("cannot parse duration: %w", err))
-		}
-
-		r.v.Set(reflect.ValueOf(dura))
-		return nil
-
-	case r.t.AssignableTo(TypeDuration):
-		dura, err := time.ParseDuration(raw)
-		if err != nil {
-			return d.WrapErr(fmt.Errorf("cannot parse duration: %w", err))
-		}
-
-		r.v.Set(reflect.ValueOf(dura))
-		return nil
-	}
-
-	return fmt.Errorf("cannot unmarshal value of unsupported type %T", r.v.Interface())
+	return fmt.Errorf("cannot unmarshal value of %w %T", ErrUnsupportedType, r.v.Interface())
 }
 
 type fieldKind interface {
@@ -329,20 +1168,29 @@ type fieldKind interface {
 // blockFieldKind is a fieldKind that indicates that the field is a field within
 // a block.
 type blockFieldKind struct {
-	name string // name of the field within our block
+	name    string   // name of the field within our block
+	aliases []string // other accepted names, e.g. from "name|alias"
+}
+
+// names returns the name of the field followed by its aliases.
+func (kind blockFieldKind) names() []string {
+	return append([]string{kind.name}, kind.aliases...)
 }
 
 // blockKind is a fieldKind that indicates that the field is an entire block.
 type blockKind struct {
 	ix       int
 	optional bool
+	variadic bool // takes all blocks from its position on, see isVariadicBlock
 }
 
 // argumentKind is a fieldKind that indicates that the field is a value
-// argument.
+// argument. Arguments tagged with the matcher option only take matcher tokens
+// and are skipped otherwise, so they may appear at any position.
 type argumentKind struct {
 	ix       int
 	optional bool
+	matcher  bool // only matcher tokens are taken at this position
 }
 
 // matcherKind is a fieldKind that indicates that the field is a matcher. It is
@@ -351,6 +1199,12 @@ type argumentKind struct {
 // structInfo.
 type matcherKind struct{}
 
+// isBlockKind returns true if the given kind is a blockKind.
+func isBlockKind(kind fieldKind) bool {
+	_, ok := kind.(blockKind)
+	return ok
+}
+
 func (blockFieldKind) fieldKind() {}
 func (blockKind) fieldKind()      {}
 func (argumentKind) fieldKind()   {}
@@ -360,6 +1214,8 @@ type fieldInfo struct {
 	field reflect.StructField
 	value reflectValue
 	kind  fieldKind
+	opts  []string     // tag options following the name
+	owner reflectValue // struct containing the field
 }
 
 func (field fieldInfo) optional() bool {
@@ -367,12 +1223,38 @@ func (field fieldInfo) optional() bool {
 	case blockKind:
 		return kind.optional
 	case argumentKind:
-		return kind.optional
+		return kind.optional || kind.matcher
 	default:
 		return true // block fields are always optional
 	}
 }
 
+// key returns the name of the field within its tag, such as $1, {1}, $matcher
+// or the subdirective name, which is unique within a structInfo.
+func (field fieldInfo) key() string {
+	switch kind := field.kind.(type) {
+	case blockFieldKind:
+		return kind.name
+	case blockKind:
+		return fmt.Sprintf("{%d}", kind.ix)
+	case argumentKind:
+		return fmt.Sprintf("$%d", kind.ix)
+	default:
+		return "$matcher"
+	}
+}
+
+// displayName returns the name of the field as shown in usage, e.g. <host>
+// for an argument.
+func (field fieldInfo) displayName() string {
+	switch field.kind.(type) {
+	case argumentKind:
+		return "<" + snakeCase(field.field.Name) + ">"
+	default:
+		return field.key()
+	}
+}
+
 func (field fieldInfo) index() int {
 	switch kind := field.kind.(type) {
 	case blockKind:
@@ -388,17 +1270,83 @@ type structInfo struct {
 	blockFields []fieldInfo // for blockFieldKinds
 	otherFields []fieldInfo // for blockKinds and argumentKinds
 	matcher     *fieldInfo
+	primary     *fieldInfo  // block field that also takes trailing arguments
+	remain      *fieldInfo  // map collecting unknown subdirectives
+	extras      *fieldInfo  // segments of unknown subdirectives
+	present     []fieldInfo // sets of the names of given fields, one per struct
+	opts        []string    // struct-level options from the _ field
+	examples    []string    // example lines appended to errors
 }
 
 func (s structInfo) blockFieldNamed(name string) (fieldInfo, bool) {
 	for _, field := range s.blockFields {
-		if field.field.Name == name {
-			return field, true
+		for _, n := range field.kind.(blockFieldKind).names() {
+			if n == name {
+				return field, true
+			}
 		}
 	}
 	return fieldInfo{}, false
 }
 
+// checkGiven checks the given fields, keyed by fieldInfo.key, against the
+// required option of subdirectives and the group option of all fields. Fields
+// sharing the same group=name are constrained by the options of its members:
+// atleastone requires at least one of them to be given, and exclusive allows
+// at most one of them.
+func (s structInfo) checkGiven(d dispenser, given map[string]bool) error {
+	var groupNames []string
+	groups := make(map[string][]fieldInfo)
+
+	for _, field := range s.blockFields {
+		if hasOpt(field.opts, "required") && !given[field.key()] {
+			return s.withExamples(d.WrapErr(fmt.Errorf("%w subdirective %q", ErrMissingRequired, field.key())))
+		}
+	}
+
+	for _, fields := range [][]fieldInfo{s.otherFields, s.blockFields} {
+		for _, field := range fields {
+			if group, ok := optValue(field.opts, "group"); ok {
+				if _, ok := groups[group]; !ok {
+					groupNames = append(groupNames, group)
+				}
+				groups[group] = append(groups[group], field)
+			}
+		}
+	}
+
+	for _, group := range groupNames {
+		var atLeastOne, exclusive bool
+		var names []string
+		var n int
+
+		for _, field := range groups[group] {
+			atLeastOne = atLeastOne || hasOpt(field.opts, "atleastone")
+			exclusive = exclusive || hasOpt(field.opts, "exclusive")
+			names = append(names, field.displayName())
+			if given[field.key()] {
+				n++
+			}
+		}
+
+		switch {
+		case atLeastOne && n == 0:
+			return s.withExamples(d.WrapErr(fmt.Errorf(
+				"expected at least one of: %s", strings.Join(names, ", "))))
+		case exclusive && n > 1:
+			return s.withExamples(d.WrapErr(fmt.Errorf(
+				"expected at most one of: %s", strings.Join(names, ", "))))
+		}
+	}
+
+	return nil
+}
+
+// hasBlock returns true if the struct takes a block of subdirectives.
+func (s structInfo) hasBlock() bool {
+	return len(s.blockFields) > 0 || s.remain != nil || s.extras != nil
+}
+
 func (s structInfo) otherFieldAt(ix int) (fieldInfo, bool) {
 	if ix < 0 || ix >= len(s.otherFields) {
 		return fieldInfo{}, false
@@ -406,30 +1354,47 @@ func (s structInfo) otherFieldAt(ix int) (fieldInfo, bool) {
 	return s.otherFields[ix], true
 }
 
-var blockIxRe = regexp.MustCompile(`^\{(\d+)\}$`)
+var blockIxRe = regexp.MustCompile(`^\{(\d+)\}(\.\.\.)?$`)
 
-// extractFields extracts all struct fields from the given struct value.
-func extractFields(r reflectValue) (structInfo, error) {
+// extractFields extracts all struct fields from the given struct value. The
+// options may be nil.
+func extractFields(r reflectValue, o *Options) (structInfo, error) {
 	var info structInfo
 
 	nfields := r.v.NumField()
 	for i := 0; i < nfields; i++ {
 		f := r.t.Field(i)
+		if f.Name == "_" {
+			// struct-level options, e.g. `caddyfile:",primary=Root"`
+			info.opts = append(info.opts, splitTag(f.Tag.Get(o.tagKey()))[1:]...)
+			continue
+		}
+
 		if !f.IsExported() {
 			continue
 		}
 
-		tag := f.Tag.Get("caddyfile")
+		if f.Anonymous && f.Type.Implements(typeWrap) {
+			// embedded Wrap, which is not part of the syntax
+			continue
+		}
+
+		tag := f.Tag.Get(o.tagKey())
 		if tag == "" {
 			// no tag, so default kind
+			name, ok := defaultFieldName(f, o)
+			if !ok {
+				continue
+			}
+
 			info.blockFields = append(info.blockFields, fieldInfo{
 				f, reflectValue{r.v.Field(i), f.Type},
-				blockFieldKind{f.Name},
+				blockFieldKind{name, nil}, nil, r,
 			})
 			continue
 		}
 
-		parts := strings.Split(tag, ",")
+		parts := splitTag(tag)
 		name := parts[0]
 
 		switch {
@@ -441,7 +1406,7 @@ func extractFields(r reflectValue) (structInfo, error) {
 			// matcher field
 			info.matcher = &fieldInfo{
 				f, reflectValue{r.v.Field(i), f.Type},
-				matcherKind{},
+				matcherKind{}, parts[1:], r,
 			}
 		case blockIxRe.MatchString(name):
 			matches := blockIxRe.FindStringSubmatch(name)
@@ -451,9 +1416,9 @@ func extractFields(r reflectValue) (structInfo, error) {
 					"caddyunmarshal: invalid block index %s: %w", name, err)
 			}
 
-			info.otherFields = append(info.blockFields, fieldInfo{
+			info.otherFields = append(info.otherFields, fieldInfo{
 				f, reflectValue{r.v.Field(i), f.Type},
-				blockKind{ix, hasOpt(parts[1:], "optional")},
+				blockKind{ix, hasOpt(parts[1:], "optional"), matches[2] != ""}, parts[1:], r,
 			})
 		case strings.HasPrefix(name, "$"):
 			ix, err := strconv.Atoi(strings.TrimPrefix(name, "$"))
@@ -464,27 +1429,147 @@ func extractFields(r reflectValue) (structInfo, error) {
 
 			info.otherFields = append(info.otherFields, fieldInfo{
 				f, reflectValue{r.v.Field(i), f.Type},
-				argumentKind{ix, hasOpt(parts[1:], "optional")},
+				argumentKind{
+					ix,
+					hasOpt(parts[1:], "optional") || isOptional(f.Type),
+					hasOpt(parts[1:], "matcher"),
+				},
+				parts[1:], r,
 			})
 		default:
+			// Other accepted spellings follow the name, e.g.
+			// "header_up|request_header".
+			name, aliases := name, []string(nil)
+			if names := strings.Split(name, "|"); len(names) > 1 {
+				name, aliases = names[0], names[1:]
+			}
+			if name == "" && hasOpt(parts[1:], "remain") {
+				if f.Type != typeRemain {
+					return structInfo{}, fmt.Errorf(
+						"caddyunmarshal: remain field %s must be a map[string][]string, got %s", f.Name, f.Type)
+				}
+				if info.remain != nil {
+					return structInfo{}, fmt.Errorf(
+						"caddyunmarshal: duplicate remain field %s", f.Name)
+				}
+
+				info.remain = &fieldInfo{
+					f, reflectValue{r.v.Field(i), f.Type},
+					blockFieldKind{}, parts[1:], r,
+				}
+				continue
+			}
+			if name == "" && hasOpt(parts[1:], "present") {
+				if f.Type != typePresent {
+					return structInfo{}, fmt.Errorf(
+						"caddyunmarshal: present field %s must be a map[string]bool, got %s", f.Name, f.Type)
+				}
+				if len(info.present) > 0 {
+					return structInfo{}, fmt.Errorf(
+						"caddyunmarshal: duplicate present field %s", f.Name)
+				}
+
+				info.present = append(info.present, fieldInfo{
+					f, reflectValue{r.v.Field(i), f.Type},
+					blockFieldKind{}, parts[1:], r,
+				})
+				continue
+			}
+			if name == "" && hasOpt(parts[1:], "extras") {
+				if f.Type != typeExtras {
+					return structInfo{}, fmt.Errorf(
+						"caddyunmarshal: extras field %s must be a []caddyfile.Segment, got %s", f.Name, f.Type)
+				}
+				if info.extras != nil {
+					return structInfo{}, fmt.Errorf(
+						"caddyunmarshal: duplicate extras field %s", f.Name)
+				}
+
+				info.extras = &fieldInfo{
+					f, reflectValue{r.v.Field(i), f.Type},
+					blockFieldKind{}, parts[1:], r,
+				}
+				continue
+			}
+			if name == "" {
+				// only options are given, so use the default name
+				var ok bool
+				if name, ok = defaultFieldName(f, o); !ok {
+					name = o.fieldName(f.Name)
+				}
+			}
+
 			info.blockFields = append(info.blockFields, fieldInfo{
 				f, reflectValue{r.v.Field(i), f.Type},
-				blockFieldKind{name},
+				blockFieldKind{name, aliases}, parts[1:], r,
 			})
 		}
 	}
 
-	sort.Slice(info.blockFields, func(i, j int) bool {
-		return info.blockFields[i].index() < info.blockFields[j].index()
+	if info.remain != nil && info.extras != nil {
+		return structInfo{}, fmt.Errorf(
+			"caddyunmarshal: remain field %s and extras field %s are exclusive",
+			info.remain.field.Name, info.extras.field.Name)
+	}
+
+	if primary, ok := optValue(info.opts, "primary"); ok {
+		for i, field := range info.blockFields {
+			if field.field.Name == primary || field.kind.(blockFieldKind).name == primary {
+				info.primary = &info.blockFields[i]
+				break
+			}
+		}
+
+		if info.primary == nil {
+			return structInfo{}, fmt.Errorf(
+				"caddyunmarshal: primary field %s is not a subdirective field", primary)
+		}
+	}
+
+	if err := info.validate(); err != nil {
+		return structInfo{}, err
+	}
+
+	info.examples = examplesOf(r, info.opts)
+
+	return info, nil
+}
+
+// defaultFieldName returns the subdirective name of a field that isn't named
+// by its tag. The name of its json tag is used if there's one, since Caddy
+// modules already name their fields that way, or the field name converted by
+// the options otherwise. It returns false if the json tag is "-".
+func defaultFieldName(f reflect.StructField, o *Options) (string, bool) {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return o.fieldName(f.Name), true
+	default:
+		return name, true
+	}
+}
+
+// validate sorts the positional fields by their indices and validates them.
+func (info *structInfo) validate() error {
+	sort.SliceStable(info.otherFields, func(i, j int) bool {
+		return info.otherFields[i].index() < info.otherFields[j].index()
 	})
 
 	// validate that optional fields are at the end
 	var foundOptional bool
 	for i, field := range info.otherFields {
+		if kind, ok := field.kind.(argumentKind); ok && kind.matcher {
+			// Matcher positions are skipped when absent, so they may
+			// appear anywhere.
+			continue
+		}
+
 		optional := field.optional()
 
 		if foundOptional && !optional {
-			return structInfo{}, fmt.Errorf(
+			return fmt.Errorf(
 				"caddyunmarshal: illegal non-optional field %d follows optional field", i)
 		}
 
@@ -493,6 +1578,42 @@ func extractFields(r reflectValue) (structInfo, error) {
 		}
 	}
 
+	// validate that variadic fields are the last arguments
+	for i, field := range info.otherFields {
+		if !isVariadic(field) {
+			continue
+		}
+		for _, next := range info.otherFields[i+1:] {
+			if _, ok := next.kind.(argumentKind); ok {
+				return fmt.Errorf(
+					"caddyunmarshal: illegal argument field %d follows variadic field", next.index())
+			}
+		}
+	}
+
+	// validate that variadic blocks are slices and the last positions
+	for i, field := range info.otherFields {
+		if !isVariadicBlock(field) {
+			continue
+		}
+		if t := field.value.t; t.Kind() != reflect.Slice || isScalar(t) || isScalar(t.Elem()) {
+			return fmt.Errorf(
+				"caddyunmarshal: variadic block field %d must be a slice of blocks, got %s", field.index(), t)
+		}
+		if i != len(info.otherFields)-1 {
+			return fmt.Errorf(
+				"caddyunmarshal: illegal field %d follows variadic block field", info.otherFields[i+1].index())
+		}
+	}
+
+	for _, fields := range [][]fieldInfo{info.otherFields, info.blockFields} {
+		for _, field := range fields {
+			if err := validateMerge(field); err != nil {
+				return err
+			}
+		}
+	}
+
 	// validate that all field indices are unique
 	usedIndices := make(map[int]struct{})
 	for _, field := range info.otherFields {
@@ -500,14 +1621,14 @@ func extractFields(r reflectValue) (structInfo, error) {
 
 		_, used := usedIndices[ix]
 		if used {
-			return structInfo{}, fmt.Errorf(
+			return fmt.Errorf(
 				"caddyunmarshal: duplicate field index %d", ix)
 		}
 
 		usedIndices[ix] = struct{}{}
 	}
 
-	return info, nil
+	return nil
 }
 
 func hasOpt(parts []string, opt string) bool {
@@ -518,3 +1639,84 @@ func hasOpt(parts []string, opt string) bool {
 	}
 	return false
 }
+
+// snakeCase converts a Go field name to the snake_case name used for
+// subdirectives, e.g. "MaxConns" becomes "max_conns" and "HTTPPort" becomes
+// "http_port".
+func snakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+	b.Grow(len(name) + 2)
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Insert an underscore at word boundaries: either after a lower
+			// case letter or digit, or before the last upper case letter of
+			// an acronym.
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// optValue returns the value of the given "opt=value" option. Quoted values
+// are unquoted.
+func optValue(parts []string, opt string) (string, bool) {
+	for _, part := range parts {
+		if strings.HasPrefix(part, opt+"=") {
+			return unquoteOpt(strings.TrimPrefix(part, opt+"=")), true
+		}
+	}
+	return "", false
+}
+
+// optValues is like optValue, except all values of a repeated option are
+// returned.
+func optValues(parts []string, opt string) []string {
+	var values []string
+	for _, part := range parts {
+		if strings.HasPrefix(part, opt+"=") {
+			values = append(values, unquoteOpt(strings.TrimPrefix(part, opt+"=")))
+		}
+	}
+	return values
+}
+
+// splitTag splits the given struct tag value by commas. Commas within single
+// or double quotes are not split on, which allows option values like
+// doc='size, in bytes'.
+func splitTag(tag string) []string {
+	var parts []string
+	var quote rune
+	var start int
+
+	for i, r := range tag {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ',':
+			parts = append(parts, tag[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, tag[start:])
+}
+
+func unquoteOpt(v string) string {
+	if len(v) >= 2 && (v[0] == '\'' || v[0] == '"') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
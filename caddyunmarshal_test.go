@@ -1,6 +1,15 @@
 package caddyunmarshal
 
-import "github.com/caddyserver/caddy/v2"
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
 
 const testCaddyfile = `
 	thing1 arg1 {
@@ -47,4 +56,710 @@ type thing3 struct {
 	Arg2    string          `caddyfile:"$2,optional"`
 }
 
-// TODO: pointer type support for optionality testing
+func TestUnmarshalThing2(t *testing.T) {
+	v, err := unmarshalString[thing2](`
+		thing2 arg1 arg2 {
+			parameter value
+			number 100
+			flag
+			unknown {
+				ignored
+			}
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := thing2{
+		Arg1:   "arg1",
+		Arg2:   "arg2",
+		Param:  "value",
+		Number: 100,
+		Flag:   true,
+	}
+	if v != expect {
+		t.Errorf("unexpected value: %+v", v)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"missing argument", `thing2`},
+		{"missing argument before block", "thing2 {\n number 1\n}"},
+		{"extra argument", `thing2 a b c`},
+		{"bad int", "thing2 a {\n number abc\n}"},
+		{"extra subdirective argument", "thing2 a {\n number 1 2\n}"},
+		{"flag with invalid argument", "thing2 a {\n flag maybe\n}"},
+		{"flag with extra argument", "thing2 a {\n flag yes no\n}"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := unmarshalString[thing2](test.input); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+// unmarshalString unmarshals the first directive within the given input into
+// a new T.
+func unmarshalString[T any](input string) (T, error) {
+	var v T
+
+	d := caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	err := Unmarshal(d, &v)
+	return v, err
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"Number":   "number",
+		"MaxConns": "max_conns",
+		"HTTPPort": "http_port",
+		"TLS":      "tls",
+		"Retry5xx": "retry5xx",
+	}
+
+	for in, expect := range tests {
+		if got := snakeCase(in); got != expect {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, expect)
+		}
+	}
+}
+
+func TestUnmarshalRepeatedSubdirectives(t *testing.T) {
+	type headerOp struct {
+		Name  string `caddyfile:"$1"`
+		Value string `caddyfile:"$2,optional"`
+	}
+
+	type proxy struct {
+		HeaderUp []headerOp `caddyfile:"header_up"`
+		Hide     []string   `caddyfile:"hide"`
+		Ports    []int      `caddyfile:"port"`
+	}
+
+	v, err := unmarshalString[proxy](`
+		proxy {
+			header_up X-A a
+			header_up X-B b
+			header_up -X-C
+			hide .git
+			hide .env .htaccess
+			port 80
+			port 443
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := proxy{
+		HeaderUp: []headerOp{{"X-A", "a"}, {"X-B", "b"}, {"-X-C", ""}},
+		Hide:     []string{".git", ".env", ".htaccess"},
+		Ports:    []int{80, 443},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+
+	if _, err := unmarshalString[proxy]("proxy {\n hide\n}"); err == nil {
+		t.Error("expected error for subdirective without arguments")
+	}
+}
+
+func TestUnmarshalMapOfSlices(t *testing.T) {
+	type command struct {
+		Env    map[string][]string `caddyfile:"env"`
+		Labels map[string]string   `caddyfile:"labels"`
+	}
+
+	v, err := unmarshalString[command](`
+		command {
+			env {
+				PATH /bin
+				PATH /usr/bin /usr/local/bin
+				HOME /root
+			}
+			labels {
+				a 1
+				a 2
+			}
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := command{
+		Env: map[string][]string{
+			"PATH": {"/bin", "/usr/bin", "/usr/local/bin"},
+			"HOME": {"/root"},
+		},
+		Labels: map[string]string{"a": "2"},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+}
+
+func TestUnmarshalKV(t *testing.T) {
+	type headers struct {
+		Set     []KV[string]   `caddyfile:"set"`
+		Rewrite []KV[[]string] `caddyfile:"rewrite"`
+	}
+
+	v, err := unmarshalString[headers](`
+		headers {
+			set {
+				Z-Last z
+				A-First a
+				Z-Last again
+			}
+			rewrite {
+				/old /new
+				/a /b /c
+			}
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := headers{
+		Set: []KV[string]{
+			{"Z-Last", "z"},
+			{"A-First", "a"},
+			{"Z-Last", "again"},
+		},
+		Rewrite: []KV[[]string]{
+			{"/old", []string{"/new"}},
+			{"/a", []string{"/b", "/c"}},
+		},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+}
+
+func TestUnmarshalThing1(t *testing.T) {
+	v, err := unmarshalString[thing1](testCaddyfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := thing1{
+		Arg1:  "arg1",
+		Arg2:  "arg2",
+		Junk1: map[string]string{"foo": "bar", "baz": "qux"},
+		Junk2: map[string]string{"foo": "bar", "baz": "qux"},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+}
+
+func TestUnmarshalSecondBlock(t *testing.T) {
+	type indexed struct {
+		Arg  string            `caddyfile:"$1"`
+		Junk map[string]string `caddyfile:"{2}"`
+	}
+
+	_, err := unmarshalString[indexed]("indexed a {\n x 1\n} {\n y 2\n}")
+	if err == nil || !strings.Contains(err.Error(), "second block not allowed") {
+		t.Errorf("expected second block error, got %v", err)
+	}
+
+	_, err = unmarshalString[thing2]("thing2 a {\n number 1\n} {\n flag\n}")
+	if err == nil || !strings.Contains(err.Error(), "second block not allowed") {
+		t.Errorf("expected second block error, got %v", err)
+	}
+}
+
+func TestUnmarshalStructIndexedBlocks(t *testing.T) {
+	type junk struct {
+		Foo string
+		Baz string
+	}
+
+	type typedThing1 struct {
+		Arg1  string `caddyfile:"$1"`
+		Arg2  string `caddyfile:"$3,optional"`
+		Junk1 junk   `caddyfile:"{2}"`
+		Junk2 junk   `caddyfile:"{4},optional"`
+	}
+
+	v, err := unmarshalString[typedThing1](testCaddyfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := typedThing1{
+		Arg1:  "arg1",
+		Arg2:  "arg2",
+		Junk1: junk{"bar", "qux"},
+		Junk2: junk{"bar", "qux"},
+	}
+	if v != expect {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	type illegal struct {
+		Block struct {
+			Arg string `caddyfile:"$1"`
+		} `caddyfile:"{1}"`
+	}
+
+	if _, err := unmarshalString[illegal]("illegal {\n arg a\n}"); err == nil {
+		t.Error("expected error for positional field within indexed block")
+	}
+}
+
+func TestUnmarshalPrimary(t *testing.T) {
+	type fileServer struct {
+		_      struct{} `caddyfile:",primary=Root"`
+		Root   string   `caddyfile:"root"`
+		Browse bool     `caddyfile:"browse"`
+	}
+
+	for _, input := range []string{
+		"file_server /var/www",
+		"file_server {\n root /var/www\n}",
+	} {
+		v, err := unmarshalString[fileServer](input)
+		if err != nil {
+			t.Fatalf("%q: %v", input, err)
+		}
+		if v.Root != "/var/www" {
+			t.Errorf("%q: unexpected root %q", input, v.Root)
+		}
+	}
+
+	if _, err := unmarshalString[fileServer]("file_server /a /b"); err == nil {
+		t.Error("expected error for second primary argument")
+	}
+
+	type encode struct {
+		_         struct{} `caddyfile:",primary=formats"`
+		Level     string   `caddyfile:"$1,optional"`
+		Formats   []string `caddyfile:"formats"`
+		MinLength int      `caddyfile:"min_length"`
+	}
+
+	// $1 is consumed first, then the rest goes to the primary field.
+	v, err := unmarshalString[encode]("encode best gzip zstd {\n min_length 512\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := encode{Level: "best", Formats: []string{"gzip", "zstd"}, MinLength: 512}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	type badPrimary struct {
+		_    struct{} `caddyfile:",primary=Missing"`
+		Root string
+	}
+
+	if _, err := unmarshalString[badPrimary]("bad /a"); err == nil {
+		t.Error("expected error for unknown primary field")
+	}
+}
+
+func TestUnmarshalEnum(t *testing.T) {
+	type tls struct {
+		Mode  string   `caddyfile:"$1,optional,enum=strict|lenient|off"`
+		Curve []string `caddyfile:"curves,enum=x25519|p256"`
+	}
+
+	v, err := unmarshalString[tls]("tls lenient {\n curves x25519 p256\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := tls{Mode: "lenient", Curve: []string{"x25519", "p256"}}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	_, err = unmarshalString[tls]("tls loose")
+	if err == nil || !strings.Contains(err.Error(), "expected one of: strict, lenient, off") {
+		t.Errorf("expected enum error, got %v", err)
+	}
+
+	if _, err := unmarshalString[tls]("tls {\n curves p384\n}"); err == nil {
+		t.Error("expected enum error for slice element")
+	}
+}
+
+func TestUnmarshalEmpty(t *testing.T) {
+	type options struct {
+		Arg     string            `caddyfile:"$1,optional"`
+		Extra   map[string]string `caddyfile:"{2},optional"`
+		Timeout string            `caddyfile:"timeout"`
+		Headers map[string]string `caddyfile:"headers"`
+		Flag    bool
+	}
+
+	defaults := options{Timeout: "30s"}
+
+	for _, input := range []string{
+		"mydir",
+		"mydir {\n}",
+		"mydir { }",
+		"mydir {}",
+	} {
+		v := defaults
+
+		d := caddyfile.NewTestDispenser(input)
+		d.Next()
+
+		if err := Unmarshal(d, &v); err != nil {
+			t.Errorf("%q: %v", input, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, defaults) {
+			t.Errorf("%q: defaults were not kept: %+v", input, v)
+		}
+	}
+
+	v, err := unmarshalString[options]("mydir a {}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Arg != "a" || v.Extra == nil || len(v.Extra) != 0 {
+		t.Errorf("expected empty indexed block, got %+v", v)
+	}
+
+	v, err = unmarshalString[options]("mydir {\n headers {}\n flag\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Headers == nil || !v.Flag {
+		t.Errorf("expected empty headers block followed by flag, got %+v", v)
+	}
+
+	// Structs without any subdirectives still accept an empty block.
+	type argsOnly struct {
+		Arg string `caddyfile:"$1,optional"`
+	}
+
+	for _, input := range []string{"mydir", "mydir {}", "mydir a {\n}"} {
+		if _, err := unmarshalString[argsOnly](input); err != nil {
+			t.Errorf("%q: %v", input, err)
+		}
+	}
+
+	if _, err := unmarshalString[argsOnly]("mydir {\n x\n}"); err == nil {
+		t.Error("expected error for non-empty block without subdirectives")
+	}
+
+	// Quoted braces are still arguments.
+	if v, err := unmarshalString[argsOnly](`mydir "{}"`); err != nil || v.Arg != "{}" {
+		t.Errorf("expected quoted argument, got %+v, %v", v, err)
+	}
+
+	// Required arguments are still required.
+	if _, err := unmarshalString[thing2]("thing2 {}"); err == nil {
+		t.Error("expected error for missing required argument")
+	}
+}
+
+func TestUnmarshalString(t *testing.T) {
+	var v thing2
+	err := UnmarshalString("thing2", "thing2 arg1 {\n\tnumber 100\n\tflag\n}", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := thing2{Arg1: "arg1", Number: 100, Flag: true}
+	if v != expect {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+
+	errorTests := []struct {
+		name    string
+		snippet string
+		expect  string
+	}{
+		{"empty", "", "caddyunmarshal: expected directive thing2, got empty snippet"},
+		{"wrong directive", "thing1 arg1", "Caddyfile:1 - Error during parsing: expected directive thing2, got thing1"},
+		{"trailing directive", "thing2 arg1\nthing2 arg2", "Caddyfile:2 - Error during parsing: unexpected thing2 after directive thing2"},
+	}
+
+	for _, test := range errorTests {
+		t.Run(test.name, func(t *testing.T) {
+			var v thing2
+			err := UnmarshalString("thing2", test.snippet, &v)
+			if err == nil || err.Error() != test.expect {
+				t.Errorf("unexpected error:\ngot  %v\nwant %s", err, test.expect)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTokens(t *testing.T) {
+	d := caddyfile.NewTestDispenser("route {\n\tthing2 arg1 {\n\t\tnumber 100\n\t}\n\tthing2 arg2\n}")
+	d.Next()
+
+	var values []thing2
+	for d.NextBlock(0) {
+		var v thing2
+		if err := UnmarshalTokens(d.NextSegment(), &v); err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v)
+	}
+
+	expect := []thing2{{Arg1: "arg1", Number: 100}, {Arg1: "arg2"}}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("unexpected values:\ngot  %+v\nwant %+v", values, expect)
+	}
+
+	var v thing2
+	if err := UnmarshalTokens(nil, &v); err == nil || err.Error() != "caddyunmarshal: expected directive, got no tokens" {
+		t.Errorf("unexpected error for no tokens: %v", err)
+	}
+
+	tokens, _ := caddyfile.Tokenize([]byte("thing2 arg1\nthing2 arg2"), "Caddyfile")
+	err := UnmarshalTokens(tokens, &v)
+	if expect := "Caddyfile:2 - Error during parsing: unexpected thing2 after directive thing2"; err == nil || err.Error() != expect {
+		t.Errorf("unexpected error:\ngot  %v\nwant %s", err, expect)
+	}
+}
+
+func TestUnmarshalRequired(t *testing.T) {
+	type target struct {
+		Root     string   `caddyfile:"root,required"`
+		Upstream []string `caddyfile:"upstream,required"`
+		Flag     bool     `caddyfile:"flag"`
+		_        struct{} `caddyfile:",primary=upstream"`
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"all given", "target {\n root /srv\n upstream a\n}", ""},
+		{"primary shortcut", "target a b {\n root /srv\n}", ""},
+		{"empty value", "target {\n root \"\"\n upstream a\n}", ""},
+		{
+			"missing subdirective",
+			"target {\n upstream a\n flag\n}",
+			`target: Testfile:4 - Error during parsing: missing required subdirective "root"`,
+		},
+		{
+			"no block",
+			"target a",
+			`target: Testfile:1 - Error during parsing: missing required subdirective "root"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := unmarshalString[target](test.input)
+			if test.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.err {
+				t.Errorf("unexpected error:\ngot  %v\nwant %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalRequiredInBlock(t *testing.T) {
+	type health struct {
+		URI string `caddyfile:"uri,required"`
+	}
+	type target struct {
+		Health health `caddyfile:"{1}"`
+	}
+
+	if _, err := unmarshalString[target]("target {\n uri /health\n}"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := unmarshalString[target]("target {\n}")
+	const expect = `target > [0]: Testfile:2 - Error during parsing: missing required subdirective "uri"`
+	if err == nil || err.Error() != expect {
+		t.Errorf("unexpected error:\ngot  %v\nwant %s", err, expect)
+	}
+}
+
+func TestUnmarshalGroups(t *testing.T) {
+	type target struct {
+		Address   string   `caddyfile:"$1,optional,group=target,atleastone"`
+		Upstreams []string `caddyfile:"upstreams,group=target"`
+		Cert      string   `caddyfile:"cert,group=tls,exclusive"`
+		Internal  bool     `caddyfile:"internal,group=tls"`
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"argument", "target localhost", ""},
+		{"subdirective", "target {\n upstreams a b\n}", ""},
+		{"both", "target localhost {\n upstreams a\n cert a.pem\n}", ""},
+		{
+			"none",
+			"target {\n internal\n}",
+			"target: Testfile:3 - Error during parsing: expected at least one of: <address>, upstreams",
+		},
+		{
+			"none without block",
+			"target",
+			"target: Testfile:1 - Error during parsing: expected at least one of: <address>, upstreams",
+		},
+		{
+			"exclusive",
+			"target localhost {\n cert a.pem\n internal\n}",
+			"target: Testfile:4 - Error during parsing: expected at most one of: cert, internal",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := unmarshalString[target](test.input)
+			if test.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.err {
+				t.Errorf("unexpected error:\ngot  %v\nwant %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalAliases(t *testing.T) {
+	type proxy struct {
+		HeaderUp []string `caddyfile:"header_up|request_header,doc='request headers'"`
+		Timeout  string   `caddyfile:"|read_timeout"`
+	}
+
+	v, err := unmarshalString[proxy](`
+		proxy {
+			header_up X-A a
+			request_header X-B b
+			read_timeout 5s
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(v.HeaderUp, []string{"X-A", "a", "X-B", "b"}) {
+		t.Errorf("unexpected headers: %v", v.HeaderUp)
+	}
+	if v.Timeout != "5s" {
+		t.Errorf("unexpected timeout: %q", v.Timeout)
+	}
+
+	syntax, err := SyntaxOf[proxy]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub := syntax.Subdirectives[0]; sub.Name != "header_up" || !reflect.DeepEqual(sub.Aliases, []string{"request_header"}) {
+		t.Errorf("unexpected subdirective syntax: %+v", sub)
+	}
+
+	docs, err := Docs[proxy]("proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(docs, "request headers Also accepted as `request_header`.") {
+		t.Errorf("missing aliases in docs:\n%s", docs)
+	}
+}
+
+func TestUnmarshalAliasesConflict(t *testing.T) {
+	type a struct {
+		Root string `caddyfile:"root|path"`
+	}
+	type b struct {
+		Path string
+	}
+
+	_, err := extractMulti([]any{new(a), new(b)}, nil)
+	if err == nil || !strings.Contains(err.Error(), `subdirective "path" is declared by both`) {
+		t.Errorf("expected conflict error, got %v", err)
+	}
+}
+
+func TestUnmarshalJSONNames(t *testing.T) {
+	type module struct {
+		MaxConns  int      `json:"max_conns_per_host,omitempty"`
+		Hosts     []string `json:"hosts,omitempty" caddyfile:",optional"`
+		Keepalive string   `json:"-"`
+		Tags      []string `json:"-" caddyfile:",optional"`
+		Timeout   string
+	}
+
+	v, err := unmarshalString[module]("module {\n max_conns_per_host 5\n hosts a b\n tags c\n timeout 1s\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := module{MaxConns: 5, Hosts: []string{"a", "b"}, Tags: []string{"c"}, Timeout: "1s"}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("expected %+v, got %+v", expect, v)
+	}
+
+	_, err = unmarshalStringWithOptions[module]("module {\n keepalive on\n}", Options{Strict: true})
+	if !errors.Is(err, ErrUnknownSubdirective) {
+		t.Errorf("expected json:\"-\" field to be ignored, got %v", err)
+	}
+}
+
+func TestUnmarshalLocation(t *testing.T) {
+	type rotate struct {
+		Every    time.Duration  `caddyfile:"$1"`
+		Timezone *time.Location `caddyfile:"timezone"`
+	}
+
+	v, err := unmarshalString[rotate]("rotate 24h {\n timezone America/New_York\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Timezone == nil || v.Timezone.String() != "America/New_York" {
+		t.Errorf("unexpected time zone: %v", v.Timezone)
+	}
+
+	_, err = unmarshalString[rotate]("rotate 24h {\n timezone Mars/Olympus_Mons\n}")
+	if err == nil || !strings.Contains(err.Error(), "cannot load time zone") {
+		t.Errorf("expected time zone error, got %v", err)
+	}
+
+	b, err := Marshal("rotate", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "rotate 24h0m0s {\n\ttimezone America/New_York\n}\n" {
+		t.Errorf("unexpected output:\n%s", b)
+	}
+
+	usage, err := Usage[rotate]("rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(usage, "timezone <time_zone>") {
+		t.Errorf("unexpected usage:\n%s", usage)
+	}
+}
@@ -0,0 +1,137 @@
+package caddyunmarshaltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/diamondburned/caddyunmarshal"
+)
+
+// Corpus runs every Caddyfile snippet within dir as a subtest, unmarshaling
+// it into a new T and comparing the result with the expected one. For each
+// snippet file named NAME.caddyfile, the directory contains either
+//
+//   - NAME.json, the canonical JSON encoding of the unmarshaled T, or
+//   - NAME.err, the expected error message, for snippets that must fail.
+//
+// Mismatches are reported as line diffs. Run the tests with -update to write
+// the expected files from the current results; snippets without an expected
+// file are also written that way.
+func Corpus[T any](t *testing.T, dir, directive string) {
+	t.Helper()
+
+	snippets, err := filepath.Glob(filepath.Join(dir, "*.caddyfile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snippets) == 0 {
+		t.Fatalf("no .caddyfile snippets in %s", dir)
+	}
+
+	for _, snippet := range snippets {
+		base := strings.TrimSuffix(snippet, ".caddyfile")
+		t.Run(filepath.Base(base), func(t *testing.T) {
+			input, err := os.ReadFile(snippet)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var v T
+			var got []byte
+			var path string
+
+			if err := caddyunmarshal.UnmarshalString(directive, string(input), &v); err != nil {
+				got = []byte(err.Error() + "\n")
+				path = base + ".err"
+			} else {
+				got, err = json.MarshalIndent(v, "", "\t")
+				if err != nil {
+					t.Fatalf("cannot marshal %s: %v", directive, err)
+				}
+				got = append(got, '\n')
+				path = base + ".json"
+			}
+
+			compareExpected(t, base, path, got)
+		})
+	}
+}
+
+// compareExpected compares got against the expected file of the given base
+// name, which is either .json or .err. path is the expected file that matches
+// the kind of result.
+func compareExpected(t *testing.T, base, path string, got []byte) {
+	t.Helper()
+
+	if *update {
+		os.Remove(base + ".json")
+		os.Remove(base + ".err")
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		switch path {
+		case base + ".json":
+			t.Fatalf("unexpected success, got:\n%s", got)
+		default:
+			t.Fatalf("unexpected error: %s", bytes.TrimSpace(got))
+		}
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("mismatch with %s (-want +got):\n%s", path, Diff(string(want), string(got)))
+	}
+}
+
+// Diff returns a line diff between want and got, where removed lines are
+// prefixed with "-", added lines with "+", and unchanged lines with " ".
+func Diff(want, got string) string {
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			sb.WriteString(" " + a[i] + "\n")
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+
+	return sb.String()
+}
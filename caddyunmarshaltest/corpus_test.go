@@ -0,0 +1,17 @@
+package caddyunmarshaltest
+
+import "testing"
+
+func TestCorpus(t *testing.T) {
+	Corpus[goldenTarget](t, "testdata/corpus", "upstream")
+}
+
+func TestDiff(t *testing.T) {
+	want := "a\nb\nc\n"
+	got := "a\nc\nd\n"
+
+	const expect = " a\n-b\n c\n+d\n"
+	if diff := Diff(want, got); diff != expect {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+}
@@ -0,0 +1,113 @@
+// Package caddyunmarshaltest provides helpers for testing directives that are
+// unmarshaled using caddyunmarshal.
+package caddyunmarshaltest
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// Builder builds a Caddyfile directive programmatically. Use Fixture to create
+// one.
+type Builder struct {
+	name  string
+	args  []string
+	block []*Builder
+}
+
+// Fixture creates a new Builder for the given directive, e.g.
+//
+//	caddyunmarshaltest.Fixture("reverse_proxy").
+//		Args("localhost:8080").
+//		Block("lb_policy", "first").
+//		Nest(caddyunmarshaltest.Fixture("health").Block("interval", "5s")).
+//		String()
+func Fixture(directive string) *Builder {
+	return &Builder{name: directive}
+}
+
+// Args appends arguments to the directive line.
+func (b *Builder) Args(args ...string) *Builder {
+	b.args = append(b.args, args...)
+	return b
+}
+
+// Block appends a subdirective with the given arguments to the directive's
+// block.
+func (b *Builder) Block(name string, args ...string) *Builder {
+	return b.Nest(Fixture(name).Args(args...))
+}
+
+// Nest appends the given subdirective, which may have its own block, to the
+// directive's block.
+func (b *Builder) Nest(sub *Builder) *Builder {
+	b.block = append(b.block, sub)
+	return b
+}
+
+// String renders the directive as a Caddyfile snippet, formatted the same way
+// caddy fmt would format it.
+func (b *Builder) String() string {
+	var sb strings.Builder
+	b.write(&sb)
+	return string(caddyfile.Format([]byte(sb.String())))
+}
+
+// Dispenser returns a dispenser over the rendered directive with the
+// directive name already consumed, which is what caddyunmarshal.Unmarshal
+// expects.
+func (b *Builder) Dispenser() *caddyfile.Dispenser {
+	d := caddyfile.NewTestDispenser(b.String())
+	d.Next()
+	return d
+}
+
+func (b *Builder) write(sb *strings.Builder) {
+	sb.WriteString(Quote(b.name))
+	for _, arg := range b.args {
+		sb.WriteByte(' ')
+		sb.WriteString(Quote(arg))
+	}
+
+	if len(b.block) > 0 {
+		sb.WriteString(" {\n")
+		for _, sub := range b.block {
+			sub.write(sb)
+		}
+		sb.WriteString("}")
+	}
+
+	sb.WriteByte('\n')
+}
+
+// Quote quotes the given token if the Caddyfile lexer would otherwise not read
+// it back as the same single token.
+func Quote(token string) string {
+	if !needsQuote(token) {
+		return token
+	}
+
+	switch {
+	case !strings.Contains(token, `"`):
+		return `"` + token + `"`
+	case !strings.Contains(token, "`"):
+		return "`" + token + "`"
+	default:
+		return `"` + strings.ReplaceAll(token, `"`, `\"`) + `"`
+	}
+}
+
+func needsQuote(token string) bool {
+	if token == "" || token == "{" || token == "}" {
+		return true
+	}
+
+	switch token[0] {
+	case '"', '`', '#':
+		return true
+	}
+
+	return strings.IndexFunc(token, unicode.IsSpace) != -1
+}
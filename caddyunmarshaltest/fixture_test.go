@@ -0,0 +1,78 @@
+package caddyunmarshaltest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/diamondburned/caddyunmarshal"
+)
+
+func TestFixture(t *testing.T) {
+	fixture := Fixture("proxy").
+		Args("localhost:8080", "hello world").
+		Block("lb_policy", "first").
+		Block("header_up", "X-Quote", `say "hi"`).
+		Nest(Fixture("health").Args("/healthz").Block("interval", "5s")).
+		Block("flag")
+
+	const expect = "proxy localhost:8080 \"hello world\" {\n" +
+		"\tlb_policy first\n" +
+		"\theader_up X-Quote `say \"hi\"`\n" +
+		"\thealth /healthz {\n" +
+		"\t\tinterval 5s\n" +
+		"\t}\n" +
+		"\tflag\n" +
+		"}\n"
+
+	if s := fixture.String(); s != expect {
+		t.Errorf("unexpected fixture:\n%s\nwant:\n%s", s, expect)
+	}
+
+	type header struct {
+		Name  string `caddyfile:"$1"`
+		Value string `caddyfile:"$2"`
+	}
+
+	type proxy struct {
+		To       string   `caddyfile:"$1"`
+		Greeting string   `caddyfile:"$2"`
+		Policy   string   `caddyfile:"lb_policy"`
+		HeaderUp []header `caddyfile:"header_up"`
+		Flag     bool
+	}
+
+	var v proxy
+	if err := caddyunmarshal.Unmarshal(fixture.Dispenser(), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	expectValue := proxy{
+		To:       "localhost:8080",
+		Greeting: "hello world",
+		Policy:   "first",
+		HeaderUp: []header{{"X-Quote", `say "hi"`}},
+		Flag:     true,
+	}
+	if !reflect.DeepEqual(v, expectValue) {
+		t.Errorf("unexpected value: %+v", v)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := map[string]string{
+		"plain":         "plain",
+		"":              `""`,
+		"{":             `"{"`,
+		"{placeholder}": "{placeholder}",
+		"#comment":      `"#comment"`,
+		"a b":           `"a b"`,
+		"a\"b c":        "`a\"b c`",
+		"a\"b`c d":      `"a\"b` + "`" + `c d"`,
+	}
+
+	for in, expect := range tests {
+		if got := Quote(in); got != expect {
+			t.Errorf("Quote(%q) = %s, want %s", in, got, expect)
+		}
+	}
+}
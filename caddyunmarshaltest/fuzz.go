@@ -0,0 +1,163 @@
+package caddyunmarshaltest
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/diamondburned/caddyunmarshal"
+)
+
+// fuzzFile is the file name given to fuzzed tokens, which every error is
+// expected to mention.
+const fuzzFile = "Fuzzfile"
+
+// Fuzz fuzzes caddyunmarshal.Unmarshal into a new T with Caddyfile snippets.
+// The corpus is seeded with snippets derived from the syntax of T, which the
+// fuzzer then mutates. Unmarshaling must never panic, and every error must
+// carry the position of the offending token. Use it in a fuzz test:
+//
+//	func FuzzMyDirective(f *testing.F) {
+//		caddyunmarshaltest.Fuzz[MyDirective](f)
+//	}
+//
+// Matchers are only parsed by caddyunmarshal.UnmarshalForHTTP, so T should not
+// have a $matcher field.
+func Fuzz[T any](f *testing.F) {
+	f.Helper()
+
+	syntax, err := caddyunmarshal.SyntaxOf[T]()
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, seed := range Seeds(syntax) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		tokens, err := caddyfile.Tokenize([]byte(input), fuzzFile)
+		if err != nil || len(tokens) == 0 {
+			t.Skip()
+		}
+
+		d := caddyfile.NewDispenser(tokens)
+		d.Next()
+
+		if err := caddyunmarshal.Unmarshal(d, new(T)); err != nil {
+			if !strings.Contains(err.Error(), fuzzFile+":") {
+				t.Errorf("error without position for input %q: %v", input, err)
+			}
+		}
+	})
+}
+
+// Seeds returns Caddyfile snippets for the directive described by the given
+// syntax: the bare directive, one with its required arguments, one with all
+// arguments and subdirectives, and a few malformed ones.
+func Seeds(syntax caddyunmarshal.Syntax) []string {
+	const name = "directive"
+
+	var required []string
+	for _, arg := range syntax.Arguments {
+		if arg.Optional || arg.Type == "block" {
+			break
+		}
+		required = append(required, seedValue(arg)...)
+	}
+
+	return []string{
+		name + "\n",
+		Fixture(name).Args(required...).String(),
+		seedDirective(Fixture(name), syntax, 0).String(),
+		name + " {\n",
+		name + " }\n",
+		name + " {\n\tunknown\n}\n",
+		name + ` ""` + "\n",
+	}
+}
+
+// maxSeedDepth limits the nesting of seeded subdirectives.
+const maxSeedDepth = 4
+
+func seedDirective(b *Builder, syntax caddyunmarshal.Syntax, depth int) *Builder {
+	if depth > maxSeedDepth {
+		return b
+	}
+
+	for _, arg := range syntax.Arguments {
+		switch arg.Type {
+		case "matcher":
+			continue
+		case "block":
+			seedDirective(b, arg, depth+1)
+		default:
+			b.Args(seedValue(arg)...)
+		}
+	}
+
+	for _, sub := range syntax.Subdirectives {
+		b.Nest(seedSubdirective(sub, depth+1))
+	}
+
+	return b
+}
+
+func seedSubdirective(sub caddyunmarshal.Syntax, depth int) *Builder {
+	b := Fixture(sub.Name)
+
+	switch sub.Type {
+	case "flag":
+		return b
+	case "struct":
+		if sub.Ref != "" {
+			return b
+		}
+		return seedDirective(b, sub, depth)
+	case "map", "kv":
+		return b.Block("key", seedValue(*sub.Value)...)
+	case "variant":
+		names := make([]string, 0, len(sub.Variants))
+		for name := range sub.Variants {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return b
+		}
+		return seedDirective(b.Args(names[0]), sub.Variants[names[0]], depth)
+	default:
+		return b.Args(seedValue(sub)...)
+	}
+}
+
+// seedValue returns the arguments of a valid value of the given syntax.
+func seedValue(syntax caddyunmarshal.Syntax) []string {
+	if len(syntax.Enum) > 0 {
+		return []string{syntax.Enum[0]}
+	}
+
+	switch syntax.Type {
+	case "flag":
+		return nil
+	case "int", "uint":
+		return []string{"1"}
+	case "float":
+		return []string{"1.5"}
+	case "bool":
+		return []string{"true"}
+	case "duration":
+		return []string{"5s"}
+	case "address":
+		return []string{"localhost:8080"}
+	case "network_address":
+		return []string{"tcp/localhost:8080"}
+	case "schemaless":
+		return []string{"a", "b"}
+	case "struct", "block", "map", "kv", "variant":
+		return nil
+	default:
+		return []string{"value"}
+	}
+}
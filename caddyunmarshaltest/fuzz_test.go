@@ -0,0 +1,61 @@
+package caddyunmarshaltest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/diamondburned/caddyunmarshal"
+)
+
+type fuzzTarget struct {
+	Host    string         `caddyfile:"$1"`
+	Port    int            `caddyfile:"$2,optional"`
+	Mode    string         `caddyfile:"mode,enum=fast|slow"`
+	Timeout time.Duration  `caddyfile:"timeout"`
+	Verbose bool           `caddyfile:"verbose"`
+	Headers map[string]int `caddyfile:"headers"`
+	Nested  struct {
+		Name  string   `caddyfile:"name"`
+		Ratio float64  `caddyfile:"ratio"`
+		Tags  []string `caddyfile:"tags"`
+	} `caddyfile:"nested"`
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	Fuzz[fuzzTarget](f)
+}
+
+func TestSeeds(t *testing.T) {
+	syntax, err := caddyunmarshal.SyntaxOf[fuzzTarget]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seeds := Seeds(syntax)
+
+	const expect = `directive value 1 {
+	mode fast
+	timeout 5s
+	verbose
+	headers {
+		key 1
+	}
+	nested {
+		name value
+		ratio 1.5
+		tags value
+	}
+}
+`
+	if seeds[2] != expect {
+		t.Errorf("unexpected full seed:\n%s", seeds[2])
+	}
+
+	var v fuzzTarget
+	d := caddyfile.NewTestDispenser(seeds[2])
+	d.Next()
+	if err := caddyunmarshal.Unmarshal(d, &v); err != nil {
+		t.Errorf("full seed is invalid: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package caddyunmarshaltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diamondburned/caddyunmarshal"
+)
+
+var update = flag.Bool("update", false, "update the expected files of caddyunmarshaltest.Golden and Corpus")
+
+// Golden unmarshals the given snippet of the directive into a new T and
+// compares its canonical JSON encoding against the golden file at path,
+// failing the test on mismatch. Run the tests with -update to (re)write the
+// golden files instead. The unmarshaled value is returned for further checks:
+//
+//	func TestMyDirective(t *testing.T) {
+//		caddyunmarshaltest.Golden[MyDirective](t, "my_directive",
+//			"my_directive arg {\n\tflag\n}", "testdata/basic.golden")
+//	}
+func Golden[T any](t testing.TB, directive, snippet, path string) T {
+	t.Helper()
+
+	var v T
+	if err := caddyunmarshal.UnmarshalString(directive, snippet, &v); err != nil {
+		t.Fatalf("cannot unmarshal %s: %v", directive, err)
+	}
+
+	got, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		t.Fatalf("cannot marshal %s: %v", directive, err)
+	}
+	got = append(got, '\n')
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read golden file (run with -update to create it): %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match golden file %s (-want +got):\n%s", directive, path, Diff(string(want), string(got)))
+	}
+
+	return v
+}
@@ -0,0 +1,42 @@
+package caddyunmarshaltest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type goldenTarget struct {
+	Host    string            `caddyfile:"$1" json:"host"`
+	Port    int               `caddyfile:"port" json:"port,omitempty"`
+	Headers map[string]string `caddyfile:"headers" json:"headers,omitempty"`
+}
+
+func TestGolden(t *testing.T) {
+	v := Golden[goldenTarget](t, "upstream",
+		"upstream localhost {\n\tport 8080\n\theaders {\n\t\tX-A a\n\t}\n}",
+		"testdata/upstream.golden")
+
+	if v.Host != "localhost" {
+		t.Errorf("unexpected host %q", v.Host)
+	}
+}
+
+func TestGoldenUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "update.golden")
+
+	*update = true
+	defer func() { *update = false }()
+
+	Golden[goldenTarget](t, "upstream", "upstream localhost", path)
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "{\n\t\"host\": \"localhost\"\n}\n"
+	if string(b) != expect {
+		t.Errorf("unexpected golden file:\n%s", b)
+	}
+}
@@ -0,0 +1,54 @@
+package caddyunmarshaltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/diamondburned/caddyunmarshal"
+)
+
+// roundTripDirective is the directive name that values are marshaled under,
+// which Unmarshal doesn't care about.
+const roundTripDirective = "round_trip"
+
+// RoundTrip marshals v, unmarshals the output into a new T and fails the test
+// unless the result is deeply equal to v. Secret fields are marshaled as they
+// are. This catches asymmetries between Marshal and Unmarshal, e.g.
+//
+//	func TestMyDirectiveRoundTrip(t *testing.T) {
+//		caddyunmarshaltest.RoundTrip(t, MyDirective{Arg: "a", Flag: true})
+//	}
+//
+// Note that empty slices and maps are left out by Marshal, so they come back
+// as nil.
+func RoundTrip[T any](t testing.TB, v T) {
+	t.Helper()
+
+	b, err := caddyunmarshal.MarshalWithOptions(roundTripDirective, &v, caddyunmarshal.MarshalOptions{
+		Secrets: true,
+	})
+	if err != nil {
+		t.Fatalf("cannot marshal %T: %v", v, err)
+	}
+
+	var got T
+	if err := caddyunmarshal.UnmarshalString(roundTripDirective, string(b), &got); err != nil {
+		t.Fatalf("cannot unmarshal %T from:\n%s\nerror: %v", v, b, err)
+	}
+
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("%T changed after a round trip through:\n%s\n(-want +got):\n%s", v, b, Diff(dump(v), dump(got)))
+	}
+}
+
+// dump returns the JSON encoding of v for diffing, falling back to the Go
+// syntax representation.
+func dump(v any) string {
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return fmt.Sprintf("%#v\n", v)
+	}
+	return string(b) + "\n"
+}
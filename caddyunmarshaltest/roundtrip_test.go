@@ -0,0 +1,61 @@
+package caddyunmarshaltest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+type roundTripHealth struct {
+	URI      string         `caddyfile:"$1" json:"uri"`
+	Interval caddy.Duration `caddyfile:"interval" json:"interval,omitempty"`
+}
+
+type roundTripTarget struct {
+	Host     string            `caddyfile:"$1" json:"host"`
+	Port     int               `caddyfile:"$2,optional" json:"port,omitempty"`
+	Headers  map[string]string `caddyfile:"headers" json:"headers,omitempty"`
+	Health   []roundTripHealth `caddyfile:"health" json:"health,omitempty"`
+	Hosts    []string          `caddyfile:"hosts" json:"hosts"`
+	Password string            `caddyfile:"password,secret" json:"-"`
+	Insecure bool              `caddyfile:"insecure" json:"insecure,omitempty"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	RoundTrip(t, roundTripTarget{
+		Host:    "localhost",
+		Port:    8080,
+		Headers: map[string]string{"X-A": "a b", "X-B": ""},
+		Health: []roundTripHealth{
+			{URI: "/health", Interval: caddy.Duration(5 * time.Second)},
+			{URI: "/ready"},
+		},
+		Hosts:    []string{"a", "b"},
+		Password: "hunter2",
+		Insecure: true,
+	})
+}
+
+// recordingTB records the errors of a test instead of failing it.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRoundTripMismatch(t *testing.T) {
+	r := &recordingTB{TB: t}
+	RoundTrip[roundTripTarget](r, roundTripTarget{Host: "localhost", Hosts: []string{}})
+
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], `-	"hosts": []`) {
+		t.Errorf("expected a mismatch of hosts, got %q", r.errors)
+	}
+}
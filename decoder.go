@@ -0,0 +1,188 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+)
+
+// Decoder unmarshals values of type T using the same options every time.
+// Unlike UnmarshalWithOptions, it extracts the fields of each struct type from
+// their tags only once, which makes unmarshaling many directives faster, e.g.
+//
+//	var decoder = caddyunmarshal.MustNewDecoder[Handler](caddyunmarshal.Options{Strict: true})
+//
+//	func (h *Handler) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+//		return decoder.Decode(d, h)
+//	}
+//
+// A Decoder is safe for concurrent use, unless Options.Warnings is set.
+type Decoder[T any] struct {
+	options Options
+	plans   *fieldPlans
+}
+
+// NewDecoder creates a Decoder using the given options. The fields of T and of
+// the structs nested within it are extracted right away, so invalid tags are
+// reported here instead of when a directive first reaches them.
+func NewDecoder[T any](opts Options) (*Decoder[T], error) {
+	dec := &Decoder[T]{options: opts}
+	dec.plans = &fieldPlans{options: &dec.options}
+
+	if err := dec.plans.compile(reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+		return nil, err
+	}
+
+	return dec, nil
+}
+
+// MustNewDecoder is like NewDecoder, except it panics on error. It is meant
+// for package-level variables.
+func MustNewDecoder[T any](opts Options) *Decoder[T] {
+	dec, err := NewDecoder[T](opts)
+	if err != nil {
+		panic(err)
+	}
+	return dec
+}
+
+// Decode is like UnmarshalWithOptions with the options of the Decoder.
+func (dec *Decoder[T]) Decode(d *caddyfile.Dispenser, v *T) error {
+	r, err := newReflectValue(v)
+	if err != nil {
+		return err
+	}
+	return withPath(d.Val(), unmarshal(dec.dispenser(d, nil), r))
+}
+
+// DecodeForHTTP is like Decode, except matchers are supported as with
+// UnmarshalForHTTP.
+func (dec *Decoder[T]) DecodeForHTTP(h *httpcaddyfile.Helper, v *T) error {
+	r, err := newReflectValue(v)
+	if err != nil {
+		return err
+	}
+	return withPath(h.Val(), unmarshal(dec.dispenser(h.Dispenser, h), r))
+}
+
+func (dec *Decoder[T]) dispenser(d *caddyfile.Dispenser, h *httpcaddyfile.Helper) dispenser {
+	return dispenser{Dispenser: d, http: h, options: &dec.options, plans: dec.plans}
+}
+
+// fieldPlans caches the fields extracted from each struct type using the
+// same options.
+type fieldPlans struct {
+	options *Options
+	plans   sync.Map // reflect.Type -> structInfo
+}
+
+// fields returns the fields of the given struct value. The fields are
+// extracted once per type, then bound to each value.
+func (p *fieldPlans) fields(r reflectValue) (structInfo, error) {
+	if plan, ok := p.plans.Load(r.t); ok {
+		return plan.(structInfo).bind(r), nil
+	}
+
+	plan, err := extractFields(zeroValue(r.t), p.options)
+	if err != nil {
+		return structInfo{}, err
+	}
+
+	p.plans.Store(r.t, plan)
+	return plan.bind(r), nil
+}
+
+// compile extracts the fields of the given type and of every struct type
+// reachable from its fields, if it is a struct unmarshaled from tags.
+func (p *fieldPlans) compile(t reflect.Type) error {
+	t = derefStructPointer(optionalElem(t))
+
+	switch {
+	case isScalar(t), isUnmarshaler(t), isKVSlice(t):
+		return nil
+	case t.Kind() == reflect.Slice, t.Kind() == reflect.Array, t.Kind() == reflect.Map:
+		return p.compile(t.Elem())
+	case t.Kind() != reflect.Struct:
+		return nil
+	}
+
+	if _, ok := p.plans.Load(t); ok {
+		return nil
+	}
+
+	plan, err := extractFields(zeroValue(t), p.options)
+	if err != nil {
+		return fmt.Errorf("cannot extract fields of %s: %w", t, err)
+	}
+
+	// Stored before descending, so that recursive types terminate.
+	p.plans.Store(t, plan)
+
+	for _, fields := range [][]fieldInfo{plan.blockFields, plan.otherFields} {
+		for _, field := range fields {
+			if err := p.compile(field.field.Type); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// bind returns a copy of the extracted fields whose values are the fields of
+// the given struct value, which must be of the same type.
+func (info structInfo) bind(r reflectValue) structInfo {
+	bound := info
+	bound.blockFields = bindFields(info.blockFields, r)
+	bound.otherFields = bindFields(info.otherFields, r)
+
+	if info.matcher != nil {
+		matcher := bindField(*info.matcher, r)
+		bound.matcher = &matcher
+	}
+
+	if info.remain != nil {
+		remain := bindField(*info.remain, r)
+		bound.remain = &remain
+	}
+
+	if info.extras != nil {
+		extras := bindField(*info.extras, r)
+		bound.extras = &extras
+	}
+
+	bound.present = bindFields(info.present, r)
+
+	if info.primary != nil {
+		for i, field := range bound.blockFields {
+			if field.field.Name == info.primary.field.Name {
+				bound.primary = &bound.blockFields[i]
+				break
+			}
+		}
+	}
+
+	bound.examples = examplesOf(r, info.opts)
+	return bound
+}
+
+func bindFields(fields []fieldInfo, r reflectValue) []fieldInfo {
+	if fields == nil {
+		return nil
+	}
+
+	bound := make([]fieldInfo, len(fields))
+	for i, field := range fields {
+		bound[i] = bindField(field, r)
+	}
+	return bound
+}
+
+func bindField(field fieldInfo, r reflectValue) fieldInfo {
+	field.value = reflectValue{r.v.FieldByIndex(field.field.Index), field.field.Type}
+	field.owner = r
+	return field
+}
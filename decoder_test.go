@@ -0,0 +1,109 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+func TestDecoder(t *testing.T) {
+	dec, err := NewDecoder[benchProxy](Options{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expect benchProxy
+	d := caddyfile.NewTestDispenser(benchProxyInput)
+	d.Next()
+	if err := UnmarshalWithOptions(d, &expect, Options{Strict: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var v benchProxy
+
+		d := caddyfile.NewTestDispenser(benchProxyInput)
+		d.Next()
+
+		if err := dec.Decode(d, &v); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, expect) {
+			t.Errorf("decode %d: expected %+v, got %+v", i, expect, v)
+		}
+	}
+
+	d = caddyfile.NewTestDispenser("reverse_proxy {\n\tupstream {\n\t\tunknown\n\t}\n}")
+	d.Next()
+	if err := dec.Decode(d, new(benchProxy)); !errors.Is(err, ErrUnknownSubdirective) {
+		t.Errorf("expected strict error, got %v", err)
+	}
+}
+
+func TestDecoderPrimary(t *testing.T) {
+	type site struct {
+		_     struct{} `caddyfile:",primary=root"`
+		Root  string   `caddyfile:"root"`
+		Index string   `caddyfile:"index"`
+	}
+
+	dec := MustNewDecoder[site](Options{})
+
+	for _, root := range []string{"/a", "/b"} {
+		var v site
+
+		d := caddyfile.NewTestDispenser("site " + root)
+		d.Next()
+
+		if err := dec.Decode(d, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Root != root {
+			t.Errorf("expected root %s, got %q", root, v.Root)
+		}
+	}
+}
+
+func TestNewDecoderInvalid(t *testing.T) {
+	type inner struct {
+		Arg string `caddyfile:"$x"`
+	}
+	type outer struct {
+		Inner []*inner `caddyfile:"inner"`
+	}
+
+	_, err := NewDecoder[outer](Options{})
+	if err == nil || !strings.Contains(err.Error(), "invalid argument index $x") {
+		t.Errorf("expected invalid nested struct to be reported, got %v", err)
+	}
+
+	if _, err := NewDecoder[recursiveRule](Options{}); err != nil {
+		t.Errorf("unexpected error for recursive type: %v", err)
+	}
+}
+
+func BenchmarkDecoderProxy(b *testing.B) {
+	tokens, err := caddyfile.Tokenize([]byte(benchProxyInput), "Benchfile")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	dec := MustNewDecoder[benchProxy](Options{})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var v benchProxy
+
+		d := caddyfile.NewDispenser(tokens)
+		d.Next()
+
+		if err := dec.Decode(d, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
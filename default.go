@@ -0,0 +1,87 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// Unmarshaler is like caddyfile.Unmarshaler, except the given dispenser also
+// carries the state of the enclosing unmarshaling, i.e. its options, session
+// and HTTP helper, over to UnmarshalDefault. It takes precedence over
+// caddyfile.Unmarshaler, so that a module can implement both.
+type Unmarshaler interface {
+	UnmarshalCaddyfileWith(d *Dispenser) error
+}
+
+var typeUnmarshalerWith = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// Dispenser is the dispenser given to Unmarshaler, along with the state of the
+// unmarshaling that called it.
+type Dispenser struct {
+	*caddyfile.Dispenser
+	state dispenser
+}
+
+// UnmarshalDefault unmarshals v the way it would be if its type did not
+// implement caddyfile.Unmarshaler or Unmarshaler. This lets a custom
+// unmarshaling method do some work around the tag-driven parsing without
+// recursing into itself, e.g.
+//
+//	func (h *Header) UnmarshalCaddyfileWith(d *caddyunmarshal.Dispenser) error {
+//		d.Next() // consume the subdirective name
+//		if err := caddyunmarshal.UnmarshalDefault(d, h); err != nil {
+//			return err
+//		}
+//		h.Name = http.CanonicalHeaderKey(h.Name)
+//		return nil
+//	}
+//
+// Struct, slice and map values are unmarshaled from the rest of the line and
+// its block, so the cursor is expected to be at the subdirective name, just
+// like for Unmarshal. Scalar values are parsed from the current token.
+//
+// If d is the *Dispenser given to UnmarshalCaddyfileWith, then the options,
+// session and HTTP helper of the enclosing unmarshaling carry over. A
+// *caddyfile.Dispenser, such as the one given to UnmarshalCaddyfile, is
+// unmarshaled using the defaults.
+func UnmarshalDefault[T any, D *caddyfile.Dispenser | *Dispenser](d D, v *T) error {
+	var dd dispenser
+	switch d := any(d).(type) {
+	case *caddyfile.Dispenser:
+		dd = dispenser{Dispenser: d}
+	case *Dispenser:
+		dd = d.state
+		dd.Dispenser = d.Dispenser
+	}
+
+	r := reflect.ValueOf(v).Elem()
+	dd.self = r
+
+	if isScalar(r.Type()) {
+		return unmarshalValue(dd, reflectValue{r, r.Type()}, dd.Val(), nil)
+	}
+	return unmarshalLine(dd, reflectValue{r, r.Type()}, nil)
+}
+
+// callUnmarshaler calls the UnmarshalCaddyfileWith or UnmarshalCaddyfile
+// method of r with the given dispenser. The former also gets the state of d.
+func (d dispenser) callUnmarshaler(r reflectValue, inner *caddyfile.Dispenser) error {
+	if unmarshaler, ok := r.v.Addr().Interface().(Unmarshaler); ok {
+		return unmarshaler.UnmarshalCaddyfileWith(&Dispenser{inner, d})
+	}
+	return r.v.Addr().Interface().(caddyfile.Unmarshaler).UnmarshalCaddyfile(inner)
+}
+
+// usesUnmarshaler returns true if r is to be unmarshaled by its own
+// UnmarshalCaddyfile method, which is not the case for the value given to
+// UnmarshalDefault.
+func (d dispenser) usesUnmarshaler(r reflectValue) bool {
+	if !isUnmarshaler(r.t) {
+		return false
+	}
+
+	self := d.self
+	return !self.IsValid() || self.Type() != r.t ||
+		!r.v.CanAddr() || self.Addr().Pointer() != r.v.Addr().Pointer()
+}
@@ -0,0 +1,97 @@
+package caddyunmarshal
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type defaultHeader struct {
+	Name   string   `caddyfile:"$1"`
+	Values []string `caddyfile:"$2,optional"`
+	Secure bool     `caddyfile:"secure"`
+}
+
+func (h *defaultHeader) UnmarshalCaddyfileWith(d *Dispenser) error {
+	d.Next()
+	if err := UnmarshalDefault(d, h); err != nil {
+		return err
+	}
+	h.Name = http.CanonicalHeaderKey(h.Name)
+	return nil
+}
+
+type defaultScheme string
+
+func (s *defaultScheme) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	if err := UnmarshalDefault(d, s); err != nil {
+		return err
+	}
+	*s = defaultScheme(strings.ToLower(string(*s)))
+	return nil
+}
+
+type defaultProxy struct {
+	Scheme  defaultScheme   `caddyfile:"$1"`
+	Headers []defaultHeader `caddyfile:"header"`
+	Timeout string          `caddyfile:"timeout"`
+}
+
+func TestUnmarshalDefault(t *testing.T) {
+	v, err := unmarshalString[defaultProxy]("proxy HTTPS {\n header x-foo a b\n header x-bar\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Scheme != "https" {
+		t.Errorf("unexpected scheme: %q", v.Scheme)
+	}
+
+	expect := []defaultHeader{
+		{"X-Foo", []string{"a", "b"}, false},
+		{"X-Bar", nil, false},
+	}
+	if !reflect.DeepEqual(v.Headers, expect) {
+		t.Errorf("unexpected headers: %+v", v.Headers)
+	}
+}
+
+func TestUnmarshalDefaultOptions(t *testing.T) {
+	_, err := unmarshalStringWithOptions[defaultProxy](
+		"proxy https {\n header x-foo {\n  unknown\n }\n}",
+		Options{Strict: true})
+	if err == nil || !strings.Contains(err.Error(), `unknown subdirective "unknown"`) {
+		t.Errorf("expected strict options to carry over, got %v", err)
+	}
+}
+
+type defaultPlainHeader struct {
+	Name   string `caddyfile:"$1"`
+	Secure bool   `caddyfile:"secure"`
+}
+
+func (h *defaultPlainHeader) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	d.Next()
+	return UnmarshalDefault(d, h)
+}
+
+func TestUnmarshalDefaultPlain(t *testing.T) {
+	type proxy struct {
+		Headers []defaultPlainHeader `caddyfile:"header"`
+	}
+
+	// Options don't carry over to a plain caddyfile.Unmarshaler, so the
+	// unknown subdirective is skipped.
+	v, err := unmarshalStringWithOptions[proxy](
+		"proxy {\n header x-foo {\n  unknown\n }\n}",
+		Options{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Headers) != 1 || v.Headers[0].Name != "x-foo" {
+		t.Errorf("unexpected headers: %+v", v.Headers)
+	}
+}
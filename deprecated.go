@@ -0,0 +1,49 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+	"go.uber.org/zap"
+)
+
+// warnDeprecated records a warning if the field bound to the current token has
+// the deprecated option, e.g. `caddyfile:"old_name,deprecated=use new_name"`.
+// The value is still unmarshaled as usual. Warnings are appended to
+// Options.Warnings if it is set, otherwise they are logged.
+func (d dispenser) warnDeprecated(field fieldInfo) {
+	note, ok := optValue(field.opts, "deprecated")
+	if !ok && !hasOpt(field.opts, "deprecated") {
+		return
+	}
+
+	var what string
+	switch kind := field.kind.(type) {
+	case blockFieldKind:
+		what = fmt.Sprintf("subdirective %s", kind.name)
+	default:
+		what = fmt.Sprintf("argument %s", snakeCase(field.field.Name))
+	}
+
+	msg := what + " is deprecated"
+	if note != "" {
+		msg += ": " + note
+	}
+
+	warning := caddyconfig.Warning{
+		File:      d.File(),
+		Line:      d.Line(),
+		Directive: d.Val(),
+		Message:   msg,
+	}
+
+	if d.options != nil && d.options.Warnings != nil {
+		*d.options.Warnings = append(*d.options.Warnings, warning)
+		return
+	}
+
+	caddy.Log().Named("caddyfile").Warn(warning.Message,
+		zap.String("file", warning.File),
+		zap.Int("line", warning.Line))
+}
@@ -0,0 +1,38 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+)
+
+func TestUnmarshalDeprecated(t *testing.T) {
+	type proxy struct {
+		To       string `caddyfile:"$1,optional,deprecated"`
+		Policy   string `caddyfile:"lb_policy"`
+		OldRetry int    `caddyfile:"retries,deprecated=use lb_retries"`
+		Retries  int    `caddyfile:"lb_retries"`
+	}
+
+	var warnings []caddyconfig.Warning
+
+	v, err := unmarshalStringWithOptions[proxy]("proxy a {\n lb_policy first\n retries 3\n}", Options{
+		Warnings: &warnings,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.OldRetry != 3 {
+		t.Errorf("deprecated field was not set: %+v", v)
+	}
+
+	expect := []caddyconfig.Warning{
+		{File: "Testfile", Line: 1, Directive: "a", Message: "argument to is deprecated"},
+		{File: "Testfile", Line: 3, Directive: "retries", Message: "subdirective retries is deprecated: use lb_retries"},
+	}
+	if !reflect.DeepEqual(warnings, expect) {
+		t.Errorf("unexpected warnings:\ngot  %+v\nwant %+v", warnings, expect)
+	}
+}
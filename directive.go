@@ -0,0 +1,54 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+// RegisterHandlerDirective registers the given directive name as an HTTP
+// handler directive using httpcaddyfile.RegisterHandlerDirective. The generated
+// parse function unmarshals the directive's tokens into a fresh T using
+// UnmarshalForHTTP and returns it.
+//
+// T is usually a pointer to the handler struct, since most handlers implement
+// caddyhttp.MiddlewareHandler on the pointer receiver. Since the directive is
+// registered as a handler directive, httpcaddyfile strips the matcher token
+// before T is unmarshaled, so T must not declare a $matcher field.
+func RegisterHandlerDirective[T caddyhttp.MiddlewareHandler](name string) {
+	httpcaddyfile.RegisterHandlerDirective(name, func(h httpcaddyfile.Helper) (caddyhttp.MiddlewareHandler, error) {
+		v, target := newValue[T]()
+
+		// Consume the directive name, since unmarshal expects it to be
+		// consumed already.
+		h.Next()
+
+		r, err := newReflectValue(target)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := unmarshal(dispenser{Dispenser: h.Dispenser, http: &h}, r); err != nil {
+			return nil, withPath(name, err)
+		}
+
+		return *v, nil
+	})
+}
+
+// newValue allocates a new T. If T is a pointer type, then the pointed-to value
+// is also allocated. The second return value is the struct pointer that should
+// be unmarshaled into.
+func newValue[T any]() (*T, any) {
+	v := new(T)
+
+	rt := reflect.TypeOf(v).Elem()
+	if rt.Kind() == reflect.Pointer {
+		rv := reflect.New(rt.Elem())
+		reflect.ValueOf(v).Elem().Set(rv)
+		return v, rv.Interface()
+	}
+
+	return v, v
+}
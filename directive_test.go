@@ -0,0 +1,86 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+type testHandler struct {
+	Body   string `json:"body" caddyfile:"$1"`
+	Status int    `json:"status,omitempty" caddyfile:"$2,optional"`
+}
+
+func (testHandler) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.caddyunmarshal_test",
+		New: func() caddy.Module { return new(testHandler) },
+	}
+}
+
+func (testHandler) ServeHTTP(http.ResponseWriter, *http.Request, caddyhttp.Handler) error {
+	return nil
+}
+
+func init() {
+	caddy.RegisterModule(testHandler{})
+	RegisterHandlerDirective[*testHandler]("caddyunmarshal_test")
+}
+
+func TestRegisterHandlerDirective(t *testing.T) {
+	const input = `
+		:8080 {
+			route {
+				caddyunmarshal_test /api/* "hello world" 201
+			}
+		}
+	`
+
+	adapter := caddyfile.Adapter{ServerType: httpcaddyfile.ServerType{}}
+	out, _, err := adapter.Adapt([]byte(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var config struct {
+		Apps struct {
+			HTTP struct {
+				Servers map[string]struct {
+					Routes []struct {
+						Handle []struct {
+							Routes []struct {
+								Match  []map[string]json.RawMessage `json:"match"`
+								Handle []json.RawMessage            `json:"handle"`
+							} `json:"routes"`
+						} `json:"handle"`
+					} `json:"routes"`
+				} `json:"servers"`
+			} `json:"http"`
+		} `json:"apps"`
+	}
+	if err := json.Unmarshal(out, &config); err != nil {
+		t.Fatal(err)
+	}
+
+	route := config.Apps.HTTP.Servers["srv0"].Routes[0].Handle[0].Routes[0]
+	if _, ok := route.Match[0]["path"]; !ok {
+		t.Errorf("expected path matcher, got %s", mustJSON(route.Match))
+	}
+
+	var handler struct {
+		testHandler
+		Handler string `json:"handler"`
+	}
+	if err := json.Unmarshal(route.Handle[0], &handler); err != nil {
+		t.Fatal(err)
+	}
+
+	if handler.Handler != "caddyunmarshal_test" || handler.Body != "hello world" || handler.Status != 201 {
+		t.Errorf("unexpected handler: %s", route.Handle[0])
+	}
+}
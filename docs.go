@@ -0,0 +1,133 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Docs renders Markdown documentation of the given directive as described by
+// T, in the style of the Caddy documentation. It consists of the syntax block
+// rendered by Usage, a list of the documented arguments and a table of all
+// subdirectives along with their types, defaults and documentation.
+//
+// Defaults are taken from the default option, e.g. default=5s. The option is
+// only used for documentation, since defaults are usually applied when the
+// module is provisioned.
+func Docs[T any](directive string) (string, error) {
+	return DocsWithOptions[T](directive, Options{})
+}
+
+// DocsWithOptions is like Docs, except the tags are read using the TagKey and
+// FieldName of the given options.
+func DocsWithOptions[T any](directive string, opts Options) (string, error) {
+	usage, err := UsageWithOptions[T](directive, opts)
+	if err != nil {
+		return "", err
+	}
+
+	syntax, err := SyntaxOfWithOptions[T](opts)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("```caddy-d\n")
+	b.WriteString(usage)
+	b.WriteString("```\n")
+
+	var wroteArgs bool
+	for _, arg := range syntax.Arguments {
+		if arg.Doc == "" {
+			continue
+		}
+		if !wroteArgs {
+			b.WriteString("\n")
+			wroteArgs = true
+		}
+		fmt.Fprintf(&b, "- **<%s>** %s\n", arg.Name, arg.Doc)
+	}
+
+	var rows [][4]string
+	docsRows(&rows, "", syntax.Subdirectives)
+
+	if len(rows) > 0 {
+		b.WriteString("\n| Subdirective | Type | Default | Description |\n")
+		b.WriteString("| --- | --- | --- | --- |\n")
+		for _, row := range rows {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", row[0], row[1], row[2], row[3])
+		}
+	}
+
+	return b.String(), nil
+}
+
+// docsRows appends a table row for each of the given subdirectives. Nested
+// subdirectives are named after their full path, e.g. "health interval".
+func docsRows(rows *[][4]string, prefix string, subs []Syntax) {
+	for _, sub := range subs {
+		name := prefix + sub.Name
+
+		desc := sub.Doc
+		if sub.Deprecated != "" {
+			desc = strings.TrimSpace("**Deprecated:** " + sub.Deprecated + ". " + desc)
+		}
+		if sub.Required {
+			desc = strings.TrimSpace("**Required.** " + desc)
+		}
+		if sub.None != "" {
+			desc = strings.TrimSpace(desc + " May be `" + sub.None + "` to clear it.")
+		}
+		if len(sub.Aliases) > 0 {
+			desc = strings.TrimSpace(desc + " Also accepted as `" + strings.Join(sub.Aliases, "`, `") + "`.")
+		}
+
+		var def string
+		if sub.Default != "" {
+			def = "`" + sub.Default + "`"
+		}
+
+		*rows = append(*rows, [4]string{
+			"`" + name + "`",
+			escapeCell(docsType(sub)),
+			escapeCell(def),
+			escapeCell(desc),
+		})
+
+		if sub.Type == "struct" && sub.Ref == "" {
+			docsRows(rows, name+" ", sub.Subdirectives)
+		}
+	}
+}
+
+// docsType returns the type column of the given subdirective.
+func docsType(sub Syntax) string {
+	typ := sub.Type
+	switch {
+	case len(sub.Enum) > 0:
+		typ = strings.Join(sub.Enum, " | ")
+	case sub.Type == "map" || sub.Type == "kv":
+		typ = fmt.Sprintf("%s of %s to %s", sub.Type, sub.Key.Type, sub.Value.Type)
+	case sub.Type == "keyed":
+		typ = fmt.Sprintf("%s keyed by %s, repeatable", sub.Value.Type, sub.Key.Type)
+	case sub.Type == "variant":
+		names := make([]string, 0, len(sub.Variants))
+		for name := range sub.Variants {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		typ = "one of " + strings.Join(names, " | ")
+	}
+
+	if sub.Repeated {
+		typ += ", repeatable"
+	}
+
+	return typ
+}
+
+var cellEscaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+func escapeCell(s string) string {
+	return cellEscaper.Replace(s)
+}
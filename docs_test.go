@@ -0,0 +1,53 @@
+package caddyunmarshal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDocs(t *testing.T) {
+	type health struct {
+		Interval time.Duration `caddyfile:",default=30s,doc='time between checks'"`
+	}
+
+	type proxy struct {
+		To     string            `caddyfile:"$1,doc='the upstream address'"`
+		Policy string            `caddyfile:"lb_policy,enum=first|random,default=random"`
+		Health health            `caddyfile:"health"`
+		Header map[string]string `caddyfile:"header"`
+		Hide   []string          `caddyfile:"hide,deprecated=use hidden"`
+	}
+
+	docs, err := Docs[proxy]("proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "```caddy-d\n" +
+		`# <to>: the upstream address
+proxy <to> {
+	lb_policy <first|random>
+	health {
+		# time between checks
+		interval <duration>
+	}
+	header {
+		<string> <string>
+	}
+	hide <string>
+}
+` + "```\n" + `
+- **<to>** the upstream address
+
+| Subdirective | Type | Default | Description |
+| --- | --- | --- | --- |
+| ` + "`lb_policy`" + ` | first \| random | ` + "`random`" + ` |  |
+| ` + "`health`" + ` | struct |  |  |
+| ` + "`health interval`" + ` | duration | ` + "`30s`" + ` | time between checks |
+| ` + "`header`" + ` | map of string to string |  |  |
+| ` + "`hide`" + ` | string, repeatable |  | **Deprecated:** use hidden. |
+`
+	if docs != expect {
+		t.Errorf("unexpected docs:\n%s\nwant:\n%s", docs, expect)
+	}
+}
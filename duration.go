@@ -0,0 +1,127 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+// durationUnits maps spelled out duration units to the ones accepted by
+// time.ParseDuration.
+var durationUnits = map[string]string{
+	"ns": "ns", "us": "us", "µs": "µs", "ms": "ms", "s": "s", "m": "m", "h": "h", "d": "d",
+
+	"msec": "ms", "msecs": "ms", "millisecond": "ms", "milliseconds": "ms",
+	"sec": "s", "secs": "s", "second": "s", "seconds": "s",
+	"min": "m", "mins": "m", "minute": "m", "minutes": "m",
+	"hr": "h", "hrs": "h", "hour": "h", "hours": "h",
+	"day": "d", "days": "d",
+}
+
+var (
+	durationWordRe  = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zA-Zµ]*)$`)
+	durationClockRe = regexp.MustCompile(`^(\d+):(\d{1,2})(?::(\d{1,2}))?$`)
+)
+
+// durationError wraps the error of a duration that failed to parse. If the
+// value looks like a common but unsupported way of writing durations (e.g.
+// "1:30" or "90 sec"), then a hint showing the accepted syntax is added. next
+// is the argument following the value, if any, since "90 sec" is two tokens.
+// If days is false, then days are not accepted, which is the case for
+// time.Duration.
+func durationError(err error, raw, next string, days bool) error {
+	hint := durationHint(raw, next, days)
+	if hint == "" {
+		return fmt.Errorf("cannot parse duration: %w", err)
+	}
+	return fmt.Errorf(
+		"cannot parse duration: %w; did you mean %s? "+
+			"durations are written like 1h30m, 90s or 500ms", err, hint)
+}
+
+func durationHint(raw, next string, days bool) string {
+	if m := durationClockRe.FindStringSubmatch(raw); m != nil {
+		if m[3] != "" {
+			return strconv.Quote(m[1] + "h" + m[2] + "m" + m[3] + "s")
+		}
+		// Without seconds, it's ambiguous whether this is h:m or m:s.
+		return fmt.Sprintf("%q or %q", m[1]+"h"+m[2]+"m", m[1]+"m"+m[2]+"s")
+	}
+
+	m := durationWordRe.FindStringSubmatch(raw)
+	if m == nil {
+		return ""
+	}
+
+	num, unit := m[1], m[2]
+	if unit == "" {
+		unit = next
+	}
+
+	canonical, ok := durationUnits[strings.ToLower(unit)]
+	switch {
+	case unit == "":
+		// A bare number is most likely meant to be seconds.
+		return strconv.Quote(num + "s")
+	case !ok:
+		return ""
+	case canonical == "d" && !days:
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return ""
+		}
+		return strconv.Quote(strconv.FormatFloat(n*24, 'f', -1, 64) + "h")
+	default:
+		return strconv.Quote(num + canonical)
+	}
+}
+
+// checkDurationRange checks the parsed duration against the min and max
+// options, e.g. `caddyfile:"timeout,min=1s,max=24h"`. Both bounds are
+// inclusive and may use days.
+func checkDurationRange(dura time.Duration, opts []string) error {
+	lo, hasMin := optValue(opts, "min")
+	hi, hasMax := optValue(opts, "max")
+	if !hasMin && !hasMax {
+		return nil
+	}
+
+	var minDura, maxDura time.Duration
+	var err error
+
+	if hasMin {
+		if minDura, err = caddy.ParseDuration(lo); err != nil {
+			return fmt.Errorf("invalid min option %q: %w", lo, err)
+		}
+	}
+	if hasMax {
+		if maxDura, err = caddy.ParseDuration(hi); err != nil {
+			return fmt.Errorf("invalid max option %q: %w", hi, err)
+		}
+	}
+
+	switch {
+	case hasMin && hasMax && (dura < minDura || dura > maxDura):
+		return fmt.Errorf("duration %s is out of range: must be between %s and %s", dura, lo, hi)
+	case hasMin && dura < minDura:
+		return fmt.Errorf("duration %s is out of range: must be at least %s", dura, lo)
+	case hasMax && dura > maxDura:
+		return fmt.Errorf("duration %s is out of range: must be at most %s", dura, hi)
+	}
+
+	return nil
+}
+
+// peekArg returns the argument following the current token without consuming
+// it.
+func (d dispenser) peekArg() string {
+	if !d.NextArg() {
+		return ""
+	}
+	defer d.Prev()
+	return d.Val()
+}
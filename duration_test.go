@@ -0,0 +1,101 @@
+package caddyunmarshal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+func TestDurationHint(t *testing.T) {
+	tests := []struct {
+		raw    string
+		next   string
+		days   bool
+		expect string
+	}{
+		{"1:30", "", true, `"1h30m" or "1m30s"`},
+		{"1:30:15", "", true, `"1h30m15s"`},
+		{"90", "sec", true, `"90s"`},
+		{"90", "", true, `"90s"`},
+		{"90sec", "", true, `"90s"`},
+		{"5 Minutes", "", true, `"5m"`},
+		{"2hrs", "", true, `"2h"`},
+		{"1day", "", true, `"1d"`},
+		{"1.5", "days", false, `"36h"`},
+		{"5", "apples", true, ""},
+		{"abc", "", true, ""},
+	}
+
+	for _, test := range tests {
+		if hint := durationHint(test.raw, test.next, test.days); hint != test.expect {
+			t.Errorf("durationHint(%q, %q) = %s, want %s", test.raw, test.next, hint, test.expect)
+		}
+	}
+}
+
+func TestUnmarshalDurationHint(t *testing.T) {
+	type timeouts struct {
+		Read  caddy.Duration `caddyfile:"read"`
+		Write time.Duration  `caddyfile:"write"`
+	}
+
+	v, err := unmarshalString[timeouts]("timeouts {\n read 1d\n write 1m30s\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Read != caddy.Duration(24*time.Hour) || v.Write != 90*time.Second {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	_, err = unmarshalString[timeouts]("timeouts {\n read 90 sec\n}")
+	if err == nil || !strings.Contains(err.Error(), `did you mean "90s"?`) {
+		t.Errorf("expected hint, got %v", err)
+	}
+
+	_, err = unmarshalString[timeouts]("timeouts {\n write 2days\n}")
+	if err == nil || !strings.Contains(err.Error(), `did you mean "48h"?`) {
+		t.Errorf("expected hint, got %v", err)
+	}
+
+	_, err = unmarshalString[timeouts]("timeouts {\n write soon\n}")
+	if err == nil || strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("expected error without hint, got %v", err)
+	}
+}
+
+func TestUnmarshalDurationRange(t *testing.T) {
+	type target struct {
+		Timeout  caddy.Duration `caddyfile:"timeout,min=1s,max=1d"`
+		Interval time.Duration  `caddyfile:"interval,min=100ms"`
+		Grace    time.Duration  `caddyfile:"grace,max=1m"`
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"in range", "target {\n timeout 1s\n interval 1h\n grace 1m\n}", ""},
+		{"below range", "target {\n timeout 500ms\n}", "duration 500ms is out of range: must be between 1s and 1d"},
+		{"above range", "target {\n timeout 2d\n}", "duration 48h0m0s is out of range: must be between 1s and 1d"},
+		{"below min", "target {\n interval 10ms\n}", "duration 10ms is out of range: must be at least 100ms"},
+		{"above max", "target {\n grace 90s\n}", "duration 1m30s is out of range: must be at most 1m"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := unmarshalString[target](test.input)
+			if test.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.HasSuffix(err.Error(), "Testfile:2 - Error during parsing: "+test.err) {
+				t.Errorf("unexpected error:\ngot  %v\nwant %s", err, test.err)
+			}
+		})
+	}
+}
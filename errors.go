@@ -0,0 +1,170 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Errors that unmarshaling fails with, which may be checked using errors.Is.
+// They are wrapped along with the details and the position of the failure,
+// e.g. ErrUnknownSubdirective is returned as
+//
+//	Caddyfile:3 - Error during parsing: unknown subdirective "foo"
+var (
+	// ErrMissingRequired is returned for a missing positional argument or a
+	// missing subdirective with the required option.
+	ErrMissingRequired = errors.New("missing required")
+	// ErrUnexpectedArgument is returned for arguments past the ones that a
+	// directive or subdirective takes.
+	ErrUnexpectedArgument = errors.New("unexpected argument")
+	// ErrUnexpectedBlock is returned for a block that a directive doesn't
+	// take.
+	ErrUnexpectedBlock = errors.New("unexpected block")
+	// ErrUnknownSubdirective is returned for unknown subdirectives when
+	// Options.Strict is set.
+	ErrUnknownSubdirective = errors.New("unknown subdirective")
+	// ErrUnsupportedType is returned for values whose Go type cannot be
+	// unmarshaled or marshaled.
+	ErrUnsupportedType = errors.New("unsupported type")
+	// ErrUnresolvedImportArg is returned for a value other than a string
+	// that still has the placeholder of an import argument, e.g. {args[0]},
+	// see Options.ImportArgs.
+	ErrUnresolvedImportArg = errors.New("unresolved import argument")
+)
+
+// PathError is returned by the Unmarshal functions. It gives the directive
+// and the path to the argument or subdirective that failed, e.g.
+//
+//	reverse_proxy > health_checks > interval: Caddyfile:4 - Error during parsing: ...
+//
+// where positional arguments and blocks are given by their index, e.g. [1].
+type PathError struct {
+	// Directive is the name of the directive, or of the global option.
+	Directive string
+	// Path is the path from the directive to the failing field, which is
+	// empty if the error is about the directive itself.
+	Path []string
+	// Err is the error that occurred.
+	Err error
+
+	msg string // message of Err without the path
+}
+
+func (err *PathError) Error() string {
+	path := append([]string{err.Directive}, err.Path...)
+	return strings.Join(path, " > ") + ": " + err.msg
+}
+
+func (err *PathError) Unwrap() error {
+	return err.Err
+}
+
+// pathError wraps an error with an element of the path to the failing field.
+// The elements are collected into a PathError at the top level.
+type pathError struct {
+	elem string // element of the path, e.g. interval or [1], if any
+	text string // element as quoted in the message, e.g. "interval" or [1]
+	err  error
+}
+
+// errorAtIndex wraps err with the position of an argument, block or element.
+func errorAtIndex(i int, err error) error {
+	elem := "[" + strconv.Itoa(i) + "]"
+	return &pathError{elem, elem, err}
+}
+
+// errorInBlock wraps err with the position of the block of subdirectives of
+// the directive itself, which is left out of the path.
+func errorInBlock(i int, err error) error {
+	return &pathError{"", "[" + strconv.Itoa(i) + "]", err}
+}
+
+// errorAtName wraps err with the name of a subdirective or option.
+func errorAtName(name string, err error) error {
+	return &pathError{name, strconv.Quote(name), err}
+}
+
+func (err *pathError) Error() string {
+	return "error at " + err.text + ": " + err.err.Error()
+}
+
+func (err *pathError) Unwrap() error {
+	return err.err
+}
+
+// withPath wraps err into a PathError for the given directive. The path is
+// taken from the leading elements of the message, which are stripped, so that
+// they're only given once.
+func withPath(directive string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	pathErr := &PathError{Directive: directive, Err: err, msg: err.Error()}
+
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		p, ok := e.(*pathError)
+		if !ok {
+			continue
+		}
+
+		prefix := "error at " + p.text + ": "
+		if !strings.HasPrefix(pathErr.msg, prefix) {
+			break
+		}
+
+		if p.elem != "" {
+			pathErr.Path = append(pathErr.Path, p.elem)
+		}
+		pathErr.msg = pathErr.msg[len(prefix):]
+	}
+
+	return pathErr
+}
+
+// joinedErrors is like the error returned by errors.Join, which isn't
+// available in Go 1.18: errors.Is and errors.As match any of the errors.
+type joinedErrors []error
+
+// joinErrors joins the given non-nil errors into one. It returns nil if there
+// are none, and the error itself if there is only one.
+func joinErrors(errs ...error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return joinedErrors(errs)
+}
+
+func (errs joinedErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (errs joinedErrors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (errs joinedErrors) As(target any) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (errs joinedErrors) Unwrap() []error {
+	return errs
+}
@@ -0,0 +1,137 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalSentinelErrors(t *testing.T) {
+	type server struct {
+		Host    string         `caddyfile:"$1"`
+		Port    int            `caddyfile:"port"`
+		Root    string         `caddyfile:"root,required"`
+		Handler chan struct{}  `caddyfile:"handler"`
+		Tags    map[string]int `caddyfile:"tags"`
+	}
+
+	tests := []struct {
+		input  string
+		expect error
+	}{
+		{"server", ErrMissingRequired},
+		{"server a {\n port 80\n}", ErrMissingRequired},
+		{"server a b", ErrUnexpectedArgument},
+		{"server a {\n root / x\n}", ErrUnexpectedArgument},
+		{"server a {\n root /\n tags x\n}", ErrUnexpectedArgument},
+		{"server a {\n root /\n unknown\n}", ErrUnknownSubdirective},
+		{"server a {\n root /\n handler x\n}", ErrUnsupportedType},
+	}
+
+	for _, test := range tests {
+		_, err := unmarshalStringWithOptions[server](test.input, Options{Strict: true})
+		if !errors.Is(err, test.expect) {
+			t.Errorf("%q: expected %v, got %v", test.input, test.expect, err)
+		}
+	}
+
+	type flag struct {
+		Name string `caddyfile:"$1"`
+	}
+
+	_, err := unmarshalString[flag]("flag a {\n x\n}")
+	if !errors.Is(err, ErrUnexpectedBlock) {
+		t.Errorf("expected %v, got %v", ErrUnexpectedBlock, err)
+	}
+}
+
+func TestUnmarshalPathError(t *testing.T) {
+	type healthChecks struct {
+		Interval time.Duration `caddyfile:"interval"`
+	}
+	type proxy struct {
+		To       string       `caddyfile:"$1"`
+		Health   healthChecks `caddyfile:"health_checks"`
+		Password string       `caddyfile:"password,secret"`
+		Timeout  int          `caddyfile:"timeout"`
+	}
+
+	tests := []struct {
+		input  string
+		path   []string
+		expect string
+	}{
+		{
+			"proxy a {\n health_checks {\n  interval 5x\n }\n}",
+			[]string{"health_checks", "interval"},
+			`proxy > health_checks > interval: Testfile:3 - Error during parsing: cannot parse duration: `,
+		},
+		{
+			"proxy a {\n password hunter2 hunter2\n}",
+			[]string{"password"},
+			`proxy > password: Testfile:2 - Error during parsing: unexpected argument at "password": REDACTED`,
+		},
+		{
+			"proxy",
+			nil,
+			`proxy: Testfile:1 - Error during parsing: missing required field [0]`,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := unmarshalString[proxy](test.input)
+
+		var pathErr *PathError
+		if !errors.As(err, &pathErr) {
+			t.Errorf("%q: expected PathError, got %v", test.input, err)
+			continue
+		}
+
+		if pathErr.Directive != "proxy" || !reflect.DeepEqual(pathErr.Path, test.path) {
+			t.Errorf("%q: unexpected path %q > %q", test.input, pathErr.Directive, pathErr.Path)
+		}
+		if !strings.HasPrefix(err.Error(), test.expect) {
+			t.Errorf("%q: unexpected error:\ngot  %v\nwant %s", test.input, err, test.expect)
+		}
+	}
+
+	_, err := unmarshalString[proxy]("proxy a {\n timeout soon\n}")
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("expected the underlying error to be kept, got %v", err)
+	}
+}
+
+func TestUnmarshalPathErrorElements(t *testing.T) {
+	type balancer struct {
+		Policy testPolicy `caddyfile:"policy"`
+		Ports  []int      `caddyfile:"ports,split=,"`
+	}
+
+	tests := []struct {
+		input string
+		path  []string
+	}{
+		{"lb {\n policy weighted {\n  weight x\n }\n}", []string{"policy", "weighted", "weight", "[0]"}},
+		{"lb {\n ports 1,x\n}", []string{"ports", "[0]", "[1]"}},
+	}
+
+	for _, test := range tests {
+		_, err := unmarshalString[balancer](test.input)
+
+		var pathErr *PathError
+		if !errors.As(err, &pathErr) {
+			t.Errorf("%q: expected PathError, got %v", test.input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(pathErr.Path, test.path) {
+			t.Errorf("%q: unexpected path %q", test.input, pathErr.Path)
+		}
+		if strings.Contains(err.Error(), "error at") {
+			t.Errorf("%q: path left in the message: %v", test.input, err)
+		}
+	}
+}
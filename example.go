@@ -0,0 +1,35 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Exampler is implemented by structs that provide canonical example lines of
+// their syntax, e.g. "mydir <host> [<port>]". The examples are appended to
+// errors about wrong arguments or unknown subdirectives. Examples can also be
+// given using the example option on the struct-level _ field, e.g.
+//
+//	_ struct{} `caddyfile:",example='mydir <host> [<port>]'"`
+//
+// The option may be repeated, but one or two examples are usually enough.
+type Exampler interface {
+	CaddyfileExamples() []string
+}
+
+// examplesOf returns the examples of the struct value r.
+func examplesOf(r reflectValue, opts []string) []string {
+	examples := optValues(opts, "example")
+	if exampler, ok := r.v.Addr().Interface().(Exampler); ok {
+		examples = append(examples, exampler.CaddyfileExamples()...)
+	}
+	return examples
+}
+
+// withExamples appends the examples of the struct to the given error.
+func (info structInfo) withExamples(err error) error {
+	if len(info.examples) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w\nexpected: %s", err, strings.Join(info.examples, "\nexpected: "))
+}
@@ -0,0 +1,47 @@
+package caddyunmarshal
+
+import (
+	"strings"
+	"testing"
+)
+
+type exampledUpstream struct {
+	_    struct{} `caddyfile:",example='upstream <host> [<port>]'"`
+	Host string   `caddyfile:"$1"`
+	Port int      `caddyfile:"$2,optional"`
+}
+
+type exampledProxy struct {
+	To string `caddyfile:"$1"`
+}
+
+func (exampledProxy) CaddyfileExamples() []string {
+	return []string{"proxy <to>", "proxy localhost:8080"}
+}
+
+func TestUnmarshalExamples(t *testing.T) {
+	_, err := unmarshalString[exampledUpstream]("upstream a 80 extra")
+	if err == nil || !strings.HasSuffix(err.Error(), "unexpected argument at [2]: extra\nexpected: upstream <host> [<port>]") {
+		t.Errorf("expected error with example, got %v", err)
+	}
+
+	_, err = unmarshalString[exampledProxy]("proxy")
+	if err == nil || !strings.HasSuffix(err.Error(), "\nexpected: proxy <to>\nexpected: proxy localhost:8080") {
+		t.Errorf("expected error with examples, got %v", err)
+	}
+
+	_, err = unmarshalString[thing2]("thing2")
+	if err == nil || strings.Contains(err.Error(), "expected:") {
+		t.Errorf("expected error without examples, got %v", err)
+	}
+}
+
+func TestSyntaxOfExamples(t *testing.T) {
+	syntax, err := SyntaxOf[exampledUpstream]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(syntax.Examples) != 1 || syntax.Examples[0] != "upstream <host> [<port>]" {
+		t.Errorf("unexpected examples: %q", syntax.Examples)
+	}
+}
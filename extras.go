@@ -0,0 +1,46 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+var typeExtras = reflect.TypeOf([]caddyfile.Segment(nil))
+
+// unmarshalExtras appends the segment of an unknown subdirective, including
+// its block, to the extras field, e.g.
+//
+//	Extras []caddyfile.Segment `caddyfile:",extras"`
+//
+// This is the middle ground between skipping unknown subdirectives and
+// rejecting them with Options.Strict: the module can warn about them, or
+// process them later using caddyfile.NewDispenser.
+func unmarshalExtras(d dispenser, r reflectValue) error {
+	seg := d.NextSegment()
+	r.v.Set(reflect.Append(r.v, reflect.ValueOf(seg)))
+	return nil
+}
+
+// extras emits the segments kept by the extras field as they were given.
+func (e *encoder) extras(info structInfo) {
+	if info.extras == nil {
+		return
+	}
+
+	for _, seg := range info.extras.value.v.Interface().([]caddyfile.Segment) {
+		e.custom(seg)
+		e.newline()
+	}
+}
+
+// hasRemaining returns true if the remain or extras field of the struct holds
+// any subdirectives.
+func hasRemaining(info structInfo) bool {
+	for _, field := range []*fieldInfo{info.remain, info.extras} {
+		if field != nil && field.value.v.Len() > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package caddyunmarshal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type extrasClient struct {
+	Addr    string              `caddyfile:"$1"`
+	Timeout string              `caddyfile:"timeout"`
+	Extras  []caddyfile.Segment `caddyfile:",extras"`
+}
+
+func TestUnmarshalExtras(t *testing.T) {
+	const input = "client localhost {\n" +
+		"\tpool_size 10\n" +
+		"\ttimeout 5s\n" +
+		"\tpool {\n" +
+		"\t\tidle 2\n" +
+		"\t}\n" +
+		"}"
+
+	v, err := unmarshalStringWithOptions[extrasClient](input, Options{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Addr != "localhost" || v.Timeout != "5s" || len(v.Extras) != 2 {
+		t.Fatalf("unexpected value %+v", v)
+	}
+
+	if name := v.Extras[0].Directive(); name != "pool_size" {
+		t.Errorf("expected first extra to be pool_size, got %q", name)
+	}
+
+	d := caddyfile.NewDispenser(v.Extras[1])
+	d.Next()
+	if !d.NextBlock(0) || d.Val() != "idle" || !d.NextArg() || d.Val() != "2" {
+		t.Errorf("unexpected second extra %v", v.Extras[1])
+	}
+
+	dec := MustNewDecoder[extrasClient](Options{})
+	for i := 0; i < 2; i++ {
+		var v extrasClient
+
+		d := caddyfile.NewTestDispenser(input)
+		d.Next()
+
+		if err := dec.Decode(d, &v); err != nil {
+			t.Fatal(err)
+		}
+		if len(v.Extras) != 2 {
+			t.Errorf("decode %d: expected 2 extras, got %d", i, len(v.Extras))
+		}
+	}
+
+	out, err := Marshal("client", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "client localhost {\n" +
+		"\ttimeout 5s\n" +
+		"\tpool_size 10\n" +
+		"\tpool {\n" +
+		"\t\tidle 2\n" +
+		"\t}\n" +
+		"}\n"
+	if string(out) != expect {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out, expect)
+	}
+}
+
+func TestUnmarshalExtrasInvalid(t *testing.T) {
+	type exclusive struct {
+		Options map[string][]string `caddyfile:",remain"`
+		Extras  []caddyfile.Segment `caddyfile:",extras"`
+	}
+	_, err := unmarshalString[exclusive]("client")
+	if err == nil || !strings.Contains(err.Error(), "are exclusive") {
+		t.Errorf("expected exclusive error, got %v", err)
+	}
+
+	type wrongType struct {
+		Extras []caddyfile.Token `caddyfile:",extras"`
+	}
+	_, err = unmarshalString[wrongType]("client")
+	if err == nil || !strings.Contains(err.Error(), "must be a []caddyfile.Segment") {
+		t.Errorf("expected invalid extras field error, got %v", err)
+	}
+}
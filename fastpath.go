@@ -0,0 +1,63 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// unmarshalFastValue unmarshals raw into r without going through the checks
+// for special types in unmarshalValue, if r is of a predeclared string, int or
+// bool type and has no options that affect parsing. It returns false if the
+// slow path must be taken instead.
+//
+// Predeclared types can't have methods, so only a registered value parser or
+// weak typing may change how they're parsed.
+func unmarshalFastValue(d dispenser, r reflectValue, raw string, opts []string) (bool, error) {
+	if len(opts) > 0 || r.t.PkgPath() != "" || r.t.Name() == "" || d.options.weaklyTyped() {
+		return false, nil
+	}
+
+	// Values with placeholders may need them checked, see checkImportArgs.
+	if strings.IndexByte(raw, '{') >= 0 {
+		return false, nil
+	}
+
+	switch r.t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	default:
+		return false, nil
+	}
+
+	if hasValueParser(r.t) {
+		return false, nil
+	}
+
+	if err := d.checkLength(raw, nil); err != nil {
+		return true, d.WrapErr(err)
+	}
+
+	raw = d.options.replace(raw)
+
+	switch r.t.Kind() {
+	case reflect.String:
+		r.v.SetString(raw)
+
+	case reflect.Bool:
+		v, err := parseBool(raw, nil)
+		if err != nil {
+			return true, d.WrapErr(fmt.Errorf("cannot parse boolean value %q: %w", raw, err))
+		}
+		r.v.SetBool(v)
+
+	default:
+		i, err := parseInt(raw, r.t.Bits())
+		if err != nil {
+			return true, d.WrapErr(fmt.Errorf("cannot parse int: %w", err))
+		}
+		r.v.SetInt(i)
+	}
+
+	return true, nil
+}
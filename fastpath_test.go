@@ -0,0 +1,133 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type fastValues struct {
+	Name    string `caddyfile:"$1"`
+	Count   int    `caddyfile:"count"`
+	Small   int8   `caddyfile:"small"`
+	Enabled bool   `caddyfile:"enabled"`
+}
+
+// slowString and the like aren't predeclared, so they take the slow path.
+type (
+	slowString string
+	slowInt    int
+	slowInt8   int8
+	slowBool   bool
+)
+
+type slowValues struct {
+	Name    slowString `caddyfile:"$1"`
+	Count   slowInt    `caddyfile:"count"`
+	Small   slowInt8   `caddyfile:"small"`
+	Enabled slowBool   `caddyfile:"enabled"`
+}
+
+func TestUnmarshalFastValue(t *testing.T) {
+	tests := []string{
+		"values a {\n count 42\n small -8\n enabled off\n}",
+		"values a {\n count x\n}",
+		"values a {\n small 300\n}",
+		"values a {\n enabled maybe\n}",
+		"values " + strings.Repeat("a", MaxTokenLength+1),
+	}
+
+	for _, test := range tests {
+		fast, fastErr := unmarshalString[fastValues](test)
+		slow, slowErr := unmarshalString[slowValues](test)
+
+		if fmt.Sprint(fastErr) != fmt.Sprint(slowErr) {
+			t.Errorf("%q: fast path error %v differs from %v", test, fastErr, slowErr)
+		}
+		if fast != (fastValues{string(slow.Name), int(slow.Count), int8(slow.Small), bool(slow.Enabled)}) {
+			t.Errorf("%q: fast path value %+v differs from %+v", test, fast, slow)
+		}
+	}
+}
+
+type benchUpstream struct {
+	To            []string          `caddyfile:"to"`
+	Policy        string            `caddyfile:"lb_policy"`
+	Retries       int               `caddyfile:"lb_retries"`
+	TryDuration   caddy.Duration    `caddyfile:"lb_try_duration"`
+	HealthURI     string            `caddyfile:"health_uri"`
+	HealthPort    int               `caddyfile:"health_port"`
+	FlushInterval time.Duration     `caddyfile:"flush_interval"`
+	Buffer        bool              `caddyfile:"buffer_requests"`
+	HeaderUp      map[string]string `caddyfile:"header_up"`
+}
+
+type benchProxy struct {
+	Upstreams []string        `caddyfile:"$1,optional"`
+	Upstream  []benchUpstream `caddyfile:"upstream"`
+}
+
+const benchProxyInput = `reverse_proxy a:80 b:80 {
+	upstream {
+		to c:80 d:80
+		lb_policy round_robin
+		lb_retries 3
+		lb_try_duration 5s
+		health_uri /health
+		health_port 8080
+		flush_interval 100ms
+		buffer_requests
+		header_up {
+			Host upstream
+			X-Real-IP {remote_host}
+		}
+	}
+}`
+
+func benchmarkUnmarshal[T any](b *testing.B, input string) {
+	tokens, err := caddyfile.Tokenize([]byte(input), "Benchfile")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var v T
+
+		d := caddyfile.NewDispenser(tokens)
+		d.Next()
+
+		if err := Unmarshal(d, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalPrimitives(b *testing.B) {
+	benchmarkUnmarshal[fastValues](b, "values a {\n count 42\n small -8\n enabled\n}")
+}
+
+func BenchmarkUnmarshalNamedPrimitives(b *testing.B) {
+	benchmarkUnmarshal[slowValues](b, "values a {\n count 42\n small -8\n enabled\n}")
+}
+
+func BenchmarkUnmarshalProxy(b *testing.B) {
+	benchmarkUnmarshal[benchProxy](b, benchProxyInput)
+}
+
+func BenchmarkUnmarshalRepeated(b *testing.B) {
+	var input strings.Builder
+	input.WriteString("proxy {\n")
+	for i := 0; i < 100; i++ {
+		input.WriteString("\tupstream {\n\t\tto a:80\n\t\tlb_retries 3\n\t\tbuffer_requests\n\t}\n")
+	}
+	input.WriteString("}")
+
+	benchmarkUnmarshal[benchProxy](b, input.String())
+}
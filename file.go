@@ -0,0 +1,33 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// filePrefix is the prefix of values that are read from a file.
+const filePrefix = "file:"
+
+// readFileValue reads the value from the file named by raw if it starts with
+// "file:", which is only allowed using the allowfile option, e.g.
+// `caddyfile:"secret,allowfile"` takes "file:/etc/secret.key". Trailing line
+// breaks are trimmed from the contents, except for []byte values without an
+// encoding, which are read as is. Other values are returned unchanged.
+func readFileValue(raw string, t reflect.Type, opts []string) (string, error) {
+	if !strings.HasPrefix(raw, filePrefix) {
+		return raw, nil
+	}
+
+	b, err := os.ReadFile(strings.TrimPrefix(raw, filePrefix))
+	if err != nil {
+		return "", fmt.Errorf("cannot read value from file: %w", err)
+	}
+
+	if _, encoded := optValue(opts, "encoding"); t.AssignableTo(TypeBytes) && !encoded {
+		return string(b), nil
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,64 @@
+package caddyunmarshal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalFileValue(t *testing.T) {
+	type target struct {
+		Secret string  `caddyfile:"secret,allowfile"`
+		Blob   []byte  `caddyfile:"blob,allowfile"`
+		Key    [2]byte `caddyfile:"key,allowfile,encoding=hex"`
+		Port   int     `caddyfile:"port,allowfile"`
+		Plain  string  `caddyfile:"plain"`
+	}
+
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	secret := write("secret", "hunter2\n")
+	blob := write("blob", "binary\n")
+	key := write("key", "beef\r\n")
+	port := write("port", "8080")
+
+	v, err := unmarshalString[target](`
+		target {
+			secret file:` + secret + `
+			blob file:` + blob + `
+			key file:` + key + `
+			port file:` + port + `
+			plain file:/not/read
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := target{
+		Secret: "hunter2",
+		Blob:   []byte("binary\n"),
+		Key:    [2]byte{0xbe, 0xef},
+		Port:   8080,
+		Plain:  "file:/not/read",
+	}
+	if v.Secret != expect.Secret || string(v.Blob) != string(expect.Blob) ||
+		v.Key != expect.Key || v.Port != expect.Port || v.Plain != expect.Plain {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+
+	missing := filepath.Join(dir, "missing")
+	_, err = unmarshalString[target]("target {\n secret file:" + missing + "\n}")
+	expectErr := "Testfile:2 - Error during parsing: cannot read value from file: open " + missing
+	if err == nil || !strings.Contains(err.Error(), expectErr) {
+		t.Errorf("unexpected error:\ngot  %v\nwant %s", err, expectErr)
+	}
+}
@@ -0,0 +1,100 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// isFlags returns true if the struct takes CLI-style flags among its
+// arguments, given using the struct-level flags option, e.g.
+//
+//	type Exec struct {
+//		_       struct{} `caddyfile:",flags"`
+//		Command string   `caddyfile:"$1"`
+//		Args    []string `caddyfile:"$2,optional"`
+//		Dir     string   `caddyfile:"dir"`
+//		Env     []string `caddyfile:"env"`
+//		Quiet   bool     `caddyfile:"quiet"`
+//	}
+//
+// takes "exec --dir /srv --quiet make build". An argument like --name or
+// --name=value sets the subdirective field of that name, wherever it is on
+// the line, and doesn't take up a position. Dashes in the name may also be
+// given for underscores, e.g. --max-conns for max_conns. Bool fields take no
+// value unless given like --quiet=false, and the other fields take the next
+// argument. Quoted arguments are positional as usual, and so are the arguments
+// following a bare --, even if they start with --. The fields may also be
+// given as subdirectives within the block.
+func isFlags(info structInfo) bool {
+	return hasOpt(info.opts, "flags")
+}
+
+// isEndOfFlags returns true if the current argument is a bare --, which ends
+// the flags of the line.
+func (info structInfo) isEndOfFlags(d dispenser) bool {
+	return isFlags(info) && d.Val() == "--" && !d.Token().Quoted()
+}
+
+// withoutFlags returns a copy of info that takes no flags, for the arguments
+// following a bare --.
+func (info structInfo) withoutFlags() structInfo {
+	opts := make([]string, 0, len(info.opts))
+	for _, opt := range info.opts {
+		if opt != "flags" {
+			opts = append(opts, opt)
+		}
+	}
+	info.opts = opts
+	return info
+}
+
+// isNamedArg returns true if the current argument is a flag or a key=value
+// argument, which doesn't take up a position.
+func (info structInfo) isNamedArg(d dispenser) bool {
+	if _, _, ok := info.keyValueField(d); ok {
+		return true
+	}
+	return isFlags(info) && !d.Token().Quoted() && strings.HasPrefix(d.Val(), "--")
+}
+
+// unmarshalFlag unmarshals the flag at the cursor and its value, if any. It
+// returns false if the current argument isn't a flag.
+func unmarshalFlag(d dispenser, info structInfo, given map[string]bool) (bool, error) {
+	if !isFlags(info) || d.Token().Quoted() || !strings.HasPrefix(d.Val(), "--") {
+		return false, nil
+	}
+
+	name, raw, hasValue := strings.Cut(strings.TrimPrefix(d.Val(), "--"), "=")
+
+	field, ok := info.blockFieldNamed(name)
+	if !ok {
+		field, ok = info.blockFieldNamed(strings.ReplaceAll(name, "-", "_"))
+	}
+	if !ok {
+		return true, info.withExamples(d.WrapErr(fmt.Errorf("%w: unknown flag --%s", ErrUnexpectedArgument, name)))
+	}
+
+	d.trace("flag", &field)
+	d.warnDeprecated(field)
+	d.owner = field.owner
+	d.merge(field, given[field.key()])
+
+	if !hasValue {
+		switch {
+		case derefScalarPointer(optionalElem(field.value.t)).Kind() == reflect.Bool:
+			raw = "true"
+		case d.NextArg():
+			raw = d.Val()
+		default:
+			return true, d.WrapErr(fmt.Errorf("expected value after --%s", name))
+		}
+	}
+
+	if err := unmarshalNamedArg(d, field, raw, "--"+name+" <value>"); err != nil {
+		return true, err
+	}
+
+	given[field.key()] = true
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type flagsExec struct {
+	_        struct{} `caddyfile:",flags"`
+	Command  string   `caddyfile:"$1"`
+	Args     []string `caddyfile:"$2,optional"`
+	Dir      string   `caddyfile:"dir"`
+	Env      []string `caddyfile:"env"`
+	Quiet    bool     `caddyfile:"quiet"`
+	MaxProcs int      `caddyfile:"max_procs"`
+}
+
+func TestUnmarshalFlags(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect flagsExec
+	}{
+		{
+			"exec --dir /srv --quiet make build",
+			flagsExec{Command: "make", Args: []string{"build"}, Dir: "/srv", Quiet: true},
+		},
+		{
+			"exec make --env A=1 --env=B=2 --max-procs 4",
+			flagsExec{Command: "make", Env: []string{"A=1", "B=2"}, MaxProcs: 4},
+		},
+		{
+			"exec --quiet=false make \"--dir\"",
+			flagsExec{Command: "make", Args: []string{"--dir"}},
+		},
+		{
+			"exec make build --quiet test --dir=/srv lint",
+			flagsExec{Command: "make", Args: []string{"build", "test", "lint"}, Dir: "/srv", Quiet: true},
+		},
+		{
+			"exec make --quiet {\n\tdir /srv\n}",
+			flagsExec{Command: "make", Dir: "/srv", Quiet: true},
+		},
+		{
+			"exec --quiet -- grep --dir -- --quiet",
+			flagsExec{Command: "grep", Args: []string{"--dir", "--", "--quiet"}, Quiet: true},
+		},
+		{
+			"exec grep --dir /srv -- --verbose",
+			flagsExec{Command: "grep", Args: []string{"--verbose"}, Dir: "/srv"},
+		},
+		{
+			"exec make build -- --verbose",
+			flagsExec{Command: "make", Args: []string{"build", "--verbose"}},
+		},
+	}
+
+	for _, test := range tests {
+		v, err := unmarshalString[flagsExec](test.input)
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, test.expect) {
+			t.Errorf("%q: expected %+v, got %+v", test.input, test.expect, v)
+		}
+	}
+
+	_, err := unmarshalString[flagsExec]("exec make --verbose")
+	if !errors.Is(err, ErrUnexpectedArgument) || !strings.Contains(err.Error(), "unknown flag --verbose") {
+		t.Errorf("expected unknown flag error, got %v", err)
+	}
+
+	_, err = unmarshalString[flagsExec]("exec make --dir")
+	if err == nil || !strings.Contains(err.Error(), "expected value after --dir") {
+		t.Errorf("expected missing value error, got %v", err)
+	}
+
+	_, err = unmarshalString[flagsExec]("exec make --max-procs many")
+	if err == nil || !strings.Contains(err.Error(), "exec > max_procs") {
+		t.Errorf("expected error at max_procs, got %v", err)
+	}
+}
+
+func TestUsageFlags(t *testing.T) {
+	usage, err := Usage[flagsExec]("exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "exec <command> [<args...>] [--dir <string>] [--env <string>] [--quiet] [--max_procs <int>] {"
+	if line, _, _ := strings.Cut(usage, "\n"); line != expect {
+		t.Errorf("unexpected usage line:\n%s\nwant:\n%s", line, expect)
+	}
+}
+
+func TestMarshalFlagsEnd(t *testing.T) {
+	v := flagsExec{Command: "grep", Args: []string{"--dir", "x"}, Quiet: true}
+
+	b, err := Marshal("exec", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "exec -- grep --dir x {\n\tquiet\n}\n"
+	if string(b) != expect {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", b, expect)
+	}
+
+	var got flagsExec
+	if err := UnmarshalString("exec", string(b), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("expected %+v, got %+v", v, got)
+	}
+}
@@ -0,0 +1,116 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+)
+
+// GlobalOptions routes the options of a Caddyfile global options block to the
+// types registered for them using RegisterGlobalOption. The zero value is an
+// empty set of options.
+type GlobalOptions struct {
+	types map[string]reflect.Type
+}
+
+// RegisterGlobalOption registers the global option of the given name to be
+// unmarshaled into a T, which is usually a tagged struct. It panics if the
+// option is already registered in g.
+func RegisterGlobalOption[T any](g *GlobalOptions, name string) {
+	if g.types == nil {
+		g.types = make(map[string]reflect.Type)
+	}
+
+	if _, ok := g.types[name]; ok {
+		panic(fmt.Sprintf("caddyunmarshal: global option %q already registered", name))
+	}
+
+	g.types[name] = reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Unmarshal unmarshals the global options block at the dispenser, e.g.
+//
+//	{
+//		my_app {
+//			workers 4
+//		}
+//		my_storage /var/lib/data
+//	}
+//
+// The returned map holds a *T for each option that occurs, keyed by the
+// option name. Repeated options are merged, i.e. unmarshaled into the same
+// value, so slices are appended to and fields are overridden. Unknown options
+// are an error, just like in Caddy.
+func (g *GlobalOptions) Unmarshal(d *caddyfile.Dispenser) (map[string]any, error) {
+	if d.Val() != "{" && !d.Next() {
+		return nil, fmt.Errorf("caddyunmarshal: expected global options block, got nothing")
+	}
+	if d.Val() != "{" && d.Val() != "{}" {
+		return nil, d.Errf("expected global options block, got %s", d.Val())
+	}
+
+	dd := dispenser{Dispenser: d}
+	values := make(map[string]any)
+
+	if dd.inEmptyBlock() {
+		return values, nil
+	}
+
+	for dd.nextInBlock() {
+		name := d.Val()
+
+		t, ok := g.types[name]
+		if !ok {
+			return nil, d.Errf("unrecognized global option: %s", name)
+		}
+
+		v, err := unmarshalGlobalOption(dd, t, values[name])
+		if err != nil {
+			return nil, err
+		}
+
+		values[name] = v
+	}
+
+	return values, nil
+}
+
+// RegisterCaddyfile registers all options of g with
+// httpcaddyfile.RegisterGlobalOption, so that Caddy parses them from the
+// global options block of a Caddyfile. The parsed *T is then found in the
+// options of httpcaddyfile, under the option name.
+func (g *GlobalOptions) RegisterCaddyfile() {
+	names := make([]string, 0, len(g.types))
+	for name := range g.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		t := g.types[name]
+		httpcaddyfile.RegisterGlobalOption(name, func(d *caddyfile.Dispenser, existing any) (any, error) {
+			// consume the option name
+			d.Next()
+			return unmarshalGlobalOption(dispenser{Dispenser: d}, t, existing)
+		})
+	}
+}
+
+// unmarshalGlobalOption unmarshals the option at the cursor into a new *T, or
+// into existing if it's a *T from a previous occurrence.
+func unmarshalGlobalOption(d dispenser, t reflect.Type, existing any) (any, error) {
+	ptr := reflect.New(t)
+	if prev := reflect.ValueOf(existing); prev.IsValid() && prev.Type() == ptr.Type() {
+		ptr = prev
+	}
+
+	name := d.Val()
+	if err := unmarshalLine(d, reflectValue{ptr.Elem(), t}, nil); err != nil {
+		return nil, withPath(name, err)
+	}
+
+	return ptr.Interface(), nil
+}
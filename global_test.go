@@ -0,0 +1,103 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+)
+
+type testAppOption struct {
+	Workers int      `caddyfile:"workers"`
+	Tags    []string `caddyfile:"tag"`
+}
+
+func newTestGlobalOptions() *GlobalOptions {
+	var g GlobalOptions
+	RegisterGlobalOption[testAppOption](&g, "test_app")
+	RegisterGlobalOption[string](&g, "test_storage")
+	return &g
+}
+
+func TestGlobalOptionsUnmarshal(t *testing.T) {
+	g := newTestGlobalOptions()
+
+	d := caddyfile.NewTestDispenser(`{
+		test_app {
+			workers 4
+			tag a
+		}
+		test_storage /var/lib/data
+		test_app {
+			tag b
+		}
+	}`)
+
+	values, err := g.Unmarshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := "/var/lib/data"
+	expect := map[string]any{
+		"test_app":     &testAppOption{Workers: 4, Tags: []string{"a", "b"}},
+		"test_storage": &storage,
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("unexpected values: %#v", values)
+	}
+}
+
+func TestGlobalOptionsUnmarshalErrors(t *testing.T) {
+	g := newTestGlobalOptions()
+
+	tests := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{
+			"unknown option",
+			"{\n\tunknown 1\n}",
+			"Testfile:2 - Error during parsing: unrecognized global option: unknown",
+		},
+		{
+			"not a block",
+			"test_app",
+			"Testfile:1 - Error during parsing: expected global options block, got test_app",
+		},
+		{
+			"invalid value",
+			"{\n\ttest_app {\n\t\tworkers many\n\t}\n}",
+			`test_app > workers: Testfile:3 - Error during parsing: ` +
+				`cannot parse int: strconv.ParseInt: parsing "many": invalid syntax`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := g.Unmarshal(caddyfile.NewTestDispenser(test.input))
+			if err == nil || err.Error() != test.expect {
+				t.Errorf("unexpected error:\ngot  %v\nwant %s", err, test.expect)
+			}
+		})
+	}
+}
+
+func TestGlobalOptionsRegisterCaddyfile(t *testing.T) {
+	var g GlobalOptions
+	RegisterGlobalOption[testAppOption](&g, "test_registered_app")
+	g.RegisterCaddyfile()
+
+	adapter := caddyfile.Adapter{ServerType: httpcaddyfile.ServerType{}}
+	_, _, err := adapter.Adapt([]byte("{\n\ttest_registered_app {\n\t\tworkers 4\n\t}\n}\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = adapter.Adapt([]byte("{\n\ttest_registered_app {\n\t\tworkers x\n\t}\n}\n"), nil)
+	if err == nil {
+		t.Fatal("expected error for invalid option")
+	}
+}
@@ -2,7 +2,10 @@ module github.com/diamondburned/caddyunmarshal
 
 go 1.18
 
-require github.com/caddyserver/caddy/v2 v2.6.4
+require (
+	github.com/caddyserver/caddy/v2 v2.6.4
+	go.uber.org/zap v1.24.0
+)
 
 require (
 	filippo.io/edwards25519 v1.0.0 // indirect
@@ -91,7 +94,6 @@ require (
 	go.step.sm/linkedca v0.19.0 // indirect
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.6.0 // indirect
-	go.uber.org/zap v1.24.0 // indirect
 	golang.org/x/crypto v0.5.0 // indirect
 	golang.org/x/exp v0.0.0-20221205204356-47842c84f3db // indirect
 	golang.org/x/mod v0.6.0 // indirect
@@ -0,0 +1,142 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+// MatcherGroup is a group of directive values sharing the same matcher.
+type MatcherGroup[T any] struct {
+	// Matcher is the matcher shared by the values, or nil if they have none.
+	Matcher caddy.ModuleMap
+	// Values are the directive values in their original order.
+	Values []T
+}
+
+// GroupByMatcher groups the given values of a repeated directive by their
+// $matcher field. Groups are ordered by the first appearance of their matcher,
+// and values without a matcher form a group with a nil Matcher. T must be a
+// struct, or a pointer to one, with a $matcher field.
+func GroupByMatcher[T any](values []T) ([]MatcherGroup[T], error) {
+	return GroupByMatcherWithOptions(values, Options{})
+}
+
+// GroupByMatcherWithOptions is like GroupByMatcher, except the $matcher field
+// is found using the TagKey of the given options.
+func GroupByMatcherWithOptions[T any](values []T, opts Options) ([]MatcherGroup[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	isPtr := t.Kind() == reflect.Pointer
+	if isPtr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("caddyunmarshal: cannot group non-struct type %s", t)
+	}
+
+	info, err := extractFields(zeroValue(t), &opts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot extract fields: %w", err)
+	}
+
+	if info.matcher == nil {
+		return nil, fmt.Errorf("caddyunmarshal: %s has no $matcher field", t)
+	}
+	if info.matcher.value.t == TypeMatcherSet {
+		return nil, fmt.Errorf("caddyunmarshal: cannot group by decoded matcher set of %s", t)
+	}
+
+	var groups []MatcherGroup[T]
+	indices := make(map[string]int)
+
+	for _, value := range values {
+		v := reflect.ValueOf(&value).Elem()
+		if isPtr {
+			if v.IsNil() {
+				continue
+			}
+			v = v.Elem()
+		}
+
+		var matcher caddy.ModuleMap
+		switch field := v.FieldByIndex(info.matcher.field.Index).Interface().(type) {
+		case caddy.ModuleMap:
+			matcher = field
+		case caddyhttp.RawMatcherSets:
+			if len(field) > 0 {
+				matcher = field[0]
+			}
+		}
+
+		// Module maps encode deterministically, since the keys are sorted.
+		key, err := json.Marshal(matcher)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode matcher: %w", err)
+		}
+
+		ix, ok := indices[string(key)]
+		if !ok {
+			ix = len(groups)
+			indices[string(key)] = ix
+			groups = append(groups, MatcherGroup[T]{Matcher: matcher})
+		}
+
+		groups[ix].Values = append(groups[ix].Values, value)
+	}
+
+	return groups, nil
+}
+
+// GroupRoutes groups the given values of a repeated directive using
+// GroupByMatcher, and emits one route per matcher, whose single handler is
+// built from all values in the group. This saves a directive like
+//
+//	redir @old /new
+//	redir @old /other
+//	redir /a /b
+//
+// from expanding into one route per occurrence. The handler must be a Caddy
+// module.
+func GroupRoutes[T any](values []T, handler func(values []T) (caddyhttp.MiddlewareHandler, error)) (caddyhttp.RouteList, error) {
+	groups, err := GroupByMatcher(values)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make(caddyhttp.RouteList, 0, len(groups))
+
+	for _, group := range groups {
+		h, err := handler(group.Values)
+		if err != nil {
+			return nil, err
+		}
+
+		mod, ok := h.(caddy.Module)
+		if !ok {
+			return nil, fmt.Errorf("caddyunmarshal: handler %T is not a Caddy module", h)
+		}
+
+		var warnings []caddyconfig.Warning
+		route := caddyhttp.Route{
+			HandlersRaw: []json.RawMessage{
+				caddyconfig.JSONModuleObject(h, "handler", mod.CaddyModule().ID.Name(), &warnings),
+			},
+		}
+		if len(warnings) > 0 {
+			return nil, fmt.Errorf("cannot encode handler: %s", warnings[0].Message)
+		}
+
+		if group.Matcher != nil {
+			route.MatcherSetsRaw = caddyhttp.RawMatcherSets{group.Matcher}
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
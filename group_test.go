@@ -0,0 +1,122 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+type redirEntry struct {
+	Matcher caddy.ModuleMap `caddyfile:"$matcher"`
+	To      string          `caddyfile:"$1"`
+}
+
+type redirHandler struct {
+	To []string `json:"to"`
+}
+
+func (redirHandler) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.test_redir",
+		New: func() caddy.Module { return new(redirHandler) },
+	}
+}
+
+func (redirHandler) ServeHTTP(http.ResponseWriter, *http.Request, caddyhttp.Handler) error {
+	return nil
+}
+
+func TestGroupByMatcher(t *testing.T) {
+	old := caddy.ModuleMap{"path": json.RawMessage(`["/old"]`)}
+	api := caddy.ModuleMap{"path": json.RawMessage(`["/api/*"]`)}
+
+	entries := []*redirEntry{
+		{old, "/new"},
+		{nil, "/a"},
+		{api, "/v2"},
+		{caddy.ModuleMap{"path": json.RawMessage(`["/old"]`)}, "/other"},
+		nil,
+		{nil, "/b"},
+	}
+
+	groups, err := GroupByMatcher(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []MatcherGroup[*redirEntry]{
+		{old, []*redirEntry{entries[0], entries[3]}},
+		{nil, []*redirEntry{entries[1], entries[5]}},
+		{api, []*redirEntry{entries[2]}},
+	}
+	if !reflect.DeepEqual(groups, expect) {
+		t.Errorf("unexpected groups: %+v", groups)
+	}
+
+	if _, err := GroupByMatcher([]thing2{}); err == nil {
+		t.Error("expected error for struct without matcher")
+	}
+}
+
+func TestGroupRoutes(t *testing.T) {
+	old := caddy.ModuleMap{"path": json.RawMessage(`["/old"]`)}
+
+	routes, err := GroupRoutes([]redirEntry{
+		{old, "/new"},
+		{nil, "/a"},
+		{old, "/other"},
+	}, func(entries []redirEntry) (caddyhttp.MiddlewareHandler, error) {
+		var h redirHandler
+		for _, entry := range entries {
+			h.To = append(h.To, entry.To)
+		}
+		return h, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = `[` +
+		`{"match":[{"path":["/old"]}],"handle":[{"handler":"test_redir","to":["/new","/other"]}]},` +
+		`{"handle":[{"handler":"test_redir","to":["/a"]}]}` +
+		`]`
+	if string(b) != expect {
+		t.Errorf("unexpected routes:\ngot  %s\nwant %s", b, expect)
+	}
+}
+
+func TestGroupByRawMatcherSets(t *testing.T) {
+	type entry struct {
+		Matcher caddyhttp.RawMatcherSets `caddyfile:"$matcher"`
+		To      string                   `caddyfile:"$1"`
+	}
+
+	old := caddy.ModuleMap{"path": json.RawMessage(`["/old"]`)}
+	entries := []entry{
+		{caddyhttp.RawMatcherSets{old}, "/new"},
+		{nil, "/a"},
+		{caddyhttp.RawMatcherSets{old}, "/other"},
+	}
+
+	groups, err := GroupByMatcher(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []MatcherGroup[entry]{
+		{old, []entry{entries[0], entries[2]}},
+		{nil, []entry{entries[1]}},
+	}
+	if !reflect.DeepEqual(groups, expect) {
+		t.Errorf("unexpected groups: %+v", groups)
+	}
+}
@@ -0,0 +1,44 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// PostUnmarshaler is implemented by structs that want to do more work after
+// their fields are set, such as normalizing values or recording positional
+// metadata. PostUnmarshalCaddyfile is called with a new dispenser over the
+// segment that the struct was unmarshaled from, i.e. its directive or
+// subdirective and the block that follows it. Like with UnmarshalCaddyfile,
+// the dispenser is positioned before the directive name.
+//
+// The hook is called before the struct is validated.
+type PostUnmarshaler interface {
+	PostUnmarshalCaddyfile(d *caddyfile.Dispenser) error
+}
+
+var typePostUnmarshaler = reflect.TypeOf((*PostUnmarshaler)(nil)).Elem()
+
+// hasPostUnmarshaler returns true if a pointer to the given type implements
+// PostUnmarshaler.
+func hasPostUnmarshaler(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(typePostUnmarshaler)
+}
+
+// segment returns a copy of the segment starting at the current token without
+// moving the cursor.
+func (d dispenser) segment() caddyfile.Segment {
+	seg := d.NextSegment()
+	for i := 1; i < len(seg); i++ {
+		d.Prev()
+	}
+	return seg
+}
+
+// postUnmarshal calls the PostUnmarshalCaddyfile hook of the given struct
+// value with the given segment.
+func postUnmarshal(r reflectValue, seg caddyfile.Segment) error {
+	hook := r.v.Addr().Interface().(PostUnmarshaler)
+	return hook.PostUnmarshalCaddyfile(caddyfile.NewDispenser(seg))
+}
@@ -0,0 +1,49 @@
+package caddyunmarshal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type hookedUpstream struct {
+	Host string `caddyfile:"$1"`
+	Line int    `caddyfile:"-"`
+}
+
+func (u *hookedUpstream) PostUnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	d.Next()
+	u.Line = d.Line()
+	u.Host = strings.ToLower(u.Host)
+	return nil
+}
+
+type hookedProxy struct {
+	To        string           `caddyfile:"$1"`
+	Upstreams []hookedUpstream `caddyfile:"upstream"`
+	Segment   []string         `caddyfile:"-"`
+}
+
+func (p *hookedProxy) PostUnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	for d.Next() {
+		p.Segment = append(p.Segment, d.Val())
+	}
+	return nil
+}
+
+func TestUnmarshalPostUnmarshaler(t *testing.T) {
+	v, err := unmarshalString[hookedProxy]("proxy a {\n upstream A.example.com\n upstream b\n}\nnext b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v.Upstreams) != 2 || v.Upstreams[0] != (hookedUpstream{"a.example.com", 2}) || v.Upstreams[1].Line != 3 {
+		t.Errorf("unexpected upstreams: %+v", v.Upstreams)
+	}
+
+	const expect = "proxy a { upstream A.example.com upstream b }"
+	if segment := strings.Join(v.Segment, " "); segment != expect {
+		t.Errorf("unexpected segment %q", segment)
+	}
+}
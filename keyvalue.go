@@ -0,0 +1,71 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// isKeyValue returns true if the struct takes key=value arguments, given
+// using the struct-level keyvalue option, e.g.
+//
+//	type Upstream struct {
+//		_        struct{} `caddyfile:",keyvalue"`
+//		Dial     string   `caddyfile:"$1"`
+//		Weight   int      `caddyfile:"weight"`
+//		MaxConns int      `caddyfile:"max_conns"`
+//	}
+//
+// takes "upstream localhost:8080 weight=5 max_conns=100". An argument of the
+// form name=value sets the subdirective field of that name, wherever it is
+// on the line, and doesn't take up a position. Other arguments, including
+// quoted ones, are positional as usual, so that values containing "=" can
+// still be given. The fields may also be given as subdirectives within the
+// block.
+func isKeyValue(info structInfo) bool {
+	return hasOpt(info.opts, "keyvalue")
+}
+
+// keyValueField returns the subdirective field named by the current
+// argument, if it's a key=value argument, along with its value.
+func (info structInfo) keyValueField(d dispenser) (fieldInfo, string, bool) {
+	if !isKeyValue(info) || d.Token().Quoted() {
+		return fieldInfo{}, "", false
+	}
+
+	name, value, ok := strings.Cut(d.Val(), "=")
+	if !ok {
+		return fieldInfo{}, "", false
+	}
+
+	field, ok := info.blockFieldNamed(name)
+	return field, value, ok
+}
+
+// unmarshalNamedArg unmarshals the value of a key=value argument or a flag
+// into its field, where form describes how the value was given for errors.
+// Only scalars and slices of them can be given this way; slices take an
+// element per argument, or several using the split option.
+func unmarshalNamedArg(d dispenser, field fieldInfo, raw, form string) error {
+	name := field.kind.(blockFieldKind).name
+
+	var secrets []string
+	if isSecret(field.opts) {
+		secrets = []string{raw}
+	}
+
+	var err error
+	switch t := derefScalarPointer(optionalElem(field.value.t)); {
+	case isScalar(t):
+		err = unmarshalValue(d, field.value, raw, field.opts)
+	case t.Kind() == reflect.Slice && isScalar(t.Elem()):
+		err = appendElems(d, field.value, raw, field.opts)
+	default:
+		err = d.WrapErr(fmt.Errorf("subdirective of type %s cannot be given as %s", field.value.t, form))
+	}
+
+	if err != nil {
+		return errorAtName(name, redact(err, secrets))
+	}
+	return nil
+}
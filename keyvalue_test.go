@@ -0,0 +1,84 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type keyValueUpstream struct {
+	_        struct{}      `caddyfile:",keyvalue"`
+	Dial     string        `caddyfile:"$1"`
+	Name     string        `caddyfile:"$2,optional"`
+	Weight   int           `caddyfile:"weight"`
+	MaxConns int           `caddyfile:"max_conns"`
+	Timeout  time.Duration `caddyfile:"timeout"`
+	Tags     []string      `caddyfile:"tags,split"`
+	Health   struct {
+		URI string `caddyfile:"uri"`
+	} `caddyfile:"health"`
+}
+
+func TestUnmarshalKeyValue(t *testing.T) {
+	tests := []struct {
+		input  string
+		expect keyValueUpstream
+	}{
+		{
+			"upstream localhost:8080 weight=5 max_conns=100",
+			keyValueUpstream{Dial: "localhost:8080", Weight: 5, MaxConns: 100},
+		},
+		{
+			"upstream weight=5 localhost:8080 primary tags=a,b tags=c",
+			keyValueUpstream{Dial: "localhost:8080", Name: "primary", Weight: 5, Tags: []string{"a", "b", "c"}},
+		},
+		{
+			"upstream localhost:8080 \"a=b\" timeout=5s",
+			keyValueUpstream{Dial: "localhost:8080", Name: "a=b", Timeout: 5 * time.Second},
+		},
+		{
+			"upstream localhost:8080 weight=5 {\n\tmax_conns 10\n}",
+			keyValueUpstream{Dial: "localhost:8080", Weight: 5, MaxConns: 10},
+		},
+	}
+
+	for _, test := range tests {
+		v, err := unmarshalString[keyValueUpstream](test.input)
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, test.expect) {
+			t.Errorf("%q: expected %+v, got %+v", test.input, test.expect, v)
+		}
+	}
+
+	_, err := unmarshalString[keyValueUpstream]("upstream localhost:8080 weight=heavy")
+	if err == nil || !strings.Contains(err.Error(), "upstream > weight") {
+		t.Errorf("expected error at weight, got %v", err)
+	}
+
+	_, err = unmarshalString[keyValueUpstream]("upstream localhost:8080 health=/")
+	if err == nil || !strings.Contains(err.Error(), "cannot be given as health=<value>") {
+		t.Errorf("expected error for struct key=value, got %v", err)
+	}
+
+	_, err = unmarshalString[keyValueUpstream]("upstream localhost:8080 primary unknown=1")
+	if !errors.Is(err, ErrUnexpectedArgument) {
+		t.Errorf("expected unknown key to be an unexpected argument, got %v", err)
+	}
+}
+
+func TestUsageKeyValue(t *testing.T) {
+	usage, err := Usage[keyValueUpstream]("upstream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "upstream <dial> [<name>] [weight=<int>] [max_conns=<int>] [timeout=<duration>] [tags=<string>] {"
+	if line, _, _ := strings.Cut(usage, "\n"); line != expect {
+		t.Errorf("unexpected usage line:\n%s\nwant:\n%s", line, expect)
+	}
+}
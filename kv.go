@@ -0,0 +1,26 @@
+package caddyunmarshal
+
+import "reflect"
+
+// KV is a single subdirective within a block, keyed by its name. A []KV[V]
+// field collects all subdirectives of its block in their original order,
+// which is useful for order-sensitive directives where a map would lose the
+// ordering, e.g.
+//
+//	Headers []caddyunmarshal.KV[string] `caddyfile:"header"`
+//
+// The value is unmarshaled from the rest of the subdirective's line, just like
+// a map value would be.
+type KV[V any] struct {
+	Key   string
+	Value V
+}
+
+func (KV[V]) kv() {}
+
+var typeKV = reflect.TypeOf((*interface{ kv() })(nil)).Elem()
+
+// isKVSlice returns true if the given type is a []KV[V].
+func isKVSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Implements(typeKV)
+}
@@ -0,0 +1,34 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// MaxTokenLength is the maximum length of a single token in bytes. Longer
+// tokens are rejected before they are parsed, which protects against
+// pathological inputs, e.g. ones submitted through the admin API's adapt
+// endpoint. Individual fields may override it using the maxlen option, e.g.
+// `caddyfile:"cert,maxlen=1048576"`. A limit of 0 disables the check.
+var MaxTokenLength = 64 * 1024
+
+// checkLength returns an error if the given token is longer than allowed by
+// the maxlen option or the dispenser's options. The token itself is not
+// included in the error, since it may be huge.
+func (d dispenser) checkLength(token string, opts []string) error {
+	max := d.options.maxTokenLength()
+
+	if v, ok := optValue(opts, "maxlen"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("caddyunmarshal: invalid maxlen %q", v)
+		}
+		max = n
+	}
+
+	if max > 0 && len(token) > max {
+		return fmt.Errorf("token is too long: %d bytes, at most %d allowed", len(token), max)
+	}
+
+	return nil
+}
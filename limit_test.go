@@ -0,0 +1,35 @@
+package caddyunmarshal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalTokenLength(t *testing.T) {
+	type header struct {
+		Name  string `caddyfile:"$1"`
+		Value string `caddyfile:"$2,maxlen=8"`
+		Other string `caddyfile:"other"`
+	}
+
+	if _, err := unmarshalString[header]("header X-A 12345678"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := unmarshalString[header]("header X-A 123456789")
+	if err == nil || !strings.Contains(err.Error(), "token is too long: 9 bytes, at most 8 allowed") {
+		t.Errorf("expected maxlen error, got %v", err)
+	}
+
+	long := strings.Repeat("a", MaxTokenLength+1)
+
+	_, err = unmarshalString[header]("header " + long + " v")
+	if err == nil || strings.Contains(err.Error(), long) {
+		t.Errorf("expected MaxTokenLength error without the token, got %v", err)
+	}
+
+	_, err = unmarshalString[header]("header a b {\n " + long + " c\n}")
+	if err == nil {
+		t.Error("expected error for long subdirective name")
+	}
+}
@@ -0,0 +1,134 @@
+package caddyunmarshal
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MapEntryError is returned when the key or the value of a map entry cannot
+// be unmarshaled. Map keys may be of any scalar type, e.g. int for ports or
+// time.Duration for thresholds, or implement encoding.TextUnmarshaler, e.g.
+// netip.Addr, so both the key and the value may be invalid. Errors of the key
+// are prefixed with "invalid key".
+type MapEntryError struct {
+	// Key is the raw key of the entry.
+	Key string
+	// Value is the raw value of the entry, which are the arguments following
+	// the key on the same line.
+	Value string
+	// Pos is the position of the entry.
+	Pos Position
+	// Err is the error that occurred.
+	Err error
+}
+
+func (err *MapEntryError) Error() string {
+	if err.Value == "" {
+		return fmt.Sprintf("invalid map entry %q: %v", err.Key, err.Err)
+	}
+	return fmt.Sprintf("invalid map entry %q %q: %v", err.Key, err.Value, err.Err)
+}
+
+func (err *MapEntryError) Unwrap() error {
+	return err.Err
+}
+
+// newMapEntryError wraps err into a MapEntryError for the entry with the given
+// key and value arguments.
+func newMapEntryError(pos Position, key string, value []string, err error) error {
+	return &MapEntryError{
+		Key:   key,
+		Value: strings.Join(value, " "),
+		Pos:   pos,
+		Err:   err,
+	}
+}
+
+var typeTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isTextKey returns true if the given map key type is parsed using its
+// encoding.TextUnmarshaler implementation, which is the case for key types
+// that aren't scalars themselves, e.g. netip.Addr.
+func isTextKey(t reflect.Type) bool {
+	return !isScalar(t) && reflect.PtrTo(t).Implements(typeTextUnmarshaler)
+}
+
+// unmarshalMapKey unmarshals the raw key of a map entry into r.
+func unmarshalMapKey(d dispenser, r reflectValue, raw string) error {
+	if isTextKey(r.t) {
+		unmarshaler := r.v.Addr().Interface().(encoding.TextUnmarshaler)
+		if err := unmarshaler.UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("invalid key: %w", d.WrapErr(err))
+		}
+		return nil
+	}
+
+	if !isScalar(r.t) {
+		return fmt.Errorf("caddyunmarshal: map key of %w %s", ErrUnsupportedType, r.t)
+	}
+
+	if err := unmarshalValue(d, r, raw, nil); err != nil {
+		return fmt.Errorf("invalid key: %w", err)
+	}
+	return nil
+}
+
+// lineArgs returns the arguments following the current token on the same
+// line, up to an opening brace, without consuming them.
+func (d dispenser) lineArgs() []string {
+	var args []string
+	for d.NextArg() {
+		if d.Val() == "{" {
+			d.Prev()
+			break
+		}
+		args = append(args, d.Val())
+	}
+	for range args {
+		d.Prev()
+	}
+	return args
+}
+
+// unmarshalKeyedLine unmarshals the current line into the map r using the
+// keyed option, e.g. `caddyfile:"to,keyed"` on a map[string]Upstream field
+// takes
+//
+//	to localhost:8080 {
+//		weight 5
+//	}
+//
+// as the entry "localhost:8080", whose value is unmarshaled from the rest of
+// the line and the block. Repeating the key unmarshals into the same value.
+func unmarshalKeyedLine(d dispenser, r reflectValue) error {
+	if !d.NextArg() {
+		return d.ArgErr()
+	}
+
+	if r.v.IsNil() {
+		r.v.Set(reflect.MakeMap(r.t))
+	}
+
+	name := d.Val()
+	pos, raw := tokenPosition(d.Dispenser), d.lineArgs()
+	d.trace("entry", nil)
+
+	key := reflect.New(r.t.Key()).Elem()
+	if err := unmarshalMapKey(d, reflectValue{key, key.Type()}, name); err != nil {
+		return newMapEntryError(pos, name, raw, err)
+	}
+
+	val := reflect.New(r.t.Elem()).Elem()
+	if existing := r.v.MapIndex(key); existing.IsValid() {
+		val.Set(existing)
+	}
+
+	if err := unmarshalLine(d, reflectValue{val, val.Type()}, nil); err != nil {
+		return newMapEntryError(pos, name, raw, err)
+	}
+
+	r.v.SetMapIndex(key, val)
+	return nil
+}
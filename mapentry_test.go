@@ -0,0 +1,226 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalMapKeys(t *testing.T) {
+	type target struct {
+		Ports      map[int]string           `caddyfile:"ports"`
+		Thresholds map[time.Duration]string `caddyfile:"thresholds"`
+	}
+
+	v, err := unmarshalString[target](`
+		target {
+			ports {
+				80 http
+				443 https
+			}
+			thresholds {
+				1s warn
+				1m error
+			}
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := target{
+		Ports:      map[int]string{80: "http", 443: "https"},
+		Thresholds: map[time.Duration]string{time.Second: "warn", time.Minute: "error"},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+}
+
+func TestUnmarshalTextMapKeys(t *testing.T) {
+	type acl struct {
+		Hosts   map[netip.Addr]string   `caddyfile:"hosts"`
+		Subnets map[netip.Prefix]string `caddyfile:"subnet,keyed"`
+	}
+
+	v, err := unmarshalString[acl](`
+		acl {
+			hosts {
+				10.0.0.1 gateway
+				::1 loopback
+			}
+			subnet 10.0.0.0/8 allow
+			subnet 192.168.0.0/16 deny
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := acl{
+		Hosts: map[netip.Addr]string{
+			netip.MustParseAddr("10.0.0.1"): "gateway",
+			netip.MustParseAddr("::1"):      "loopback",
+		},
+		Subnets: map[netip.Prefix]string{
+			netip.MustParsePrefix("10.0.0.0/8"):     "allow",
+			netip.MustParsePrefix("192.168.0.0/16"): "deny",
+		},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+
+	_, err = unmarshalString[acl]("acl {\n hosts {\n  10.0.0.256 gateway\n }\n}")
+
+	var entryErr *MapEntryError
+	if !errors.As(err, &entryErr) || entryErr.Key != "10.0.0.256" || entryErr.Pos.Line != 3 {
+		t.Fatalf("expected MapEntryError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `invalid map entry "10.0.0.256" "gateway": invalid key: Testfile:3 - `) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	b, err := Marshal("acl", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "\tsubnet 10.0.0.0/8 allow\n\tsubnet 192.168.0.0/16 deny\n") {
+		t.Errorf("unexpected output:\n%s", b)
+	}
+
+	type invalid struct {
+		Pairs map[[2]string]string `caddyfile:"pairs"`
+	}
+
+	_, err = unmarshalString[invalid]("invalid {\n pairs {\n  a b\n }\n}")
+	if err == nil || !strings.Contains(err.Error(), "map key of unsupported type [2]string") {
+		t.Errorf("expected unsupported key error, got %v", err)
+	}
+}
+
+func TestUnmarshalMapEntryError(t *testing.T) {
+	type target struct {
+		Ports  map[int]string `caddyfile:"ports"`
+		Limits map[string]int `caddyfile:"limits"`
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		expect MapEntryError
+	}{
+		{
+			name:   "invalid key",
+			input:  "target {\n ports {\n  http 80\n }\n}",
+			expect: MapEntryError{Key: "http", Value: "80", Pos: Position{"Testfile", 3}},
+		},
+		{
+			name:   "invalid value",
+			input:  "target {\n limits {\n  requests 10 per_second\n }\n}",
+			expect: MapEntryError{Key: "requests", Value: "10 per_second", Pos: Position{"Testfile", 3}},
+		},
+		{
+			name:   "invalid value with block",
+			input:  "target {\n limits {\n  requests abc {\n  }\n }\n}",
+			expect: MapEntryError{Key: "requests", Value: "abc", Pos: Position{"Testfile", 3}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := unmarshalString[target](test.input)
+
+			var entryErr *MapEntryError
+			if !errors.As(err, &entryErr) {
+				t.Fatalf("expected MapEntryError, got %v", err)
+			}
+
+			if entryErr.Err == nil {
+				t.Error("missing underlying error")
+			}
+			entryErr.Err = nil
+
+			if *entryErr != test.expect {
+				t.Errorf("unexpected error:\ngot  %+v\nwant %+v", *entryErr, test.expect)
+			}
+
+			if len(v.Ports) > 0 || len(v.Limits) > 0 {
+				t.Errorf("invalid entry was stored: %+v", v)
+			}
+		})
+	}
+}
+
+func TestUnmarshalKeyed(t *testing.T) {
+	type upstream struct {
+		Weight  int      `caddyfile:"weight"`
+		Backup  bool     `caddyfile:"backup"`
+		Headers []string `caddyfile:"header"`
+	}
+
+	type target struct {
+		To    map[string]upstream `caddyfile:"to,keyed"`
+		Ports map[int]string      `caddyfile:"port,keyed"`
+	}
+
+	v, err := unmarshalString[target](`
+		target {
+			to localhost:8080 {
+				weight 5
+			}
+			to localhost:8081
+			to localhost:8080 {
+				header X-A
+			}
+			port 80 http
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := target{
+		To: map[string]upstream{
+			"localhost:8080": {Weight: 5, Headers: []string{"X-A"}},
+			"localhost:8081": {},
+		},
+		Ports: map[int]string{80: "http"},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+
+	_, err = unmarshalString[target]("target {\n port http 80\n}")
+	var entryErr *MapEntryError
+	if !errors.As(err, &entryErr) || entryErr.Key != "http" || entryErr.Value != "80" {
+		t.Errorf("expected MapEntryError for http, got %v", err)
+	}
+
+	if _, err := unmarshalString[target]("target {\n to\n}"); err == nil {
+		t.Error("expected error for missing key")
+	}
+}
+
+func TestUsageKeyed(t *testing.T) {
+	type upstream struct {
+		Weight int `caddyfile:"weight"`
+	}
+
+	type target struct {
+		To map[string]upstream `caddyfile:"to,keyed"`
+	}
+
+	usage, err := Usage[target]("target")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "target {\n\tto <string> {\n\t\tweight <int>\n\t}\n}\n"
+	if usage != expect {
+		t.Errorf("unexpected usage:\n%s", usage)
+	}
+}
@@ -0,0 +1,899 @@
+package caddyunmarshal
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+// CaddyfileMarshaler is the counterpart of caddyfile.Unmarshaler for Marshal.
+// Types implementing it are marshaled by calling MarshalCaddyfile instead of
+// using reflection.
+//
+// When used as a directive or subdirective, MarshalCaddyfile returns the
+// tokens following its name, i.e. its arguments and optionally a block. A new
+// line is started wherever the Line of a token differs from the previous one,
+// and unquoted braces open and close blocks. When used as an argument, it
+// returns the tokens of the argument only.
+type CaddyfileMarshaler interface {
+	MarshalCaddyfile() ([]caddyfile.Token, error)
+}
+
+// Marshal marshals the given struct value into a Caddyfile snippet of the
+// given directive, such that unmarshaling the snippet gives back the value.
+// Zero-valued optional arguments and subdirectives are left out, and secret
+// fields are rendered as Redacted, see MarshalOptions. The output is formatted
+// like caddy fmt would.
+func Marshal[T any](directive string, v *T) ([]byte, error) {
+	return MarshalWithOptions(directive, v, MarshalOptions{})
+}
+
+// MarshalOptions configures how a value is marshaled. The zero value is the
+// default behavior of Marshal.
+type MarshalOptions struct {
+	// Indent is the indentation of each block level. If empty, a tab is
+	// used, which is what caddy fmt expects.
+	Indent string
+	// Sort orders subdirectives alphabetically instead of in the order their
+	// fields are declared.
+	Sort bool
+	// OmitDefaults also leaves out the optional arguments and subdirectives
+	// whose value is the same as their documented default option, e.g.
+	// `caddyfile:"interval,default=30s"`.
+	OmitDefaults bool
+	// Secrets renders the values of secret fields as they are, instead of
+	// as Redacted.
+	Secrets bool
+	// TagKey is the key of the struct tag to read, like Options.TagKey. If
+	// empty, "caddyfile" is used.
+	TagKey string
+	// FieldName converts the Go name of a field to its subdirective name,
+	// like Options.FieldName. If nil, field names are converted to
+	// snake_case.
+	FieldName func(string) string
+}
+
+// MarshalWithOptions is like Marshal, except the given options are used.
+func MarshalWithOptions[T any](directive string, v *T, opts MarshalOptions) ([]byte, error) {
+	r, err := newReflectValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	e := encoder{marshal: opts, options: &Options{TagKey: opts.TagKey, FieldName: opts.FieldName}}
+	if err := e.line([]string{directive}, r, nil); err != nil {
+		return nil, err
+	}
+
+	return e.format()
+}
+
+// encodedToken is a token emitted by the encoder.
+type encodedToken struct {
+	text  string
+	line  int
+	brace bool // opens or closes a block, so it's never quoted
+}
+
+// encoder emits the tokens of marshaled values, line by line.
+type encoder struct {
+	tokens  []encodedToken
+	lineNo  int
+	options *Options
+	marshal MarshalOptions
+}
+
+func (e *encoder) words(texts ...string) {
+	for _, text := range texts {
+		e.tokens = append(e.tokens, encodedToken{text: text, line: e.lineNo})
+	}
+}
+
+func (e *encoder) brace(text string) {
+	e.tokens = append(e.tokens, encodedToken{text: text, line: e.lineNo, brace: true})
+}
+
+func (e *encoder) newline() {
+	e.lineNo++
+}
+
+// openBlock opens a block at the end of the current line.
+func (e *encoder) openBlock() {
+	e.brace("{")
+	e.newline()
+}
+
+// custom emits the tokens returned by a CaddyfileMarshaler, starting on the
+// current line.
+func (e *encoder) custom(tokens []caddyfile.Token) {
+	for i, token := range tokens {
+		if i > 0 && token.Line != tokens[i-1].Line {
+			e.newline()
+		}
+
+		if (token.Text == "{" || token.Text == "}") && !token.Quoted() {
+			e.brace(token.Text)
+		} else {
+			e.words(token.Text)
+		}
+	}
+}
+
+// format renders the emitted tokens, indenting blocks.
+func (e *encoder) format() ([]byte, error) {
+	var b bytes.Buffer
+	var depth int
+
+	indent := e.marshal.Indent
+	if indent == "" {
+		indent = "\t"
+	}
+
+	for i, token := range e.tokens {
+		if token.brace && token.text == "}" && depth > 0 {
+			depth--
+		}
+
+		if i == 0 || token.line != e.tokens[i-1].line {
+			if i > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(strings.Repeat(indent, depth))
+		} else {
+			b.WriteByte(' ')
+		}
+
+		if token.brace {
+			b.WriteString(token.text)
+		} else {
+			text, err := quoteToken(token.text)
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(text)
+		}
+
+		if token.brace && token.text == "{" {
+			depth++
+		}
+	}
+
+	if len(e.tokens) > 0 {
+		b.WriteByte('\n')
+	}
+
+	return b.Bytes(), nil
+}
+
+// quoteToken quotes the given token text if the Caddyfile lexer would not
+// read it back as a single token of the same text. An error is returned if
+// the lexer can't read back the text at all.
+func quoteToken(text string) (string, error) {
+	needsQuotes := text == "" ||
+		strings.HasPrefix(text, "{") || strings.HasPrefix(text, "}") ||
+		strings.HasPrefix(text, "#") ||
+		strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "`") ||
+		strings.ContainsAny(text, " \t\r\n\\")
+	if !needsQuotes {
+		return text, nil
+	}
+
+	// Backticks take everything literally, so prefer them over quotes.
+	if !strings.Contains(text, "`") {
+		return "`" + text + "`", nil
+	}
+
+	// Within quotes, a backslash before a quote escapes it, while any other
+	// backslash is kept along with the next rune. A run of backslashes before
+	// a quote or the closing quote must thus be of even length.
+	var backslashes int
+	for _, r := range text + `"` {
+		if r == '\\' {
+			backslashes++
+			continue
+		}
+		if r == '"' && backslashes%2 == 1 {
+			return "", fmt.Errorf(
+				"caddyunmarshal: cannot quote %q with an odd number of backslashes before a quote", text)
+		}
+		backslashes = 0
+	}
+
+	return `"` + strings.ReplaceAll(text, `"`, `\"`) + `"`, nil
+}
+
+// with returns a copy of head with the given texts appended.
+func with(head []string, texts ...string) []string {
+	return append(append([]string(nil), head...), texts...)
+}
+
+// marshalerOf returns the value as a CaddyfileMarshaler if it implements it.
+func marshalerOf(r reflectValue) (CaddyfileMarshaler, bool) {
+	if r.v.CanAddr() {
+		m, ok := r.v.Addr().Interface().(CaddyfileMarshaler)
+		return m, ok
+	}
+	m, ok := r.v.Interface().(CaddyfileMarshaler)
+	return m, ok
+}
+
+// isEmpty returns true if the given value is zero or an empty slice or map,
+// in which case optional arguments and subdirectives are left out.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+// addressable returns a settable copy of the given value, e.g. of a map
+// value, so that pointer methods may be called on it.
+func addressable(v reflect.Value) reflectValue {
+	copied := reflect.New(v.Type()).Elem()
+	copied.Set(v)
+	return reflectValue{copied, copied.Type()}
+}
+
+// line emits the line of a directive or subdirective, whose name and leading
+// arguments are given as head, followed by the given value. It is the
+// counterpart of unmarshalLine.
+func (e *encoder) line(head []string, r reflectValue, opts []string) error {
+	if isOptional(r.t) {
+		if !r.v.Interface().(interface{ IsSet() bool }).IsSet() {
+			return nil
+		}
+		if sentinel, ok := noneSentinel(opts); ok && r.v.Interface().(interface{ IsCleared() bool }).IsCleared() {
+			e.words(append(head, sentinel)...)
+			e.newline()
+			return nil
+		}
+		value, _ := unwrapOptional(r)
+		return e.line(head, value, opts)
+	}
+
+	if isSubroute(opts) {
+		return fmt.Errorf("cannot marshal subroutes")
+	}
+
+	if isStructPointer(r.t) || isScalarPointer(r.t) {
+		if r.v.IsNil() {
+			return nil
+		}
+		return e.line(head, reflectValue{r.v.Elem(), r.t.Elem()}, opts)
+	}
+
+	if m, ok := marshalerOf(r); ok {
+		tokens, err := m.MarshalCaddyfile()
+		if err != nil {
+			return err
+		}
+
+		e.words(head...)
+		e.custom(tokens)
+		e.newline()
+		return nil
+	}
+
+	switch {
+	case r.v.Kind() == reflect.Bool:
+		e.words(head...)
+		if !r.v.Bool() {
+			e.words("false")
+		}
+		e.newline()
+		return nil
+
+	case isCountFlag(r.t, opts):
+		var n uint64
+		if r.v.CanInt() {
+			if r.v.Int() > 0 {
+				n = uint64(r.v.Int())
+			}
+		} else {
+			n = r.v.Uint()
+		}
+
+		for i := uint64(0); i < n; i++ {
+			e.words(head...)
+			e.newline()
+		}
+		return nil
+
+	case hasVariants(r.t):
+		return e.variant(head, r)
+
+	case r.t == TypeSchemaless:
+		return e.schemaless(head, r.v.Interface().(map[string]any))
+
+	case r.v.Kind() == reflect.Slice && !isKVSlice(r.t) && !isScalar(r.t):
+		elemType := r.t.Elem()
+
+		if !isScalar(elemType) || isUnmarshaler(elemType) {
+			// Each element is its own occurrence of the subdirective.
+			for i := 0; i < r.v.Len(); i++ {
+				elem := reflectValue{r.v.Index(i), elemType}
+				if err := e.line(head, elem, opts); err != nil {
+					return fmt.Errorf("error at [%d]: %w", i, err)
+				}
+			}
+			return nil
+		}
+
+		e.words(head...)
+		if err := e.array(r, opts); err != nil {
+			return err
+		}
+		e.newline()
+		return nil
+
+	case r.v.Kind() == reflect.Array && !isEncodedArray(r.t, opts):
+		e.words(head...)
+		if err := e.array(r, opts); err != nil {
+			return err
+		}
+		e.newline()
+		return nil
+
+	case r.v.Kind() == reflect.Map && hasOpt(opts, "keyed"):
+		keys, err := e.mapKeys(r)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err := e.line(with(head, key.text), addressable(r.v.MapIndex(key.v)), nil); err != nil {
+				return fmt.Errorf("error at %q: %w", key.text, err)
+			}
+		}
+		return nil
+
+	case r.v.Kind() == reflect.Map || isKVSlice(r.t):
+		e.words(head...)
+		if r.v.Len() > 0 {
+			e.openBlock()
+			if err := e.block(r); err != nil {
+				return err
+			}
+			e.brace("}")
+		}
+		e.newline()
+		return nil
+
+	case r.v.Kind() == reflect.Struct && !isScalar(r.t):
+		return e.structLine(head, r)
+	}
+
+	e.words(head...)
+	if err := e.value(r, opts); err != nil {
+		return err
+	}
+	e.newline()
+	return nil
+}
+
+// structLine emits the line of a struct: its matcher, positional arguments
+// and blocks, then its own block of subdirectives.
+func (e *encoder) structLine(head []string, r reflectValue) error {
+	info, err := extractFields(r, e.options)
+	if err != nil {
+		return fmt.Errorf("cannot extract fields: %w", err)
+	}
+
+	e.words(head...)
+
+	if info.matcher != nil {
+		if err := e.matcher(info.matcher.value); err != nil {
+			return err
+		}
+	}
+
+	// Optional arguments are left out if they and all arguments after them
+	// are empty.
+	last := -1
+	for i, field := range info.otherFields {
+		if !field.optional() || !e.omitted(field) {
+			last = i
+		}
+	}
+
+	start := len(e.tokens)
+	for i, field := range info.otherFields[:last+1] {
+		switch kind := field.kind.(type) {
+		case argumentKind:
+			switch {
+			case kind.matcher:
+				err = e.matcher(field.value)
+			case field.value.v.Kind() == reflect.Array && !isEncodedArray(field.value.t, field.opts),
+				isVariadic(field):
+				err = e.array(field.value, field.opts)
+			default:
+				err = e.value(field.value, field.opts)
+			}
+		case blockKind:
+			if isSubroute(field.opts) {
+				return fmt.Errorf("error at [%d]: cannot marshal subroutes", i)
+			}
+			if isVariadicBlock(field) {
+				err = e.blocks(field.value)
+				break
+			}
+			e.openBlock()
+			err = e.block(field.value)
+			e.brace("}")
+		}
+		if err != nil {
+			return fmt.Errorf("error at [%d]: %w", i, err)
+		}
+	}
+
+	if isFlags(info) {
+		e.endFlags(start)
+	}
+
+	if err := e.ownBlock(info); err != nil {
+		return err
+	}
+
+	e.newline()
+	return nil
+}
+
+// endFlags inserts a bare -- before the arguments emitted since the token at
+// start if any of them starts with --, so that they aren't taken as flags.
+func (e *encoder) endFlags(start int) {
+	for _, token := range e.tokens[start:] {
+		if token.brace {
+			return
+		}
+		if strings.HasPrefix(token.text, "--") {
+			end := encodedToken{text: "--", line: e.tokens[start].line}
+			e.tokens = append(e.tokens[:start], append([]encodedToken{end}, e.tokens[start:]...)...)
+			return
+		}
+	}
+}
+
+// ownBlock emits the block of subdirectives of a struct, unless they're all
+// empty.
+func (e *encoder) ownBlock(info structInfo) error {
+	fields := e.blockFields(info)
+	if len(fields) == 0 && !hasRemaining(info) {
+		return nil
+	}
+
+	e.openBlock()
+	if err := e.subdirectives(fields); err != nil {
+		return err
+	}
+	e.remain(info)
+	e.extras(info)
+	e.brace("}")
+	return nil
+}
+
+// blockFields returns the block fields of the struct that aren't omitted, in
+// the order they're emitted in.
+func (e *encoder) blockFields(info structInfo) []fieldInfo {
+	var fields []fieldInfo
+	for _, field := range info.blockFields {
+		if !e.omitted(field) {
+			fields = append(fields, field)
+		}
+	}
+
+	if e.marshal.Sort {
+		sort.SliceStable(fields, func(i, j int) bool {
+			return fields[i].kind.(blockFieldKind).name < fields[j].kind.(blockFieldKind).name
+		})
+	}
+
+	return fields
+}
+
+// omitted returns true if the optional field is left out, since it's empty
+// or, with OmitDefaults, the same as its default.
+func (e *encoder) omitted(field fieldInfo) bool {
+	if isEmpty(field.value.v) {
+		return true
+	}
+
+	def, ok := optValue(field.opts, "default")
+	if !e.marshal.OmitDefaults || !ok || !isScalar(field.value.t) {
+		return false
+	}
+
+	// Compare the parsed default, since e.g. 1m and 60s are the same.
+	d := dispenser{Dispenser: caddyfile.NewDispenser([]caddyfile.Token{{Text: def}})}
+	d.Next()
+
+	parsed := reflect.New(field.value.t).Elem()
+	if err := unmarshalValue(d, reflectValue{parsed, parsed.Type()}, def, field.opts); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(parsed.Interface(), field.value.v.Interface())
+}
+
+// subdirectives emits a line for each of the given block fields.
+func (e *encoder) subdirectives(fields []fieldInfo) error {
+	for _, field := range fields {
+		name := field.kind.(blockFieldKind).name
+		if name == "@" {
+			return fmt.Errorf("cannot marshal named matcher definitions")
+		}
+
+		if err := e.line([]string{name}, field.value, field.opts); err != nil {
+			return fmt.Errorf("error at %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// block emits the contents of a block, which is either a struct of
+// subdirectives, a map or a []KV. It is the counterpart of unmarshalBlock.
+func (e *encoder) block(r reflectValue) error {
+	switch {
+	case r.t == TypeSchemaless:
+		return e.schemalessBlock(r.v.Interface().(map[string]any))
+
+	case isKVSlice(r.t):
+		for i := 0; i < r.v.Len(); i++ {
+			elem := r.v.Index(i)
+			key := elem.Field(0).String()
+			if err := e.line([]string{key}, reflectValue{elem.Field(1), elem.Field(1).Type()}, nil); err != nil {
+				return fmt.Errorf("error at %q: %w", key, err)
+			}
+		}
+		return nil
+
+	case r.v.Kind() == reflect.Map:
+		keys, err := e.mapKeys(r)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err := e.line([]string{key.text}, addressable(r.v.MapIndex(key.v)), nil); err != nil {
+				return fmt.Errorf("error at %q: %w", key.text, err)
+			}
+		}
+		return nil
+
+	case r.v.Kind() == reflect.Struct:
+		info, err := extractFields(r, e.options)
+		if err != nil {
+			return fmt.Errorf("cannot extract fields: %w", err)
+		}
+
+		if err := e.subdirectives(e.blockFields(info)); err != nil {
+			return err
+		}
+		e.remain(info)
+		e.extras(info)
+		return nil
+	}
+
+	return fmt.Errorf("cannot marshal block of %w %s", ErrUnsupportedType, r.t)
+}
+
+// blocks emits a block for each element of the slice of a variadic block
+// field, each following the closing brace of the previous one.
+func (e *encoder) blocks(r reflectValue) error {
+	for i := 0; i < r.v.Len(); i++ {
+		e.openBlock()
+		if err := e.block(addressable(r.v.Index(i))); err != nil {
+			return fmt.Errorf("error at block [%d]: %w", i, err)
+		}
+		e.brace("}")
+	}
+	return nil
+}
+
+type mapKey struct {
+	v    reflect.Value
+	text string
+}
+
+// mapKeys returns the keys of the given map along with their marshaled
+// text, sorted by the text.
+func (e *encoder) mapKeys(r reflectValue) ([]mapKey, error) {
+	keys := make([]mapKey, 0, r.v.Len())
+
+	for _, key := range r.v.MapKeys() {
+		texts, err := e.valueTexts(addressable(key), nil)
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal map key: %w", err)
+		}
+		keys = append(keys, mapKey{key, strings.Join(texts, " ")})
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].text < keys[j].text })
+	return keys, nil
+}
+
+// variant emits the name of the variant held by the interface value, followed
+// by the variant itself.
+func (e *encoder) variant(head []string, r reflectValue) error {
+	if r.v.IsNil() {
+		return fmt.Errorf("cannot marshal nil variant of %s", r.t)
+	}
+
+	impl := r.v.Elem()
+	name, ok := variantName(r.t, impl.Type())
+	if !ok {
+		return fmt.Errorf("%s is not a registered variant of %s", impl.Type(), r.t)
+	}
+
+	if impl.Kind() == reflect.Pointer {
+		impl = impl.Elem()
+	}
+
+	return e.line(with(head, name), addressable(impl), nil)
+}
+
+// schemaless emits a schemaless value, see TypeSchemaless.
+func (e *encoder) schemaless(head []string, m map[string]any) error {
+	e.words(head...)
+
+	args, _ := m[argsKey].([]any)
+	for _, arg := range args {
+		e.words(fmt.Sprint(arg))
+	}
+
+	_, hasArgs := m[argsKey]
+	if hasArgs && len(m) > 1 || !hasArgs && len(m) > 0 {
+		e.openBlock()
+		if err := e.schemalessBlock(m); err != nil {
+			return err
+		}
+		e.brace("}")
+	}
+
+	e.newline()
+	return nil
+}
+
+// schemalessBlock emits the subdirectives of a schemaless value, sorted by
+// their names.
+func (e *encoder) schemalessBlock(m map[string]any) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		if key != argsKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch value := m[key].(type) {
+		case []any:
+			e.words(key)
+			for _, arg := range value {
+				e.words(fmt.Sprint(arg))
+			}
+			e.newline()
+		case map[string]any:
+			if err := e.schemaless([]string{key}, value); err != nil {
+				return fmt.Errorf("error at %q: %w", key, err)
+			}
+		default:
+			return fmt.Errorf("error at %q: cannot marshal schemaless value of type %T", key, value)
+		}
+	}
+
+	return nil
+}
+
+// matcher emits the matcher token of the given matcher field, if it's set.
+// Only single path matchers have a token form.
+func (e *encoder) matcher(r reflectValue) error {
+	if isEmpty(r.v) {
+		return nil
+	}
+
+	var set caddy.ModuleMap
+	switch matcher := r.v.Interface().(type) {
+	case caddy.ModuleMap:
+		set = matcher
+	case caddyhttp.RawMatcherSets:
+		if len(matcher) == 1 {
+			set = matcher[0]
+		}
+	case caddyhttp.MatcherSet:
+		if len(matcher) == 1 {
+			if path, ok := matcher[0].(*caddyhttp.MatchPath); ok {
+				set = caddy.ModuleMap{"path": caddyconfig.JSON(path, nil)}
+			}
+		}
+	}
+
+	var paths []string
+	if len(set) == 1 {
+		// Anything but a path matcher leaves paths empty.
+		_ = json.Unmarshal(set["path"], &paths)
+	}
+	if len(paths) != 1 {
+		return fmt.Errorf("cannot marshal matcher: only single path matchers can be given as a token")
+	}
+
+	e.words(paths[0])
+	return nil
+}
+
+// array emits the elements of a fixed-size array or a slice of scalars as
+// separate arguments, or as a single argument for slices with the split
+// option.
+func (e *encoder) array(r reflectValue, opts []string) error {
+	sep, split := splitSeparator(opts)
+	split = split && r.t.Kind() == reflect.Slice
+
+	var joined []string
+	for i := 0; i < r.v.Len(); i++ {
+		texts, err := e.valueTexts(reflectValue{r.v.Index(i), r.t.Elem()}, opts)
+		if err != nil {
+			return fmt.Errorf("error at [%d]: %w", i, err)
+		}
+
+		if split {
+			joined = append(joined, texts...)
+		} else {
+			e.words(texts...)
+		}
+	}
+
+	if len(joined) > 0 {
+		e.words(strings.Join(joined, sep))
+	}
+	return nil
+}
+
+// value emits the argument of a scalar value.
+func (e *encoder) value(r reflectValue, opts []string) error {
+	texts, err := e.valueTexts(r, opts)
+	if err != nil {
+		return err
+	}
+
+	e.words(texts...)
+	return nil
+}
+
+// valueTexts marshals a scalar value into the text of its argument. It is the
+// counterpart of unmarshalValue.
+func (e *encoder) valueTexts(r reflectValue, opts []string) ([]string, error) {
+	if isOptional(r.t) {
+		r, _ = unwrapOptional(r)
+	}
+
+	if isScalarPointer(r.t) {
+		if r.v.IsNil() {
+			// Positional arguments can't be left out, so the sentinel
+			// stands in for nil if there is one.
+			sentinel, _ := noneSentinel(opts)
+			return []string{sentinel}, nil
+		}
+		r = reflectValue{r.v.Elem(), r.t.Elem()}
+	}
+
+	if isSecret(opts) && !e.marshal.Secrets {
+		return []string{Redacted}, nil
+	}
+
+	if m, ok := marshalerOf(r); ok {
+		tokens, err := m.MarshalCaddyfile()
+		if err != nil {
+			return nil, err
+		}
+
+		texts := make([]string, len(tokens))
+		for i, token := range tokens {
+			texts[i] = token.Text
+		}
+		return texts, nil
+	}
+
+	text, err := valueText(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	return []string{text}, nil
+}
+
+func valueText(r reflectValue, opts []string) (string, error) {
+	switch {
+	case r.t.AssignableTo(TypeCaddyAddress):
+		return addressText(r.v.Interface().(httpcaddyfile.Address)), nil
+
+	case r.t.AssignableTo(TypeCaddyNetworkAddress):
+		return r.v.Interface().(caddy.NetworkAddress).String(), nil
+
+	case r.t.AssignableTo(TypeCaddyDuration), r.t.AssignableTo(TypeDuration):
+		return time.Duration(r.v.Int()).String(), nil
+
+	case r.t.AssignableTo(TypeTLSCurve), r.t.AssignableTo(TypeTLSPublicKeyAlgorithm):
+		return formatTLSValue(r)
+
+	case r.t.AssignableTo(TypeSchedule):
+		return r.v.Interface().(Schedule).String(), nil
+
+	case r.t.AssignableTo(TypeLocation):
+		return r.v.Interface().(*time.Location).String(), nil
+
+	case isEncodedArray(r.t, opts):
+		b := make([]byte, r.t.Len())
+		reflect.Copy(reflect.ValueOf(b), r.v)
+		return encodeBytes(b, opts)
+
+	case r.t.AssignableTo(TypeBytes):
+		return encodeBytes(r.v.Bytes(), opts)
+
+	case isAny(r.t):
+		if r.v.IsNil() {
+			return "", nil
+		}
+		return valueText(addressable(r.v.Elem()), opts)
+	}
+
+	switch r.t.Kind() {
+	case reflect.String:
+		return r.v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(r.v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(r.v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		if scale, ok, err := percentScale(opts); ok {
+			if err != nil {
+				return "", err
+			}
+			return formatPercent(r.v.Float(), scale, r.t.Bits()), nil
+		}
+		return strconv.FormatFloat(r.v.Float(), 'g', -1, r.t.Bits()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(r.v.Bool()), nil
+	}
+
+	if r.v.CanAddr() {
+		if m, ok := r.v.Addr().Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			return string(text), err
+		}
+	}
+
+	return "", fmt.Errorf("cannot marshal value of %w %s", ErrUnsupportedType, r.t)
+}
+
+// addressText formats the address such that httpcaddyfile.ParseAddress gives
+// it back. Unlike Address.String, no scheme or port is filled in.
+func addressText(a httpcaddyfile.Address) string {
+	var s string
+	if a.Scheme != "" {
+		s = a.Scheme + "://"
+	}
+
+	switch {
+	case a.Port != "":
+		s += net.JoinHostPort(a.Host, a.Port)
+	case strings.Contains(a.Host, ":"):
+		s += "[" + a.Host + "]"
+	default:
+		s += a.Host
+	}
+
+	return s + a.Path
+}
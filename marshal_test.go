@@ -0,0 +1,338 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type marshalHealth struct {
+	URI      string         `caddyfile:"$1"`
+	Interval caddy.Duration `caddyfile:"interval"`
+}
+
+type marshalProxy struct {
+	To       string            `caddyfile:"$1"`
+	Port     int               `caddyfile:"$2,optional"`
+	Policy   string            `caddyfile:"lb_policy"`
+	Timeout  time.Duration     `caddyfile:"timeout"`
+	Hosts    []string          `caddyfile:"hosts"`
+	Health   []marshalHealth   `caddyfile:"health"`
+	Header   map[string]string `caddyfile:"header"`
+	Verbose  int               `caddyfile:"verbose,flag"`
+	Insecure bool              `caddyfile:"insecure"`
+	Password string            `caddyfile:"password,secret"`
+	Note     string            `caddyfile:"note"`
+}
+
+func TestMarshal(t *testing.T) {
+	v := marshalProxy{
+		To:      "localhost",
+		Policy:  "random",
+		Timeout: 90 * time.Second,
+		Hosts:   []string{"a", "b"},
+		Health: []marshalHealth{
+			{URI: "/health", Interval: caddy.Duration(time.Minute)},
+			{URI: "/ready"},
+		},
+		Header:   map[string]string{"X-B": "b", "X-A": "a b"},
+		Verbose:  2,
+		Insecure: true,
+		Password: "hunter2",
+		Note:     `say "hi"`,
+	}
+
+	b, err := Marshal("proxy", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "proxy localhost {\n" +
+		"\tlb_policy random\n" +
+		"\ttimeout 1m30s\n" +
+		"\thosts a b\n" +
+		"\thealth /health {\n" +
+		"\t\tinterval 1m0s\n" +
+		"\t}\n" +
+		"\thealth /ready\n" +
+		"\theader {\n" +
+		"\t\tX-A `a b`\n" +
+		"\t\tX-B b\n" +
+		"\t}\n" +
+		"\tverbose\n" +
+		"\tverbose\n" +
+		"\tinsecure\n" +
+		"\tpassword REDACTED\n" +
+		"\tnote `say \"hi\"`\n" +
+		"}\n"
+	if string(b) != expect {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", b, expect)
+	}
+
+	got, err := unmarshalString[marshalProxy](string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v.Password = Redacted
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("unexpected value after unmarshaling:\ngot  %+v\nwant %+v", got, v)
+	}
+}
+
+func TestMarshalOptionalArguments(t *testing.T) {
+	type upstream struct {
+		Host string `caddyfile:"$1,optional"`
+		Port int    `caddyfile:"$2,optional"`
+	}
+
+	tests := []struct {
+		value  upstream
+		expect string
+	}{
+		{upstream{}, "upstream\n"},
+		{upstream{Host: "a"}, "upstream a\n"},
+		{upstream{Port: 80}, "upstream `` 80\n"},
+	}
+
+	for _, test := range tests {
+		b, err := Marshal("upstream", &test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != test.expect {
+			t.Errorf("unexpected output for %+v: %q", test.value, b)
+		}
+	}
+}
+
+type marshalColor struct{ r, g, b uint8 }
+
+func (c marshalColor) MarshalCaddyfile() ([]caddyfile.Token, error) {
+	return []caddyfile.Token{
+		{Line: 1, Text: "rgb"},
+		{Line: 1, Text: "{"},
+		{Line: 2, Text: "r"}, {Line: 2, Text: "255"},
+		{Line: 3, Text: "}"},
+	}, nil
+}
+
+func (c *marshalColor) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	return nil
+}
+
+func TestMarshalCaddyfileMarshaler(t *testing.T) {
+	type theme struct {
+		Name       string       `caddyfile:"$1"`
+		Background marshalColor `caddyfile:"background"`
+	}
+
+	v := theme{Name: "dark", Background: marshalColor{r: 255}}
+	b, err := Marshal("theme", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "theme dark {\n" +
+		"\tbackground rgb {\n" +
+		"\t\tr 255\n" +
+		"\t}\n" +
+		"}\n"
+	if string(b) != expect {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", b, expect)
+	}
+}
+
+func TestMarshalUnsupported(t *testing.T) {
+	type unsupported struct {
+		Ch chan int `caddyfile:"$1"`
+	}
+
+	_, err := Marshal("unsupported", &unsupported{})
+	if err == nil || !strings.Contains(err.Error(), "cannot marshal value of unsupported type chan int") {
+		t.Errorf("expected unsupported type error, got %v", err)
+	}
+}
+
+func TestMarshalWithOptions(t *testing.T) {
+	type health struct {
+		Path     string         `caddyfile:"path,default=/"`
+		Interval caddy.Duration `caddyfile:"interval,default=1m"`
+		Fails    int            `caddyfile:"fails,default=1"`
+	}
+
+	v := health{Path: "/health", Interval: caddy.Duration(time.Minute), Fails: 3}
+
+	tests := []struct {
+		name   string
+		opts   MarshalOptions
+		expect string
+	}{
+		{
+			"default",
+			MarshalOptions{},
+			"health {\n\tpath /health\n\tinterval 1m0s\n\tfails 3\n}\n",
+		},
+		{
+			"sorted",
+			MarshalOptions{Sort: true},
+			"health {\n\tfails 3\n\tinterval 1m0s\n\tpath /health\n}\n",
+		},
+		{
+			"omit defaults",
+			MarshalOptions{OmitDefaults: true},
+			"health {\n\tpath /health\n\tfails 3\n}\n",
+		},
+		{
+			"indent",
+			MarshalOptions{Indent: "  "},
+			"health {\n  path /health\n  interval 1m0s\n  fails 3\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := MarshalWithOptions("health", &v, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != test.expect {
+				t.Errorf("unexpected output:\n%s\nwant:\n%s", b, test.expect)
+			}
+		})
+	}
+}
+
+func TestMarshalTagKey(t *testing.T) {
+	type server struct {
+		Host    string `cfg:"$1" caddyfile:"host"`
+		MaxConn int    `cfg:"max_conns" caddyfile:"-"`
+	}
+
+	v := server{Host: "a", MaxConn: 5}
+
+	b, err := MarshalWithOptions("server", &v, MarshalOptions{TagKey: "cfg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "server a {\n\tmax_conns 5\n}\n"
+	if string(b) != expect {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", b, expect)
+	}
+}
+
+func TestMarshalFieldName(t *testing.T) {
+	type server struct {
+		Host    string `caddyfile:"$1"`
+		MaxConn int
+	}
+
+	v := server{Host: "a", MaxConn: 5}
+
+	b, err := MarshalWithOptions("server", &v, MarshalOptions{FieldName: strings.ToLower})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "server a {\n\tmaxconn 5\n}\n"
+	if string(b) != expect {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", b, expect)
+	}
+
+	got, err := unmarshalStringWithOptions[server](string(b), Options{FieldName: strings.ToLower})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != v {
+		t.Errorf("expected %+v, got %+v", v, got)
+	}
+}
+
+func TestMarshalFormatted(t *testing.T) {
+	v := marshalProxy{
+		To:     "localhost",
+		Port:   8080,
+		Health: []marshalHealth{{URI: "/health", Interval: caddy.Duration(time.Minute)}},
+		Header: map[string]string{"X-A": "a b"},
+	}
+
+	b, err := Marshal("proxy", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if formatted := caddyfile.Format(b); string(formatted) != string(b) {
+		t.Errorf("output changed by caddy fmt:\n%s\nformatted:\n%s", b, formatted)
+	}
+}
+
+func TestMarshalMultilineValues(t *testing.T) {
+	type tls struct {
+		Cert     string `caddyfile:"cert"`
+		Key      []byte `caddyfile:"key"`
+		Template string `caddyfile:"template"`
+	}
+
+	const pem = "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+
+	v, err := unmarshalString[tls]("tls {\n cert `" + pem + "`\n key \"" + pem + "\"\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Cert != pem || string(v.Key) != pem {
+		t.Fatalf("multi-line values were not kept intact: %q, %q", v.Cert, v.Key)
+	}
+
+	v.Template = "{{ .Name }} uses `backticks`\n\tand \"quotes\""
+
+	b, err := Marshal("tls", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := unmarshalString[tls](string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("unexpected value after unmarshaling:\n%s\ngot  %q\nwant %q", b, got, v)
+	}
+}
+
+func TestMarshalQuoting(t *testing.T) {
+	type quoted struct {
+		A string `caddyfile:"$1"`
+		B string `caddyfile:"b"`
+	}
+
+	for _, v := range []quoted{
+		{A: "{}", B: "{}"},
+		{A: "}x", B: "{placeholder}"},
+		{A: "a`b\\\\", B: "a`b\\\\\"c\\d"},
+	} {
+		b, err := Marshal("d", &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := unmarshalString[quoted](string(b))
+		if err != nil {
+			t.Fatalf("cannot unmarshal %q: %v", b, err)
+		}
+		if got != v {
+			t.Errorf("unexpected value after unmarshaling:\n%s\ngot  %q\nwant %q", b, got, v)
+		}
+	}
+
+	// The lexer can't read back an odd run of backslashes before the closing
+	// quote, which is needed because of the backtick.
+	v := quoted{A: "a`b\\", B: "x"}
+	if b, err := Marshal("d", &v); err == nil {
+		t.Errorf("expected error for unquotable text, got %q", b)
+	}
+}
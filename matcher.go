@@ -0,0 +1,221 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+// TypeMatcherDefinitions is the type of the field that collects named matcher
+// definitions (e.g. "@api path /api/*") within a block. The field must be
+// tagged with `caddyfile:"@"`. Matchers are keyed by their name, including
+// the @ prefix.
+var TypeMatcherDefinitions = reflect.TypeOf(map[string]caddy.ModuleMap{})
+
+// Matcher field types besides TypeCaddyModuleMap. A RawMatcherSets holds the
+// single matcher set given, while a MatcherSet holds the decoded matchers,
+// which are NOT provisioned; callers must provision them if needed.
+var (
+	TypeRawMatcherSets = reflect.TypeOf(caddyhttp.RawMatcherSets(nil))
+	TypeMatcherSet     = reflect.TypeOf(caddyhttp.MatcherSet(nil))
+)
+
+// isMatcherType returns true if the given type can hold a matcher.
+func isMatcherType(t reflect.Type) bool {
+	return t == TypeCaddyModuleMap || t == TypeRawMatcherSets || t == TypeMatcherSet
+}
+
+// setMatcher sets the matcher field to the given matcher set, converting it
+// to the field's type. A nil set matches all requests and leaves the field
+// nil.
+func setMatcher(r reflectValue, set caddy.ModuleMap) error {
+	if set == nil {
+		return nil
+	}
+
+	switch r.t {
+	case TypeRawMatcherSets:
+		r.v.Set(reflect.ValueOf(caddyhttp.RawMatcherSets{set}))
+	case TypeMatcherSet:
+		matchers, err := decodeMatcherSet(set)
+		if err != nil {
+			return err
+		}
+		r.v.Set(reflect.ValueOf(matchers))
+	default:
+		r.v.Set(reflect.ValueOf(set))
+	}
+
+	return nil
+}
+
+// decodeMatcherSet decodes each matcher module of the given set, ordered by
+// their names.
+func decodeMatcherSet(set caddy.ModuleMap) (caddyhttp.MatcherSet, error) {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	matchers := make(caddyhttp.MatcherSet, 0, len(names))
+	for _, name := range names {
+		mod, err := caddy.GetModule("http.matchers." + name)
+		if err != nil {
+			return nil, fmt.Errorf("getting matcher module '%s': %v", name, err)
+		}
+
+		val := mod.New()
+		if err := json.Unmarshal(set[name], val); err != nil {
+			return nil, fmt.Errorf("decoding matcher module '%s': %w", name, err)
+		}
+
+		matcher, ok := val.(caddyhttp.RequestMatcher)
+		if !ok {
+			return nil, fmt.Errorf("matcher module '%s' is not a request matcher", name)
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	return matchers, nil
+}
+
+// isMatcherToken returns true if the given token would be taken as a matcher
+// by httpcaddyfile, i.e. it is *, a path or a named matcher.
+func isMatcherToken(token string) bool {
+	return token == "*" || strings.HasPrefix(token, "/") || strings.HasPrefix(token, "@")
+}
+
+// matcherToken consumes the next argument into the given matcher field if it
+// is a matcher token. It returns false without consuming anything otherwise.
+func (d dispenser) matcherToken(field fieldInfo) (bool, error) {
+	// If we expect a matcher, then the user MUST have called
+	// UnmarshalForHTTP, because we need the httpcaddyfile.Helper instance.
+	if d.http == nil {
+		return false, fmt.Errorf("cannot unmarshal matcher: UnmarshalForHTTP was not called")
+	}
+
+	r := field.value
+	if !isMatcherType(r.t) {
+		return false, fmt.Errorf(
+			"cannot unmarshal matcher: expected caddy.ModuleMap, caddyhttp.RawMatcherSets or caddyhttp.MatcherSet, got %s", r.t)
+	}
+
+	// MatcherToken consumes the argument even if it is not a matcher, so
+	// check it ourselves first.
+	if !isMatcherToken(d.peekArg()) {
+		return false, nil
+	}
+
+	moduleMap, ok, err := d.http.MatcherToken()
+	if err != nil {
+		return false, fmt.Errorf("cannot get module map: %w", err)
+	}
+
+	if ok {
+		// We matched a matcher, so we can set the value.
+		d.trace("matcher", &field)
+		if err := setMatcher(r, moduleMap); err != nil {
+			return false, d.WrapErr(err)
+		}
+	}
+
+	return ok, nil
+}
+
+// unmarshalMatcherDefinition parses the named matcher definition at the
+// cursor into the given map[string]caddy.ModuleMap.
+func unmarshalMatcherDefinition(d dispenser, r reflectValue) error {
+	if !r.t.AssignableTo(TypeMatcherDefinitions) {
+		return fmt.Errorf(
+			"cannot unmarshal matcher definitions: expected map[string]caddy.ModuleMap, got %s", r.t)
+	}
+
+	if r.v.IsNil() {
+		r.v.Set(reflect.MakeMap(r.t))
+	}
+
+	matchers := r.v.Interface().(map[string]caddy.ModuleMap)
+	if err := parseMatcherDefinitions(caddyfile.NewDispenser(d.NextSegment()), matchers); err != nil {
+		return d.WrapErr(err)
+	}
+
+	return nil
+}
+
+// parseMatcherDefinitions is a copy of the unexported httpcaddyfile function
+// of the same name.
+func parseMatcherDefinitions(d *caddyfile.Dispenser, matchers map[string]caddy.ModuleMap) error {
+	for d.Next() {
+		// this is the "name" for "named matchers"
+		definitionName := d.Val()
+
+		if _, ok := matchers[definitionName]; ok {
+			return fmt.Errorf("matcher is defined more than once: %s", definitionName)
+		}
+		matchers[definitionName] = make(caddy.ModuleMap)
+
+		// given a matcher name and the tokens following it, parse
+		// the tokens as a matcher module and record it
+		makeMatcher := func(matcherName string, tokens []caddyfile.Token) error {
+			mod, err := caddy.GetModule("http.matchers." + matcherName)
+			if err != nil {
+				return fmt.Errorf("getting matcher module '%s': %v", matcherName, err)
+			}
+			unm, ok := mod.New().(caddyfile.Unmarshaler)
+			if !ok {
+				return fmt.Errorf("matcher module '%s' is not a Caddyfile unmarshaler", matcherName)
+			}
+			err = unm.UnmarshalCaddyfile(caddyfile.NewDispenser(tokens))
+			if err != nil {
+				return err
+			}
+			rm, ok := unm.(caddyhttp.RequestMatcher)
+			if !ok {
+				return fmt.Errorf("matcher module '%s' is not a request matcher", matcherName)
+			}
+			matchers[definitionName][matcherName] = caddyconfig.JSON(rm, nil)
+			return nil
+		}
+
+		// if the next token is quoted, we can assume it's not a matcher name
+		// and that it's probably an 'expression' matcher
+		if d.NextArg() {
+			if d.Token().Quoted() {
+				err := makeMatcher("expression", []caddyfile.Token{d.Token()})
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			// if it wasn't quoted, then we need to rewind after calling
+			// d.NextArg() so the below properly grabs the matcher name
+			d.Prev()
+		}
+
+		// in case there are multiple instances of the same matcher, concatenate
+		// their tokens (we expect that UnmarshalCaddyfile should be able to
+		// handle more than one segment); otherwise, we'd overwrite other
+		// instances of the matcher in this set
+		tokensByMatcherName := make(map[string][]caddyfile.Token)
+		for nesting := d.Nesting(); d.NextArg() || d.NextBlock(nesting); {
+			matcherName := d.Val()
+			tokensByMatcherName[matcherName] = append(tokensByMatcherName[matcherName], d.NextSegment()...)
+		}
+		for matcherName, tokens := range tokensByMatcherName {
+			err := makeMatcher(matcherName, tokens)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+func TestUnmarshalMatcherDefinitions(t *testing.T) {
+	type router struct {
+		Matchers map[string]caddy.ModuleMap `caddyfile:"@"`
+		Default  string                     `caddyfile:"default"`
+	}
+
+	v, err := unmarshalString[router](`
+		router {
+			@api {
+				path /api/*
+				method GET
+			}
+			@static path /static/*
+			default /index.html
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Default != "/index.html" {
+		t.Errorf("unexpected default: %q", v.Default)
+	}
+
+	if len(v.Matchers) != 2 {
+		t.Fatalf("unexpected matchers: %v", v.Matchers)
+	}
+
+	var paths []string
+	if err := json.Unmarshal(v.Matchers["@api"]["path"], &paths); err != nil || paths[0] != "/api/*" {
+		t.Errorf("unexpected @api path matcher: %s", v.Matchers["@api"]["path"])
+	}
+	if _, ok := v.Matchers["@api"]["method"]; !ok {
+		t.Errorf("missing @api method matcher")
+	}
+	if _, ok := v.Matchers["@static"]["path"]; !ok {
+		t.Errorf("missing @static path matcher")
+	}
+}
+
+func TestUnmarshalMatcherDefinitionsUnsupported(t *testing.T) {
+	_, err := unmarshalString[thing2]("thing2 a {\n @api path /api/*\n}")
+	if err == nil || !strings.Contains(err.Error(), "named matcher definition @api") {
+		t.Errorf("expected unsupported matcher definition error, got %v", err)
+	}
+}
+
+type positionalMatcher struct {
+	Name    string          `caddyfile:"$1"`
+	Matcher caddy.ModuleMap `caddyfile:"$2,matcher"`
+	Status  int             `caddyfile:"$3"`
+}
+
+func unmarshalHTTPString[T any](input string) (T, error) {
+	tokens, err := caddyfile.Tokenize([]byte(input), "Testfile")
+	if err != nil {
+		var z T
+		return z, err
+	}
+
+	d := caddyfile.NewDispenser(tokens)
+	d.Next()
+
+	var v T
+	err = UnmarshalForHTTP(&httpcaddyfile.Helper{Dispenser: d}, &v)
+	return v, err
+}
+
+func TestUnmarshalPositionalMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    positionalMatcher
+		matcher string
+	}{
+		{
+			name:  "without matcher",
+			input: "respond foo 200",
+			want:  positionalMatcher{Name: "foo", Status: 200},
+		},
+		{
+			name:    "path matcher",
+			input:   "respond foo /api/* 200",
+			want:    positionalMatcher{Name: "foo", Status: 200},
+			matcher: `{"path":["/api/*"]}`,
+		},
+		{
+			name:    "wildcard matcher",
+			input:   "respond foo * 404",
+			want:    positionalMatcher{Name: "foo", Status: 404},
+			matcher: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := unmarshalHTTPString[positionalMatcher](test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v.Name != test.want.Name || v.Status != test.want.Status {
+				t.Errorf("unexpected value: %+v", v)
+			}
+
+			var matcher string
+			if v.Matcher != nil {
+				matcher = mustJSON(v.Matcher)
+			}
+			if matcher != test.matcher {
+				t.Errorf("unexpected matcher: got %s, want %s", matcher, test.matcher)
+			}
+		})
+	}
+}
+
+func TestUnmarshalPositionalMatcherUnknown(t *testing.T) {
+	_, err := unmarshalHTTPString[positionalMatcher]("respond foo @api 200")
+	if err == nil || !strings.Contains(err.Error(), "unrecognized matcher name") {
+		t.Errorf("expected unrecognized matcher error, got %v", err)
+	}
+}
+
+func TestUnmarshalPositionalMatcherWithoutHTTP(t *testing.T) {
+	_, err := unmarshalString[positionalMatcher]("respond foo /api 200")
+	if err == nil || !strings.Contains(err.Error(), "UnmarshalForHTTP was not called") {
+		t.Errorf("expected UnmarshalForHTTP error, got %v", err)
+	}
+}
+
+func TestFirstMatcherNotConsumed(t *testing.T) {
+	v, err := unmarshalHTTPString[thing3]("thing3 foo bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Matcher != nil || v.Arg1 != "foo" || v.Arg2 != "bar" {
+		t.Errorf("unexpected value: %+v", v)
+	}
+}
+
+func TestUsagePositionalMatcher(t *testing.T) {
+	usage, err := Usage[positionalMatcher]("respond")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(usage, "respond <name> [<matcher>] <status>") {
+		t.Errorf("unexpected usage:\n%s", usage)
+	}
+}
+
+func TestUnmarshalMatcherSetTypes(t *testing.T) {
+	type rawSets struct {
+		Matcher caddyhttp.RawMatcherSets `caddyfile:"$matcher"`
+		Status  int                      `caddyfile:"$1"`
+	}
+
+	raw, err := unmarshalHTTPString[rawSets]("respond /api/* 200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mustJSON(raw.Matcher); got != `[{"path":["/api/*"]}]` {
+		t.Errorf("unexpected raw matcher sets: %s", got)
+	}
+
+	raw, err = unmarshalHTTPString[rawSets]("respond * 200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.Matcher != nil {
+		t.Errorf("expected nil matcher sets for *, got %s", mustJSON(raw.Matcher))
+	}
+
+	type decodedSet struct {
+		Matcher caddyhttp.MatcherSet `caddyfile:"$matcher"`
+		Status  int                  `caddyfile:"$1"`
+	}
+
+	decoded, err := unmarshalHTTPString[decodedSet]("respond /api/* 200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Matcher) != 1 {
+		t.Fatalf("unexpected matcher set: %#v", decoded.Matcher)
+	}
+	path, ok := decoded.Matcher[0].(*caddyhttp.MatchPath)
+	if !ok || len(*path) != 1 || (*path)[0] != "/api/*" {
+		t.Errorf("unexpected path matcher: %#v", decoded.Matcher[0])
+	}
+}
+
+func TestUnmarshalMatcherInvalidType(t *testing.T) {
+	type invalid struct {
+		Matcher string `caddyfile:"$matcher"`
+	}
+
+	_, err := unmarshalHTTPString[invalid]("respond /api")
+	if err == nil || !strings.Contains(err.Error(), "expected caddy.ModuleMap") {
+		t.Errorf("expected matcher type error, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// merge prepares the value of a field that is about to be given, so that
+// unmarshaling into a struct pre-populated with defaults merges the Caddyfile
+// into it, e.g.
+//
+//	v := defaults
+//	err := caddyunmarshal.Unmarshal(d, &v)
+//
+// Scalars given in the Caddyfile overwrite the defaults, and so do structs,
+// field by field. Maps merge, with the given entries overwriting the default
+// ones. Slices are appended to, unless Options.ReplaceSlices is set, in which
+// case they're replaced.
+//
+// Slice and map fields may override this using the append and replace
+// options, e.g.
+//
+//	Hosts []string `caddyfile:"hosts,replace"`
+//
+// Fields with the replace option are reset each time they're given, so that
+// the last of repeated subdirectives wins over the others and the defaults.
+// Fields with the append option are extended by repeated subdirectives and
+// the defaults, even with Options.ReplaceSlices.
+//
+// Maps and struct pointers are copied before they're modified, and slices
+// are clipped, so that defaults shared with v are left untouched. Given is
+// true if the field was already given within its struct, in which case only
+// fields with the replace option are prepared again.
+func (d dispenser) merge(field fieldInfo, given bool) {
+	replace := hasOpt(field.opts, "replace")
+	if given && !replace {
+		return
+	}
+
+	r := field.value
+
+	switch {
+	case isScalar(r.t):
+		// Scalars are set whole, even those that are slices or maps.
+
+	case r.v.Kind() == reflect.Slice:
+		switch {
+		case r.v.IsNil():
+		case replace, d.options.replaceSlices() && !hasOpt(field.opts, "append"):
+			r.v.Set(reflect.Zero(r.t))
+		default:
+			n := r.v.Len()
+			r.v.Set(r.v.Slice3(0, n, n))
+		}
+
+	case r.v.Kind() == reflect.Map:
+		if r.v.IsNil() {
+			break
+		}
+		if replace {
+			r.v.Set(reflect.Zero(r.t))
+			break
+		}
+
+		m := reflect.MakeMapWithSize(r.t, r.v.Len())
+		iter := r.v.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(iter.Key(), iter.Value())
+		}
+		r.v.Set(m)
+
+	case isStructPointer(r.t):
+		if r.v.IsNil() {
+			break
+		}
+
+		p := reflect.New(r.t.Elem())
+		p.Elem().Set(r.v.Elem())
+		r.v.Set(p)
+	}
+}
+
+// validateMerge validates the append and replace options of the field.
+func validateMerge(field fieldInfo) error {
+	appends, replaces := hasOpt(field.opts, "append"), hasOpt(field.opts, "replace")
+	if !appends && !replaces {
+		return nil
+	}
+
+	if appends && replaces {
+		return fmt.Errorf(
+			"caddyunmarshal: field %s cannot have both the append and replace options", field.field.Name)
+	}
+
+	switch t := field.value.t; {
+	case isScalar(t), t.Kind() != reflect.Slice && t.Kind() != reflect.Map:
+		return fmt.Errorf(
+			"caddyunmarshal: append and replace options of field %s require a slice or map, got %s", field.field.Name, t)
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type mergeTLS struct {
+	Protocols []string `caddyfile:"protocols"`
+	Insecure  bool     `caddyfile:"insecure"`
+}
+
+type mergeProxy struct {
+	To      []string          `caddyfile:"$1,optional"`
+	Timeout time.Duration     `caddyfile:"timeout"`
+	Retries int               `caddyfile:"retries"`
+	Headers map[string]string `caddyfile:"headers"`
+	TLS     *mergeTLS         `caddyfile:"tls"`
+}
+
+func newMergeDefaults() mergeProxy {
+	return mergeProxy{
+		To:      append(make([]string, 0, 4), "localhost:8080"),
+		Timeout: 30 * time.Second,
+		Retries: 3,
+		Headers: map[string]string{"X-Proxy": "caddy", "X-Env": "dev"},
+		TLS:     &mergeTLS{Protocols: []string{"tls1.2"}},
+	}
+}
+
+func TestUnmarshalMerge(t *testing.T) {
+	const input = "proxy localhost:9090 {\n" +
+		"\ttimeout 5s\n" +
+		"\theaders {\n" +
+		"\t\tX-Env prod\n" +
+		"\t}\n" +
+		"\ttls {\n" +
+		"\t\tinsecure\n" +
+		"\t}\n" +
+		"}"
+
+	defaults := newMergeDefaults()
+
+	v := defaults
+	d := caddyfile.NewTestDispenser(input)
+	d.Next()
+	if err := Unmarshal(d, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := mergeProxy{
+		To:      []string{"localhost:8080", "localhost:9090"},
+		Timeout: 5 * time.Second,
+		Retries: 3,
+		Headers: map[string]string{"X-Proxy": "caddy", "X-Env": "prod"},
+		TLS:     &mergeTLS{Protocols: []string{"tls1.2"}, Insecure: true},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("expected %+v, got %+v", expect, v)
+	}
+
+	if !reflect.DeepEqual(defaults, newMergeDefaults()) {
+		t.Errorf("expected defaults to be left untouched, got %+v", defaults)
+	}
+	if spare := defaults.To[:2][1]; spare != "" {
+		t.Errorf("expected appending not to write into the defaults, got %q", spare)
+	}
+}
+
+func TestUnmarshalMergeReplaceSlices(t *testing.T) {
+	v := newMergeDefaults()
+
+	d := caddyfile.NewTestDispenser("proxy localhost:9090 {\n\ttls {\n\t\tprotocols tls1.3\n\t}\n}")
+	d.Next()
+	if err := UnmarshalWithOptions(d, &v, Options{ReplaceSlices: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expect := []string{"localhost:9090"}; !reflect.DeepEqual(v.To, expect) {
+		t.Errorf("expected to %v, got %v", expect, v.To)
+	}
+	if expect := []string{"tls1.3"}; !reflect.DeepEqual(v.TLS.Protocols, expect) {
+		t.Errorf("expected protocols %v, got %v", expect, v.TLS.Protocols)
+	}
+
+	v = newMergeDefaults()
+
+	d = caddyfile.NewTestDispenser("proxy {\n\tretries 5\n}")
+	d.Next()
+	if err := UnmarshalWithOptions(d, &v, Options{ReplaceSlices: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expect := []string{"localhost:8080"}; !reflect.DeepEqual(v.To, expect) {
+		t.Errorf("expected slices that aren't given to be kept, got %v", v.To)
+	}
+}
+
+func TestUnmarshalAppendReplace(t *testing.T) {
+	type upstreams struct {
+		Hosts   []string          `caddyfile:"hosts,replace"`
+		Tags    []string          `caddyfile:"tags,append"`
+		Headers map[string]string `caddyfile:"headers,replace"`
+		Labels  map[string]string `caddyfile:"labels,append"`
+	}
+
+	const input = "upstreams {\n" +
+		"\thosts a b\n" +
+		"\thosts c\n" +
+		"\ttags x\n" +
+		"\ttags y\n" +
+		"\theaders {\n\t\tA 1\n\t}\n" +
+		"\theaders {\n\t\tB 2\n\t}\n" +
+		"\tlabels {\n\t\tenv prod\n\t}\n" +
+		"}"
+
+	for _, opts := range []Options{{}, {ReplaceSlices: true}} {
+		v := upstreams{
+			Hosts:   []string{"default"},
+			Tags:    []string{"default"},
+			Headers: map[string]string{"X": "0"},
+			Labels:  map[string]string{"team": "web"},
+		}
+
+		d := caddyfile.NewTestDispenser(input)
+		d.Next()
+		if err := UnmarshalWithOptions(d, &v, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		expect := upstreams{
+			Hosts:   []string{"c"},
+			Tags:    []string{"default", "x", "y"},
+			Headers: map[string]string{"B": "2"},
+			Labels:  map[string]string{"team": "web", "env": "prod"},
+		}
+		if !reflect.DeepEqual(v, expect) {
+			t.Errorf("with %+v: expected %+v, got %+v", opts, expect, v)
+		}
+	}
+}
+
+func TestUnmarshalAppendReplaceInvalid(t *testing.T) {
+	type both struct {
+		Hosts []string `caddyfile:"hosts,append,replace"`
+	}
+	_, err := unmarshalString[both]("upstreams")
+	if err == nil || !strings.Contains(err.Error(), "cannot have both the append and replace options") {
+		t.Errorf("expected conflicting options error, got %v", err)
+	}
+
+	type scalar struct {
+		Host string `caddyfile:"host,replace"`
+	}
+	_, err = unmarshalString[scalar]("upstreams")
+	if err == nil || !strings.Contains(err.Error(), "require a slice or map") {
+		t.Errorf("expected invalid type error, got %v", err)
+	}
+}
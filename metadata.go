@@ -0,0 +1,147 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// FieldTag is the parsed caddyfile tag of a struct field, as seen by
+// extensions registered using RegisterMetadata.
+type FieldTag struct {
+	// Field is the struct field.
+	Field reflect.StructField
+	// Name is the name of the subdirective, or the positional name of the
+	// field, such as $1, {1} or $matcher.
+	Name string
+	// Options are the tag options following the name.
+	Options []string
+}
+
+// Option returns the value of the given tag option, e.g. "text" for
+// `caddyfile:"name,widget=text"`.
+func (tag FieldTag) Option(name string) (string, bool) {
+	return optValue(tag.Options, name)
+}
+
+// HasOption returns true if the given tag option is present, with or without
+// a value.
+func (tag FieldTag) HasOption(name string) bool {
+	return hasOpt(tag.Options, name)
+}
+
+// MetadataFunc derives custom metadata from the tag of a field. It returns nil
+// if the field has no metadata.
+type MetadataFunc func(tag FieldTag) any
+
+var (
+	metadataMu    sync.RWMutex
+	metadataFuncs = map[string]MetadataFunc{}
+	metadataCache sync.Map // metadataKey -> map[string]any
+)
+
+type metadataKey struct {
+	t      reflect.Type
+	key    string
+	tagKey string
+}
+
+// RegisterMetadata registers a function deriving the metadata stored under the
+// given key, which is then retrieved using MetadataOf. This allows other
+// libraries to attach their own information to fields, such as UI hints, from
+// the same tags that the Caddyfile syntax is described with:
+//
+//	RegisterMetadata("widget", func(tag FieldTag) any {
+//		widget, _ := tag.Option("widget")
+//		return widget
+//	})
+//
+// RegisterMetadata panics if the key is already registered.
+func RegisterMetadata(key string, fn MetadataFunc) {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+
+	if _, ok := metadataFuncs[key]; ok {
+		panic(fmt.Sprintf("caddyunmarshal: metadata %q already registered", key))
+	}
+
+	metadataFuncs[key] = fn
+}
+
+// MetadataOf returns the metadata stored under the given key for each field of
+// the struct T, keyed by the Go field name. Fields without metadata are
+// omitted. The result is computed once per type and must not be modified.
+func MetadataOf[T any](key string) (map[string]any, error) {
+	return metadataOf(reflect.TypeOf((*T)(nil)).Elem(), key, nil)
+}
+
+// MetadataOfWithOptions is like MetadataOf, except the tags are read using
+// the TagKey and FieldName of the given options. Results are only cached if
+// FieldName is nil, since functions can't be compared.
+func MetadataOfWithOptions[T any](key string, opts Options) (map[string]any, error) {
+	return metadataOf(reflect.TypeOf((*T)(nil)).Elem(), key, &opts)
+}
+
+func metadataOf(t reflect.Type, key string, o *Options) (map[string]any, error) {
+	cacheKey := metadataKey{t, key, o.tagKey()}
+	cacheable := o == nil || o.FieldName == nil
+
+	if cacheable {
+		if cached, ok := metadataCache.Load(cacheKey); ok {
+			return cached.(map[string]any), nil
+		}
+	}
+
+	metadataMu.RLock()
+	fn, ok := metadataFuncs[key]
+	metadataMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("caddyunmarshal: unknown metadata %q", key)
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("caddyunmarshal: cannot get metadata of non-struct type %s", t)
+	}
+
+	tags, err := fieldTags(t, o)
+	if err != nil {
+		return nil, fmt.Errorf("cannot extract fields of %s: %w", t, err)
+	}
+
+	metadata := make(map[string]any, len(tags))
+	for _, tag := range tags {
+		if v := fn(tag); v != nil {
+			metadata[tag.Field.Name] = v
+		}
+	}
+
+	if !cacheable {
+		return metadata, nil
+	}
+
+	cached, _ := metadataCache.LoadOrStore(cacheKey, metadata)
+	return cached.(map[string]any), nil
+}
+
+// fieldTags returns the tags of all fields of the given struct type that are
+// part of its syntax, read using the given options.
+func fieldTags(t reflect.Type, o *Options) ([]FieldTag, error) {
+	info, err := extractFields(zeroValue(t), o)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []FieldTag
+	if info.matcher != nil {
+		tags = append(tags, FieldTag{info.matcher.field, info.matcher.key(), info.matcher.opts})
+	}
+	for _, field := range info.otherFields {
+		tags = append(tags, FieldTag{field.field, field.key(), field.opts})
+	}
+	for _, field := range info.blockFields {
+		tags = append(tags, FieldTag{field.field, field.key(), field.opts})
+	}
+
+	return tags, nil
+}
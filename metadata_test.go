@@ -0,0 +1,62 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func init() {
+	RegisterMetadata("widget", func(tag FieldTag) any {
+		if widget, ok := tag.Option("widget"); ok {
+			return widget
+		}
+		if tag.HasOption("secret") {
+			return "password"
+		}
+		return nil
+	})
+}
+
+func TestMetadataOf(t *testing.T) {
+	type target struct {
+		Path     string `caddyfile:"$1,widget=file"`
+		Username string `caddyfile:"username,widget=text"`
+		Password string `caddyfile:"password,secret"`
+		Timeout  int
+	}
+
+	metadata, err := MetadataOf[target]("widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]any{
+		"Path":     "file",
+		"Username": "text",
+		"Password": "password",
+	}
+	if !reflect.DeepEqual(metadata, expect) {
+		t.Errorf("unexpected metadata: %v", metadata)
+	}
+
+	again, err := MetadataOf[target]("widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(again).Pointer() != reflect.ValueOf(metadata).Pointer() {
+		t.Error("metadata is not cached")
+	}
+
+	if _, err := MetadataOf[target]("unknown"); err == nil {
+		t.Error("expected error for unknown metadata")
+	}
+}
+
+func TestRegisterMetadataDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	RegisterMetadata("widget", func(FieldTag) any { return nil })
+}
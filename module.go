@@ -0,0 +1,44 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+var eventNameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// validateModuleValue validates string values against the module and event
+// options. The module option (e.g. module=http.handlers) requires the value
+// to be the name of a module registered within the given namespace, and the
+// event option requires the value to be a valid event name. Since the events
+// app lowercases event names when they are emitted, a subscription to a name
+// with upper case letters would never fire.
+func validateModuleValue(raw string, opts []string) error {
+	if namespace, ok := optValue(opts, "module"); ok {
+		if _, err := caddy.GetModule(namespace + "." + raw); err != nil {
+			modules := caddy.GetModules(namespace)
+			if len(modules) == 0 {
+				return fmt.Errorf("unknown module %q: no modules registered in namespace %s", raw, namespace)
+			}
+
+			names := make([]string, len(modules))
+			for i, module := range modules {
+				names[i] = module.ID.Name()
+			}
+
+			return fmt.Errorf(
+				"unknown module %q in namespace %s, expected one of: %s",
+				raw, namespace, strings.Join(names, ", "))
+		}
+	}
+
+	if hasOpt(opts, "event") && !eventNameRe.MatchString(raw) {
+		return fmt.Errorf(
+			"invalid event name %q: must only contain lower case letters, digits and underscores", raw)
+	}
+
+	return nil
+}
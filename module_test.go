@@ -0,0 +1,44 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalModuleReferences(t *testing.T) {
+	type subscription struct {
+		Event   string   `caddyfile:"$1,event"`
+		Handler string   `caddyfile:"$2,module=http.handlers"`
+		Also    []string `caddyfile:"also,module=http.handlers"`
+	}
+
+	v, err := unmarshalString[subscription]("on cert_obtained static_response {\n also vars\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := subscription{"cert_obtained", "static_response", []string{"vars"}}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	_, err = unmarshalString[subscription]("on cert_obtained nonexistent")
+	if err == nil || !strings.Contains(err.Error(), `unknown module "nonexistent" in namespace http.handlers`) {
+		t.Errorf("expected unknown module error, got %v", err)
+	}
+
+	_, err = unmarshalString[subscription]("on CertObtained static_response")
+	if err == nil || !strings.Contains(err.Error(), "invalid event name") {
+		t.Errorf("expected invalid event name error, got %v", err)
+	}
+
+	type unknownNamespace struct {
+		Handler string `caddyfile:"$1,module=nonexistent.handlers"`
+	}
+
+	_, err = unmarshalString[unknownNamespace]("x y")
+	if err == nil || !strings.Contains(err.Error(), "no modules registered") {
+		t.Errorf("expected unknown namespace error, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package caddyunmarshal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+// ModuleDoc is the documentation of a Caddy module, modeled after the JSON
+// metadata that caddyserver.com renders its module documentation from.
+type ModuleDoc struct {
+	// ID is the module ID, e.g. "http.handlers.my_module".
+	ID string `json:"id"`
+	// Structure describes the JSON structure of the module.
+	Structure DocValue `json:"structure"`
+	// Caddyfile is the Caddyfile syntax of the module's directive as
+	// rendered by Usage.
+	Caddyfile string `json:"caddyfile,omitempty"`
+}
+
+// DocValue describes a JSON value within a module's structure.
+type DocValue struct {
+	// Type is one of struct, string, bool, int, uint, float, array, map,
+	// module or module_map.
+	Type string `json:"type"`
+	// TypeName is the fully qualified Go type name for named types.
+	TypeName string `json:"type_name,omitempty"`
+	// StructFields lists the fields of struct values.
+	StructFields []DocField `json:"struct_fields,omitempty"`
+	// Elems describes the elements of array and map values.
+	Elems *DocValue `json:"elems,omitempty"`
+	// MapKeys describes the keys of map values.
+	MapKeys *DocValue `json:"map_keys,omitempty"`
+	// ModuleNamespace and ModuleInlineKey describe module and module_map
+	// values, as given in their caddy struct tag.
+	ModuleNamespace string `json:"module_namespace,omitempty"`
+	ModuleInlineKey string `json:"module_inline_key,omitempty"`
+}
+
+// DocField describes a single field of a struct value.
+type DocField struct {
+	// Key is the JSON key of the field.
+	Key   string   `json:"key"`
+	Value DocValue `json:"value"`
+	// Doc is the documentation given using the doc option of the field's
+	// caddyfile tag.
+	Doc string `json:"doc,omitempty"`
+}
+
+// ModuleDocs generates the documentation of the given module, whose Caddyfile
+// directive is named directive, as indented JSON. The JSON structure is
+// derived from the json tags of the module, while the documentation of each
+// field is taken from the doc option of its caddyfile tag.
+func ModuleDocs(m caddy.Module, directive string) ([]byte, error) {
+	return ModuleDocsWithOptions(m, directive, Options{})
+}
+
+// ModuleDocsWithOptions is like ModuleDocs, except the tags are read using the
+// TagKey and FieldName of the given options.
+func ModuleDocsWithOptions(m caddy.Module, directive string, opts Options) ([]byte, error) {
+	t := reflect.TypeOf(m)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("caddyunmarshal: expected struct module, got %T", m)
+	}
+
+	var b strings.Builder
+	if err := writeUsage(&b, &opts, 0, directive, t, nil); err != nil {
+		return nil, err
+	}
+
+	doc := ModuleDoc{
+		ID:        string(m.CaddyModule().ID),
+		Structure: docValue(&opts, t, nil),
+		Caddyfile: b.String(),
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // keep <placeholders> in the Caddyfile syntax
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var typeRawMessage = reflect.TypeOf(json.RawMessage(nil))
+
+// docValue describes the given type. seen contains the struct types that are
+// currently being described, which stops recursive types.
+func docValue(o *Options, t reflect.Type, seen []reflect.Type) DocValue {
+	t = optionalElem(t)
+
+	var value DocValue
+	if t.Name() != "" && t.PkgPath() != "" {
+		value.TypeName = t.PkgPath() + "." + t.Name()
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return docValue(o, t.Elem(), seen)
+	case reflect.String:
+		value.Type = "string"
+	case reflect.Bool:
+		value.Type = "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.Type = "int"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		value.Type = "uint"
+	case reflect.Float32, reflect.Float64:
+		value.Type = "float"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			// encoding/json encodes byte slices as base64 strings
+			value.Type = "string"
+			break
+		}
+		elems := docValue(o, t.Elem(), seen)
+		value.Type = "array"
+		value.Elems = &elems
+	case reflect.Map:
+		keys := docValue(o, t.Key(), seen)
+		elems := docValue(o, t.Elem(), seen)
+		value.Type = "map"
+		value.MapKeys = &keys
+		value.Elems = &elems
+	case reflect.Struct:
+		value.Type = "struct"
+		for _, s := range seen {
+			if s == t {
+				return value
+			}
+		}
+		value.StructFields = docFields(o, t, append(seen, t))
+	default:
+		value.Type = "interface"
+	}
+
+	return value
+}
+
+func docFields(o *Options, t reflect.Type, seen []reflect.Type) []DocField {
+	var fields []DocField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		key := strings.Split(f.Tag.Get("json"), ",")[0]
+		if key == "-" {
+			continue
+		}
+
+		if f.Anonymous && key == "" {
+			// embedded structs are flattened, just like encoding/json does
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, docFields(o, ft, seen)...)
+				continue
+			}
+		}
+
+		if key == "" {
+			key = f.Name
+		}
+
+		var value DocValue
+		if namespace, ok := caddyTagValue(f, "namespace"); ok {
+			inlineKey, _ := caddyTagValue(f, "inline_key")
+			value = moduleDocValue(o, f.Type, namespace, inlineKey)
+		} else {
+			value = docValue(o, f.Type, seen)
+		}
+
+		doc, _ := optValue(splitTag(f.Tag.Get(o.tagKey())), "doc")
+		fields = append(fields, DocField{Key: key, Value: value, Doc: doc})
+	}
+
+	return fields
+}
+
+// moduleDocValue describes a field holding modules, which is either a
+// json.RawMessage, a caddy.ModuleMap or a slice of either.
+func moduleDocValue(o *Options, t reflect.Type, namespace, inlineKey string) DocValue {
+	switch {
+	case t == TypeCaddyModuleMap:
+		return DocValue{Type: "module_map", ModuleNamespace: namespace}
+	case t == typeRawMessage:
+		return DocValue{Type: "module", ModuleNamespace: namespace, ModuleInlineKey: inlineKey}
+	case t.Kind() == reflect.Slice:
+		elems := moduleDocValue(o, t.Elem(), namespace, inlineKey)
+		return DocValue{Type: "array", Elems: &elems}
+	case t.Kind() == reflect.Map:
+		keys := DocValue{Type: "string"}
+		elems := moduleDocValue(o, t.Elem(), namespace, inlineKey)
+		return DocValue{Type: "map", MapKeys: &keys, Elems: &elems}
+	default:
+		return docValue(o, t, nil)
+	}
+}
+
+// caddyTagValue returns the value of the given key within the caddy struct
+// tag of f, e.g. namespace for `caddy:"namespace=http.handlers"`.
+func caddyTagValue(f reflect.StructField, key string) (string, bool) {
+	for _, part := range strings.Fields(f.Tag.Get("caddy")) {
+		if strings.HasPrefix(part, key+"=") {
+			return strings.TrimPrefix(part, key+"="), true
+		}
+	}
+	return "", false
+}
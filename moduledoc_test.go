@@ -0,0 +1,110 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+type documentedModule struct {
+	Root     string            `json:"root,omitempty" caddyfile:"$1,doc='the root directory'"`
+	Timeout  caddy.Duration    `json:"timeout,omitempty" caddyfile:"timeout,doc='how long to wait'"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Handlers []json.RawMessage `json:"handlers,omitempty" caddy:"namespace=http.handlers inline_key=handler" caddyfile:"-"`
+	Internal string            `json:"-" caddyfile:"-"`
+}
+
+func (documentedModule) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.documented",
+		New: func() caddy.Module { return new(documentedModule) },
+	}
+}
+
+func TestModuleDocs(t *testing.T) {
+	b, err := ModuleDocs(documentedModule{}, "documented")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc ModuleDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := ModuleDoc{
+		ID: "http.handlers.documented",
+		Structure: DocValue{
+			Type:     "struct",
+			TypeName: "github.com/diamondburned/caddyunmarshal.documentedModule",
+			StructFields: []DocField{
+				{Key: "root", Value: DocValue{Type: "string"}, Doc: "the root directory"},
+				{Key: "timeout", Value: DocValue{
+					Type:     "int",
+					TypeName: "github.com/caddyserver/caddy/v2.Duration",
+				}, Doc: "how long to wait"},
+				{Key: "headers", Value: DocValue{
+					Type:    "map",
+					MapKeys: &DocValue{Type: "string"},
+					Elems:   &DocValue{Type: "string"},
+				}},
+				{Key: "handlers", Value: DocValue{
+					Type: "array",
+					Elems: &DocValue{
+						Type:            "module",
+						ModuleNamespace: "http.handlers",
+						ModuleInlineKey: "handler",
+					},
+				}},
+			},
+		},
+		Caddyfile: "# <root>: the root directory\ndocumented <root> {\n\t# how long to wait\n\ttimeout <duration>\n\theaders {\n\t\t<string> <string>\n\t}\n}\n",
+	}
+	if !reflect.DeepEqual(doc, expect) {
+		t.Errorf("unexpected docs:\n%s", b)
+	}
+}
+
+type customTagModule struct {
+	Timeout caddy.Duration `json:"timeout,omitempty" cfg:"timeout,doc='how long to wait'" caddyfile:"-"`
+	MaxSize int            `json:"max_size,omitempty" cfg:",doc='largest body'"`
+	Retries int            `cfg:",doc='times to retry'"`
+}
+
+func (customTagModule) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.custom_tag",
+		New: func() caddy.Module { return new(customTagModule) },
+	}
+}
+
+func TestModuleDocsWithOptions(t *testing.T) {
+	opts := Options{TagKey: "cfg", FieldName: strings.ToUpper}
+
+	b, err := ModuleDocsWithOptions(customTagModule{}, "custom", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc ModuleDoc
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var docs []string
+	for _, field := range doc.Structure.StructFields {
+		docs = append(docs, field.Doc)
+	}
+	if expect := []string{"how long to wait", "largest body", "times to retry"}; !reflect.DeepEqual(docs, expect) {
+		t.Errorf("unexpected field docs %q", docs)
+	}
+
+	const caddyfile = "custom {\n\t# how long to wait\n\ttimeout <duration>\n" +
+		"\t# largest body\n\tmax_size <int>\n\t# times to retry\n\tRETRIES <int>\n}\n"
+	if doc.Caddyfile != caddyfile {
+		t.Errorf("unexpected Caddyfile syntax:\n%s", doc.Caddyfile)
+	}
+}
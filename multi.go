@@ -0,0 +1,163 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// UnmarshalMulti unmarshals the given Caddyfile dispenser into several struct
+// pointers at once. The fields of all targets are combined as if they were
+// declared in one struct, so each argument, block and subdirective is routed
+// to the target that declares it. This is useful for plugins whose config is
+// split across packages, e.g.
+//
+//	type Transport struct {
+//		DialTimeout caddy.Duration `caddyfile:"dial_timeout"`
+//	}
+//
+//	type Policy struct {
+//		Upstream string `caddyfile:"$1"`
+//		Retries  int    `caddyfile:"retries"`
+//	}
+//
+//	err := caddyunmarshal.UnmarshalMulti(d, &transport, &policy)
+//
+// The targets must not declare the same argument index, block index or
+// subdirective name, and at most one of them may declare a matcher, a primary
+// field, or a remain or extras field.
+func UnmarshalMulti(d *caddyfile.Dispenser, targets ...any) error {
+	return unmarshalMulti(d, nil, targets)
+}
+
+// UnmarshalMultiWithOptions is like UnmarshalMulti, except the given options
+// are used.
+func UnmarshalMultiWithOptions(d *caddyfile.Dispenser, opts Options, targets ...any) error {
+	return unmarshalMulti(d, &opts, targets)
+}
+
+func unmarshalMulti(d *caddyfile.Dispenser, o *Options, targets []any) error {
+	info, err := extractMulti(targets, o)
+	if err != nil {
+		return err
+	}
+
+	dd := dispenser{Dispenser: d, options: o}
+	seg := dd.segment()
+
+	directive, pos := d.Val(), tokenPosition(d)
+	if err := unmarshalInfo(dd, info); err != nil {
+		return withPath(directive, err)
+	}
+
+	for _, target := range targets {
+		r, _ := newReflectValue(target)
+		if hasPostUnmarshaler(r.t) {
+			if err := postUnmarshal(r, seg); err != nil {
+				return err
+			}
+		}
+		if err := validateValue(r, pos); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractMulti extracts and composes the fields of all given struct pointers.
+func extractMulti(targets []any, o *Options) (structInfo, error) {
+	infos := make([]structInfo, len(targets))
+	for i, target := range targets {
+		r, err := newReflectValue(target)
+		if err != nil {
+			return structInfo{}, err
+		}
+		if r.v.Kind() != reflect.Struct {
+			return structInfo{}, fmt.Errorf("caddyunmarshal: expected struct value, got %T", target)
+		}
+
+		info, err := extractFields(r, o)
+		if err != nil {
+			return structInfo{}, fmt.Errorf("cannot extract fields of %T: %w", target, err)
+		}
+
+		infos[i] = info
+	}
+
+	return composeInfo(infos...)
+}
+
+// composeInfo combines the fields of the given structInfos into one.
+func composeInfo(infos ...structInfo) (structInfo, error) {
+	var composed structInfo
+	names := make(map[string]fieldInfo)
+
+	for _, info := range infos {
+		for _, field := range info.blockFields {
+			for _, name := range field.kind.(blockFieldKind).names() {
+				if prev, ok := names[name]; ok {
+					return structInfo{}, fmt.Errorf(
+						"caddyunmarshal: subdirective %q is declared by both %s.%s and %s.%s",
+						name, prev.owner.t, prev.field.Name, field.owner.t, field.field.Name)
+				}
+				names[name] = field
+			}
+		}
+
+		if info.matcher != nil {
+			if composed.matcher != nil {
+				return structInfo{}, fmt.Errorf(
+					"caddyunmarshal: matcher is declared by both %s and %s",
+					composed.matcher.owner.t, info.matcher.owner.t)
+			}
+			composed.matcher = info.matcher
+		}
+
+		if info.remain != nil {
+			if composed.remain != nil {
+				return structInfo{}, fmt.Errorf(
+					"caddyunmarshal: remain field is declared by both %s and %s",
+					composed.remain.owner.t, info.remain.owner.t)
+			}
+			composed.remain = info.remain
+		}
+
+		if info.extras != nil {
+			if composed.extras != nil {
+				return structInfo{}, fmt.Errorf(
+					"caddyunmarshal: extras field is declared by both %s and %s",
+					composed.extras.owner.t, info.extras.owner.t)
+			}
+			composed.extras = info.extras
+		}
+
+		if info.primary != nil {
+			if composed.primary != nil {
+				return structInfo{}, fmt.Errorf(
+					"caddyunmarshal: primary field is declared by both %s and %s",
+					composed.primary.owner.t, info.primary.owner.t)
+			}
+			composed.primary = info.primary
+		}
+
+		composed.present = append(composed.present, info.present...)
+		composed.blockFields = append(composed.blockFields, info.blockFields...)
+		composed.otherFields = append(composed.otherFields, info.otherFields...)
+		composed.opts = append(composed.opts, info.opts...)
+		composed.examples = append(composed.examples, info.examples...)
+	}
+
+	if composed.remain != nil && composed.extras != nil {
+		return structInfo{}, fmt.Errorf(
+			"caddyunmarshal: remain field of %s and extras field of %s are exclusive",
+			composed.remain.owner.t, composed.extras.owner.t)
+	}
+
+	if err := composed.validate(); err != nil {
+		return structInfo{}, err
+	}
+
+	return composed, nil
+}
@@ -0,0 +1,194 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+func TestUnmarshalMulti(t *testing.T) {
+	type transport struct {
+		Network     string `caddyfile:"$2,optional"`
+		DialTimeout string `caddyfile:"dial_timeout"`
+	}
+
+	type policy struct {
+		Upstream string `caddyfile:"$1"`
+		Retries  int    `caddyfile:"retries"`
+	}
+
+	d := caddyfile.NewTestDispenser(`
+		proxy localhost:8080 tcp {
+			dial_timeout 5s
+			retries 3
+		}
+	`)
+	d.Next()
+
+	var tr transport
+	var p policy
+	if err := UnmarshalMulti(d, &tr, &p); err != nil {
+		t.Fatal(err)
+	}
+
+	if tr != (transport{"tcp", "5s"}) {
+		t.Errorf("unexpected transport: %+v", tr)
+	}
+	if p != (policy{"localhost:8080", 3}) {
+		t.Errorf("unexpected policy: %+v", p)
+	}
+
+	type conflicting struct {
+		Retries string `caddyfile:"retries"`
+	}
+
+	d = caddyfile.NewTestDispenser("proxy a")
+	d.Next()
+
+	var c conflicting
+	if err := UnmarshalMulti(d, &p, &c); err == nil {
+		t.Error("expected error for conflicting subdirective")
+	}
+
+	type conflictingArg struct {
+		Other string `caddyfile:"$1"`
+	}
+
+	d = caddyfile.NewTestDispenser("proxy a")
+	d.Next()
+
+	var ca conflictingArg
+	if err := UnmarshalMulti(d, &p, &ca); err == nil {
+		t.Error("expected error for conflicting argument index")
+	}
+}
+
+type multiPolicy struct {
+	Retries int `caddyfile:"retries"`
+}
+
+type multiRest struct {
+	Rest map[string][]string `caddyfile:",remain"`
+}
+
+func TestUnmarshalMultiRemain(t *testing.T) {
+	const input = "h {\n retries 3\n other 1 2\n}"
+	expect := map[string][]string{"other": {"1", "2"}}
+
+	d := caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	var p multiPolicy
+	var r multiRest
+	if err := UnmarshalMultiWithOptions(d, Options{Strict: true}, &p, &r); err != nil {
+		t.Fatal(err)
+	}
+	if p.Retries != 3 || !reflect.DeepEqual(r.Rest, expect) {
+		t.Errorf("unexpected values: %+v %+v", p, r)
+	}
+
+	schema := MustComposeSchemas(multiPolicy{}, multiRest{})
+
+	d = caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	p, r = multiPolicy{}, multiRest{}
+	if err := schema.Unmarshal(d, &p, &r); err != nil {
+		t.Fatal(err)
+	}
+	if p.Retries != 3 || !reflect.DeepEqual(r.Rest, expect) {
+		t.Errorf("unexpected schema values: %+v %+v", p, r)
+	}
+
+	d = caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	var other multiRest
+	if err := UnmarshalMulti(d, &r, &other); err == nil || !strings.Contains(err.Error(), "remain field is declared by both") {
+		t.Errorf("expected duplicate remain field error, got %v", err)
+	}
+}
+
+type multiExtras struct {
+	Extras []caddyfile.Segment `caddyfile:",extras"`
+}
+
+func TestUnmarshalMultiExtras(t *testing.T) {
+	const input = "h {\n retries 3\n other 1 2\n}"
+
+	d := caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	var p multiPolicy
+	var e multiExtras
+	if err := UnmarshalMultiWithOptions(d, Options{Strict: true}, &p, &e); err != nil {
+		t.Fatal(err)
+	}
+	if p.Retries != 3 || len(e.Extras) != 1 || e.Extras[0].Directive() != "other" {
+		t.Errorf("unexpected values: %+v %+v", p, e)
+	}
+
+	schema := MustComposeSchemas(multiPolicy{}, multiExtras{})
+
+	d = caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	p, e = multiPolicy{}, multiExtras{}
+	if err := schema.Unmarshal(d, &p, &e); err != nil {
+		t.Fatal(err)
+	}
+	if p.Retries != 3 || len(e.Extras) != 1 || e.Extras[0].Directive() != "other" {
+		t.Errorf("unexpected schema values: %+v %+v", p, e)
+	}
+
+	d = caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	var other multiExtras
+	if err := UnmarshalMulti(d, &e, &other); err == nil || !strings.Contains(err.Error(), "extras field is declared by both") {
+		t.Errorf("expected duplicate extras field error, got %v", err)
+	}
+
+	var r multiRest
+	if err := UnmarshalMulti(d, &r, &e); err == nil || !strings.Contains(err.Error(), "are exclusive") {
+		t.Errorf("expected exclusive error, got %v", err)
+	}
+}
+
+type multiTimeout struct {
+	Timeout time.Duration   `caddyfile:"timeout"`
+	Present map[string]bool `caddyfile:",present"`
+}
+
+func TestUnmarshalMultiPresent(t *testing.T) {
+	const input = "h {\n retries 3\n timeout 5s\n}"
+	expect := map[string]bool{"Timeout": true}
+
+	d := caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	var p multiPolicy
+	var o multiTimeout
+	if err := UnmarshalMulti(d, &p, &o); err != nil {
+		t.Fatal(err)
+	}
+	if p.Retries != 3 || o.Timeout != 5*time.Second || !reflect.DeepEqual(o.Present, expect) {
+		t.Errorf("unexpected values: %+v %+v", p, o)
+	}
+
+	schema := MustComposeSchemas(multiPolicy{}, multiTimeout{})
+
+	d = caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	p, o = multiPolicy{}, multiTimeout{}
+	if err := schema.Unmarshal(d, &p, &o); err != nil {
+		t.Fatal(err)
+	}
+	if p.Retries != 3 || o.Timeout != 5*time.Second || !reflect.DeepEqual(o.Present, expect) {
+		t.Errorf("unexpected schema values: %+v %+v", p, o)
+	}
+}
@@ -0,0 +1,34 @@
+package caddyunmarshal
+
+import "github.com/caddyserver/caddy/v2"
+
+// parseNetworkAddress parses a caddy.NetworkAddress, filling in the parts that
+// the address omits from the defaultnetwork and defaultport options, e.g.
+//
+//	Listen caddy.NetworkAddress `caddyfile:"$1,defaultnetwork=udp,defaultport=53"`
+//
+// takes "localhost" as udp/localhost:53, and "tcp/localhost:5353" as is.
+// Without a default network, the network is tcp as with
+// caddy.ParseNetworkAddress. Unix sockets have no port, so the default port
+// doesn't apply to them.
+func parseNetworkAddress(raw string, opts []string) (caddy.NetworkAddress, error) {
+	defaultNetwork, hasNetwork := optValue(opts, "defaultnetwork")
+	defaultPort, hasPort := optValue(opts, "defaultport")
+	if !hasNetwork && !hasPort {
+		return caddy.ParseNetworkAddress(raw)
+	}
+
+	network, host, port, err := caddy.SplitNetworkAddress(raw)
+	if err != nil {
+		return caddy.NetworkAddress{}, err
+	}
+
+	if network == "" {
+		network = defaultNetwork
+	}
+	if port == "" && !caddy.IsUnixNetwork(network) {
+		port = defaultPort
+	}
+
+	return caddy.ParseNetworkAddress(caddy.JoinNetworkAddress(network, host, port))
+}
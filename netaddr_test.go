@@ -0,0 +1,58 @@
+package caddyunmarshal
+
+import (
+	"testing"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+func TestUnmarshalNetworkAddressDefaults(t *testing.T) {
+	type resolver struct {
+		Listen    caddy.NetworkAddress   `caddyfile:"$1,defaultnetwork=udp,defaultport=53"`
+		Upstreams []caddy.NetworkAddress `caddyfile:"upstreams,defaultport=53"`
+	}
+
+	tests := []struct {
+		input  string
+		listen string
+	}{
+		{"resolver localhost", "udp/localhost:53"},
+		{"resolver :5353", "udp/:5353"},
+		{"resolver tcp/localhost", "localhost:53"},
+		{"resolver tcp/[::1]:5353", "[::1]:5353"},
+		{"resolver unix//run/dns.sock", "unix//run/dns.sock"},
+	}
+
+	for _, test := range tests {
+		v, err := unmarshalString[resolver](test.input)
+		if err != nil {
+			t.Errorf("%s: %v", test.input, err)
+			continue
+		}
+		if s := v.Listen.String(); s != test.listen {
+			t.Errorf("%s: expected %s, got %s", test.input, test.listen, s)
+		}
+	}
+
+	v, err := unmarshalString[resolver]("resolver localhost {\n\tupstreams 1.1.1.1 8.8.8.8:5353\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []string{"1.1.1.1:53", "8.8.8.8:5353"}
+	if len(v.Upstreams) != len(expect) {
+		t.Fatalf("expected %d upstreams, got %v", len(expect), v.Upstreams)
+	}
+	for i, upstream := range v.Upstreams {
+		if s := upstream.String(); s != expect[i] {
+			t.Errorf("upstream %d: expected %s, got %s", i, expect[i], s)
+		}
+	}
+
+	type invalid struct {
+		Listen caddy.NetworkAddress `caddyfile:"$1,defaultport=dns"`
+	}
+	if _, err := unmarshalString[invalid]("resolver localhost"); err == nil {
+		t.Error("expected error for invalid default port")
+	}
+}
@@ -0,0 +1,75 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+)
+
+// noneSentinel returns the argument that clears the field, which is given by
+// the allownone option and defaults to "none", e.g.
+//
+//	Compression []string `caddyfile:"encode,allownone"`
+//	Logging     *Log     `caddyfile:"log,allownone=off"`
+//	Root        *string  `caddyfile:"root,allownone"`
+//
+// The sentinel resets the field to its zero value, see clearValue. Pointers,
+// including pointers to scalars such as *string, are set to nil, which tells
+// a cleared field apart from one given as an empty or zero value. Optional
+// fields also record that they were cleared, see Optional.IsCleared. For the
+// other kinds, a present field tells a cleared field apart from one that was
+// left out, see markPresent.
+func noneSentinel(opts []string) (string, bool) {
+	if sentinel, ok := optValue(opts, "allownone"); ok {
+		return sentinel, true
+	}
+	if hasOpt(opts, "allownone") {
+		return "none", true
+	}
+	return "", false
+}
+
+// isNone returns true if the current token is the unquoted sentinel of the
+// allownone option, so that a quoted "none" is still taken literally.
+func (d dispenser) isNone(opts []string) bool {
+	sentinel, ok := noneSentinel(opts)
+	return ok && d.Val() == sentinel && !d.Token().Quoted()
+}
+
+// unmarshalNone clears r if the rest of the line is only the sentinel of the
+// allownone option. It returns false if it isn't, leaving the cursor as is.
+func unmarshalNone(d dispenser, r reflectValue, opts []string) bool {
+	if !d.NextArg() {
+		return false
+	}
+	if !d.isNone(opts) || d.nextArgOrBlock() {
+		d.Prev()
+		return false
+	}
+
+	clearValue(r)
+	return true
+}
+
+// nextArgOrBlock returns true if the current token is followed by another
+// argument or a block on the same line, without moving the cursor.
+func (d dispenser) nextArgOrBlock() bool {
+	prev := d.Token()
+	if !d.Next() {
+		return false
+	}
+	defer d.Prev()
+	return onSameLine(prev, d.Token())
+}
+
+// clearValue sets r to its zero value, which is nil for pointers, slices and
+// maps. Optional values are marked as both set and cleared, so that the
+// sentinel can be told apart from the field being left out.
+func clearValue(r reflectValue) {
+	if isOptional(r.t) {
+		o := r.v.Addr().Interface().(optionalField)
+		r.v.Set(reflect.Zero(r.t))
+		o.markCleared()
+		return
+	}
+
+	r.v.Set(reflect.Zero(r.t))
+}
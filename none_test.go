@@ -0,0 +1,123 @@
+package caddyunmarshal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type noneSite struct {
+	Root     string            `caddyfile:"$1,allownone"`
+	Encode   []string          `caddyfile:"encode,allownone"`
+	Headers  map[string]string `caddyfile:"header,allownone=off"`
+	Compress Optional[bool]    `caddyfile:"compress,allownone"`
+	Name     string            `caddyfile:"name"`
+}
+
+func TestUnmarshalNone(t *testing.T) {
+	v := noneSite{
+		Root:    "/srv",
+		Encode:  []string{"gzip"},
+		Headers: map[string]string{"X-Foo": "bar"},
+	}
+
+	d := caddyfile.NewTestDispenser("site none {\n encode none\n header off\n compress none\n}")
+	d.Next()
+
+	if err := Unmarshal(d, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Root != "" || v.Encode != nil || v.Headers != nil {
+		t.Errorf("expected cleared values, got %+v", v)
+	}
+	if !v.Compress.IsSet() || !v.Compress.IsCleared() || v.Compress.Get() {
+		t.Errorf("expected compress to be cleared, got %+v", v.Compress)
+	}
+
+	v, err := unmarshalString[noneSite]("site `none` {\n encode none zstd\n compress\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Root != "none" {
+		t.Errorf("expected quoted none to be taken literally, got %q", v.Root)
+	}
+	if strings.Join(v.Encode, " ") != "none zstd" {
+		t.Errorf("expected none among other arguments to be kept, got %q", v.Encode)
+	}
+	if v.Compress != Some(true) {
+		t.Errorf("expected compress to be set, got %+v", v.Compress)
+	}
+
+	_, err = unmarshalString[noneSite]("site /srv {\n header off {\n  X-Foo bar\n }\n}")
+	if err == nil {
+		t.Error("expected error for none with a block")
+	}
+}
+
+func TestUnmarshalNonePointer(t *testing.T) {
+	type target struct {
+		Root  *string         `caddyfile:"$1,allownone"`
+		Port  *int            `caddyfile:"port,allownone"`
+		Quiet *bool           `caddyfile:"quiet,allownone"`
+		Set   map[string]bool `caddyfile:",present"`
+	}
+
+	v, err := unmarshalString[target]("site /srv {\n port 80\n quiet\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Root == nil || *v.Root != "/srv" || v.Port == nil || *v.Port != 80 || v.Quiet == nil || !*v.Quiet {
+		t.Errorf("expected pointers to be set, got %+v", v)
+	}
+
+	root, port := "/srv", 80
+	v = target{Root: &root, Port: &port}
+
+	d := caddyfile.NewTestDispenser("site none {\n port none\n}")
+	d.Next()
+
+	if err := Unmarshal(d, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Root != nil || v.Port != nil || v.Quiet != nil {
+		t.Errorf("expected cleared pointers, got %+v", v)
+	}
+	if !v.Set["Root"] || !v.Set["Port"] || v.Set["Quiet"] {
+		t.Errorf("unexpected present fields %v", v.Set)
+	}
+	if root != "/srv" || port != 80 {
+		t.Errorf("pointees were modified: %q %d", root, port)
+	}
+
+	if _, err := unmarshalString[target]("site /srv {\n port abc\n}"); err == nil {
+		t.Error("expected error for invalid port")
+	}
+
+	b, err := Marshal("site", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "site none\n" {
+		t.Errorf("unexpected output:\n%s", b)
+	}
+}
+
+func TestMarshalNone(t *testing.T) {
+	v, err := unmarshalString[noneSite]("site /srv {\n compress none\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Marshal("site", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "site /srv {\n\tcompress none\n}\n"
+	if string(b) != expect {
+		t.Errorf("unexpected output:\n%s", b)
+	}
+}
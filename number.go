@@ -0,0 +1,185 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// siMultipliers maps SI suffixes to their multipliers. Since these are used
+// for counts, the suffixes are case-insensitive, so "2m" is two million.
+var siMultipliers = map[byte]int64{
+	'k': 1e3,
+	'm': 1e6,
+	'g': 1e9,
+	't': 1e12,
+	'p': 1e15,
+	'e': 1e18,
+}
+
+// binaryMultipliers maps the prefixes of binary suffixes, such as the "Mi" in
+// "64Mi", to their multipliers. Like SI suffixes, they are case-insensitive.
+var binaryMultipliers = map[byte]int64{
+	'k': 1 << 10,
+	'm': 1 << 20,
+	'g': 1 << 30,
+	't': 1 << 40,
+	'p': 1 << 50,
+	'e': 1 << 60,
+}
+
+// siNumberRe matches the number of a value with an SI or binary suffix. It is
+// stricter than big.Rat.SetString, which also takes fractions, exponents and
+// hexadecimal numbers.
+var siNumberRe = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// parseSI parses a number with an optional SI or binary suffix, such as "10k",
+// "1.5M" or "64Mi". Fractional numbers are allowed as long as the result is a
+// whole number.
+func parseSI(raw string) (*big.Int, error) {
+	num := strings.ToLower(raw)
+	mult := int64(1)
+
+	if n := len(num); n > 1 && num[n-1] == 'i' {
+		if m, ok := binaryMultipliers[num[n-2]]; ok {
+			num = num[:n-2]
+			mult = m
+		}
+	} else if n > 0 {
+		if m, ok := siMultipliers[num[n-1]]; ok {
+			num = num[:n-1]
+			mult = m
+		}
+	}
+
+	if !siNumberRe.MatchString(num) {
+		return nil, fmt.Errorf("invalid number %q", raw)
+	}
+
+	n, ok := new(big.Rat).SetString(num)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", raw)
+	}
+
+	n.Mul(n, new(big.Rat).SetInt64(mult))
+	if !n.IsInt() {
+		return nil, fmt.Errorf("%q is not a whole number", raw)
+	}
+
+	return n.Num(), nil
+}
+
+// parseSIInt parses a signed integer with an optional SI or binary suffix that
+// must fit within the given bit size.
+func parseSIInt(raw string, bits int) (int64, error) {
+	n, err := parseSI(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	min := new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), uint(bits-1)))
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bits-1)), big.NewInt(1))
+	if n.Cmp(min) < 0 || n.Cmp(max) > 0 {
+		return 0, intRangeError(raw, bits)
+	}
+
+	return n.Int64(), nil
+}
+
+// parseSIUint parses an unsigned integer with an optional SI or binary suffix
+// that must fit within the given bit size.
+func parseSIUint(raw string, bits int) (uint64, error) {
+	n, err := parseSI(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bits)), big.NewInt(1))
+	if n.Sign() < 0 || n.Cmp(max) > 0 {
+		return 0, uintRangeError(raw, bits)
+	}
+
+	return n.Uint64(), nil
+}
+
+// parseInt parses a signed integer that must fit within the given bit size.
+// Unlike strconv.ParseInt, out of range values are reported along with the
+// allowed range.
+func parseInt(raw string, bits int) (int64, error) {
+	i, err := strconv.ParseInt(raw, 10, bits)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, intRangeError(raw, bits)
+	}
+	return i, err
+}
+
+// parseUint parses an unsigned integer that must fit within the given bit
+// size. Unlike strconv.ParseUint, negative and out of range values are
+// reported along with the allowed range.
+func parseUint(raw string, bits int) (uint64, error) {
+	u, err := strconv.ParseUint(raw, 10, bits)
+	if errors.Is(err, strconv.ErrRange) ||
+		(err != nil && strings.HasPrefix(raw, "-") && isDigits(raw[1:])) {
+		return 0, uintRangeError(raw, bits)
+	}
+	return u, err
+}
+
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+func intRangeError(raw string, bits int) error {
+	max := int64(math.MaxInt64 >> (64 - bits))
+	return fmt.Errorf("%s is out of range: must be between %d and %d", raw, -max-1, max)
+}
+
+func uintRangeError(raw string, bits int) error {
+	max := uint64(math.MaxUint64 >> (64 - bits))
+	return fmt.Errorf("%s is out of range: must be between 0 and %d", raw, max)
+}
+
+// isCountFlag returns true if the given type is an integer tagged with the
+// flag option, e.g. `caddyfile:"verbose,flag"`, which counts the occurrences
+// of its bare subdirective.
+func isCountFlag(t reflect.Type, opts []string) bool {
+	if !hasOpt(opts, "flag") {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// unmarshalCountFlag increments the given counting flag for the bare
+// subdirective at the cursor.
+func unmarshalCountFlag(d dispenser, r reflectValue) error {
+	name := d.Val()
+	if d.NextArg() {
+		return d.WrapErr(fmt.Errorf("%w at %q: %s", ErrUnexpectedArgument, name, d.Val()))
+	}
+
+	if r.v.CanInt() {
+		if r.v.OverflowInt(r.v.Int() + 1) {
+			return d.WrapErr(fmt.Errorf("%q is given too many times", name))
+		}
+		r.v.SetInt(r.v.Int() + 1)
+	} else {
+		if r.v.OverflowUint(r.v.Uint() + 1) {
+			return d.WrapErr(fmt.Errorf("%q is given too many times", name))
+		}
+		r.v.SetUint(r.v.Uint() + 1)
+	}
+
+	return nil
+}
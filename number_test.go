@@ -0,0 +1,152 @@
+package caddyunmarshal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSI(t *testing.T) {
+	tests := []struct {
+		in     string
+		bits   int
+		expect int64
+		err    bool
+	}{
+		{"100", 64, 100, false},
+		{"10k", 64, 10_000, false},
+		{"10K", 64, 10_000, false},
+		{"2m", 64, 2_000_000, false},
+		{"1.5M", 64, 1_500_000, false},
+		{"-3g", 64, -3_000_000_000, false},
+		{"1.5", 64, 0, true},
+		{"1.0005k", 64, 0, true},
+		{"k", 64, 0, true},
+		{"", 64, 0, true},
+		{"abc", 64, 0, true},
+		{"1k", 8, 0, true},
+		{"10e", 64, 0, true},
+		{"64Mi", 64, 64 << 20, false},
+		{"1.5ki", 64, 1536, false},
+		{"-2Gi", 64, -2 << 30, false},
+		{"7Ei", 64, 7 << 60, false},
+		{"8Ei", 64, 0, true},
+		{"1.1Ki", 64, 0, true},
+		{"Mi", 64, 0, true},
+		{"5i", 64, 0, true},
+		{"1Ki", 8, 0, true},
+		{"10/4k", 64, 0, true},
+		{"1e3k", 64, 0, true},
+		{"1e3", 64, 0, true},
+		{"0x10k", 64, 0, true},
+		{"0b1Ki", 64, 0, true},
+		{"+1k", 64, 0, true},
+		{"1.k", 64, 0, true},
+		{".5k", 64, 0, true},
+	}
+
+	for _, test := range tests {
+		n, err := parseSIInt(test.in, test.bits)
+		if test.err {
+			if err == nil {
+				t.Errorf("parseSIInt(%q): expected error, got %d", test.in, n)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseSIInt(%q): %v", test.in, err)
+			continue
+		}
+		if n != test.expect {
+			t.Errorf("parseSIInt(%q) = %d, want %d", test.in, n, test.expect)
+		}
+	}
+
+	if _, err := parseSIUint("-1k", 64); err == nil {
+		t.Error("expected error for negative uint")
+	}
+}
+
+func TestUnmarshalSI(t *testing.T) {
+	type limits struct {
+		Rate  int    `caddyfile:"rate,si"`
+		Queue uint32 `caddyfile:"queue,si"`
+		Plain int    `caddyfile:"plain"`
+	}
+
+	v, err := unmarshalString[limits]("limits {\n rate 10k\n queue 64Mi\n plain 5\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != (limits{10_000, 64 << 20, 5}) {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	if _, err := unmarshalString[limits]("limits {\n plain 5k\n}"); err == nil {
+		t.Error("expected error for suffix without si option")
+	}
+}
+
+func TestUnmarshalIntRange(t *testing.T) {
+	type sizes struct {
+		Small int8   `caddyfile:"small"`
+		Bytes uint16 `caddyfile:"bytes"`
+		Count uint64 `caddyfile:"count,si"`
+	}
+
+	tests := []struct {
+		input  string
+		expect string
+	}{
+		{"small 128", "128 is out of range: must be between -128 and 127"},
+		{"small -129", "-129 is out of range: must be between -128 and 127"},
+		{"bytes 65536", "65536 is out of range: must be between 0 and 65535"},
+		{"bytes -1", "-1 is out of range: must be between 0 and 65535"},
+		{"count 20e", "20e is out of range: must be between 0 and 18446744073709551615"},
+	}
+
+	for _, test := range tests {
+		_, err := unmarshalString[sizes]("sizes {\n " + test.input + "\n}")
+		if err == nil || !strings.Contains(err.Error(), test.expect) {
+			t.Errorf("%q: expected %q, got %v", test.input, test.expect, err)
+		}
+	}
+
+	if _, err := unmarshalString[sizes]("sizes {\n bytes abc\n}"); err == nil || strings.Contains(err.Error(), "out of range") {
+		t.Errorf("expected syntax error, got %v", err)
+	}
+}
+
+func TestUnmarshalCountFlag(t *testing.T) {
+	type logger struct {
+		Verbose int   `caddyfile:"verbose,flag"`
+		Quiet   uint8 `caddyfile:"quiet,flag"`
+	}
+
+	v, err := unmarshalString[logger]("logger {\n verbose\n verbose\n verbose\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Verbose != 3 || v.Quiet != 0 {
+		t.Errorf("unexpected counts: %+v", v)
+	}
+
+	_, err = unmarshalString[logger]("logger {\n verbose 2\n}")
+	if err == nil || !strings.Contains(err.Error(), `unexpected argument at "verbose": 2`) {
+		t.Errorf("expected unexpected argument error, got %v", err)
+	}
+
+	input := "logger {\n" + strings.Repeat(" quiet\n", 256) + "}"
+	_, err = unmarshalString[logger](input)
+	if err == nil || !strings.Contains(err.Error(), `"quiet" is given too many times`) {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+
+	syntax, err := SyntaxOf[logger]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub := syntax.Subdirectives[0]; sub.Type != "flag" || !sub.Repeated {
+		t.Errorf("unexpected syntax: %+v", sub)
+	}
+}
@@ -0,0 +1,113 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Optional holds a value that may be left out of the Caddyfile. Unlike a
+// pointer, it tells an unset value apart from a zero one without the nil
+// checks, e.g.
+//
+//	Compress caddyunmarshal.Optional[bool] `caddyfile:"compress"`
+//
+// is unset if compress is not given, and set to false for "compress false".
+// Optional fields are unmarshaled just like a field of type T would be. They
+// encode to JSON as their value, or null if unset.
+type Optional[T any] struct {
+	value   T
+	set     bool
+	cleared bool
+}
+
+// Some returns an Optional that is set to v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{value: v, set: true}
+}
+
+// IsSet returns true if the value was given.
+func (o Optional[T]) IsSet() bool {
+	return o.set
+}
+
+// IsCleared returns true if the value was explicitly cleared using the
+// sentinel of the allownone option, in which case it is set to the zero value
+// of T.
+func (o Optional[T]) IsCleared() bool {
+	return o.cleared
+}
+
+// Get returns the value, which is the zero value of T if unset.
+func (o Optional[T]) Get() T {
+	return o.value
+}
+
+// GetOr returns the value if set, or def otherwise.
+func (o Optional[T]) GetOr(def T) T {
+	if !o.set {
+		return def
+	}
+	return o.value
+}
+
+// Set sets the value.
+func (o *Optional[T]) Set(v T) {
+	o.value = v
+	o.set = true
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.set {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (o *Optional[T]) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*o = Optional[T]{}
+		return nil
+	}
+	if err := json.Unmarshal(b, &o.value); err != nil {
+		return err
+	}
+	o.set = true
+	return nil
+}
+
+func (o *Optional[T]) optionalValue() any { return &o.value }
+func (o *Optional[T]) markSet()           { o.set = true }
+func (o *Optional[T]) markCleared()       { o.set, o.cleared = true, true }
+
+// optionalField is implemented by pointers to Optional.
+type optionalField interface {
+	optionalValue() any
+	markSet()
+	markCleared()
+}
+
+var typeOptionalField = reflect.TypeOf((*optionalField)(nil)).Elem()
+
+// isOptional returns true if the given type is an Optional[T].
+func isOptional(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && reflect.PointerTo(t).Implements(typeOptionalField)
+}
+
+// optionalElem returns T if the given type is an Optional[T], or the type
+// itself otherwise.
+func optionalElem(t reflect.Type) reflect.Type {
+	if !isOptional(t) {
+		return t
+	}
+	return t.Field(0).Type
+}
+
+// unwrapOptional returns the value held by the Optional r, and a function
+// marking it as set.
+func unwrapOptional(r reflectValue) (reflectValue, func()) {
+	o := r.v.Addr().Interface().(optionalField)
+	v := reflect.ValueOf(o.optionalValue()).Elem()
+	return reflectValue{v, v.Type()}, o.markSet
+}
@@ -0,0 +1,92 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type optionalServer struct {
+	Host     string                  `caddyfile:"$1"`
+	Port     Optional[int]           `caddyfile:"$2"`
+	Compress Optional[bool]          `caddyfile:"compress"`
+	Retries  Optional[int]           `caddyfile:"retries"`
+	Timeout  Optional[time.Duration] `caddyfile:"timeout"`
+}
+
+func TestUnmarshalOptional(t *testing.T) {
+	v, err := unmarshalString[optionalServer]("server localhost {\n compress false\n retries 0\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Port.IsSet() || v.Port.GetOr(80) != 80 {
+		t.Errorf("unexpected port: %+v", v.Port)
+	}
+	if !v.Compress.IsSet() || v.Compress.Get() {
+		t.Errorf("unexpected compress: %+v", v.Compress)
+	}
+	if !v.Retries.IsSet() || v.Retries.Get() != 0 {
+		t.Errorf("unexpected retries: %+v", v.Retries)
+	}
+	if v.Timeout.IsSet() {
+		t.Errorf("unexpected timeout: %+v", v.Timeout)
+	}
+
+	v, err = unmarshalString[optionalServer]("server localhost 8080 {\n compress\n timeout 5s\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Port != Some(8080) || v.Compress != Some(true) || v.Timeout != Some(5*time.Second) {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	_, err = unmarshalString[optionalServer]("server localhost {\n retries many\n}")
+	if err == nil || !strings.Contains(err.Error(), "server > retries: ") {
+		t.Errorf("expected retries error, got %v", err)
+	}
+}
+
+func TestOptionalJSON(t *testing.T) {
+	b, err := json.Marshal(optionalServer{Host: "a", Retries: Some(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = `{"Host":"a","Port":null,"Compress":null,"Retries":0,"Timeout":null}`
+	if string(b) != expect {
+		t.Errorf("unexpected JSON: %s", b)
+	}
+
+	var v optionalServer
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Port.IsSet() || v.Retries != Some(0) {
+		t.Errorf("unexpected value: %+v", v)
+	}
+}
+
+func TestMarshalOptional(t *testing.T) {
+	v := optionalServer{Host: "localhost", Compress: Some(false), Retries: Some(0)}
+
+	b, err := Marshal("server", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "server localhost {\n\tcompress false\n\tretries 0\n}\n"
+	if string(b) != expect {
+		t.Errorf("unexpected output:\n%s", b)
+	}
+
+	usage, err := Usage[optionalServer]("server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(usage, "server <host> [<port>] {") || !strings.Contains(usage, "\tretries <int>\n") {
+		t.Errorf("unexpected usage:\n%s", usage)
+	}
+}
@@ -0,0 +1,145 @@
+package caddyunmarshal
+
+import (
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"go.uber.org/zap"
+)
+
+// Options configures how a Caddyfile is unmarshaled. The zero value is the
+// default behavior of Unmarshal.
+type Options struct {
+	// Strict rejects unknown subdirectives instead of skipping over them.
+	Strict bool
+	// FieldName converts the Go name of a field to its subdirective name.
+	// It is used for fields that have no name in their tag nor in their json
+	// tag. If nil, field names are converted to snake_case, e.g. "MaxConns"
+	// becomes "max_conns".
+	FieldName func(string) string
+	// TagKey is the key of the struct tag to read. If empty, "caddyfile" is
+	// used. Functions that take no Options, such as Usage and SyntaxOf,
+	// always read "caddyfile" tags; their WithOptions variants take it into
+	// account.
+	TagKey string
+	// MaxTokenLength overrides the package-level MaxTokenLength if non-zero.
+	// A negative value disables the check.
+	MaxTokenLength int
+	// Replacer, if not nil, is used to replace known placeholders within
+	// argument values before they are parsed. Unknown placeholders are left
+	// as-is, since they may only be known at runtime.
+	Replacer *caddy.Replacer
+	// ImportArgs replaces the placeholders of import arguments, {args[N]}
+	// or {args.N}, within argument values. caddyfile.Parse already replaces
+	// them when a snippet is imported, so this is for tokens that don't go
+	// through it, e.g. those of custom adapters. Placeholders that are left
+	// unresolved are kept in strings, and reported as
+	// ErrUnresolvedImportArg for other values.
+	ImportArgs []string
+	// Tracer, if not nil, is reported every decision made while
+	// unmarshaling.
+	Tracer Tracer
+	// Logger, if not nil, logs every decision made while unmarshaling at the
+	// debug level. It may be used along with Tracer.
+	Logger *zap.Logger
+	// WeaklyTyped accepts forgiving conversions for configurations migrated
+	// from systems with looser types: numbers surrounded by spaces, integers
+	// written as whole floats (e.g. "1e3"), booleans written as 1 or 0, and
+	// durations written as plain numbers in DurationUnit.
+	WeaklyTyped bool
+	// DurationUnit is the unit of durations written as plain numbers when
+	// WeaklyTyped is set. If zero, seconds are used.
+	DurationUnit time.Duration
+	// Warnings, if not nil, collects warnings such as uses of deprecated
+	// fields. If nil, warnings are logged instead.
+	Warnings *[]caddyconfig.Warning
+	// ReplaceSlices makes slices given in the Caddyfile replace the slices
+	// already in the value, such as defaults, rather than append to them.
+	// Fields may override this using the append and replace options.
+	ReplaceSlices bool
+}
+
+// UnmarshalWithOptions is like Unmarshal, except the given options are used.
+func UnmarshalWithOptions[T any](d *caddyfile.Dispenser, v *T, opts Options) error {
+	r, err := newReflectValue(v)
+	if err != nil {
+		return err
+	}
+	return withPath(d.Val(), unmarshal(dispenser{Dispenser: d, options: &opts}, r))
+}
+
+// The methods below allow a nil *Options to be used as the defaults.
+
+func (o *Options) strict() bool {
+	return o != nil && o.Strict
+}
+
+func (o *Options) fieldName(name string) string {
+	if o == nil || o.FieldName == nil {
+		return snakeCase(name)
+	}
+	return o.FieldName(name)
+}
+
+func (o *Options) replaceSlices() bool {
+	return o != nil && o.ReplaceSlices
+}
+
+func (o *Options) weaklyTyped() bool {
+	return o != nil && o.WeaklyTyped
+}
+
+func (o *Options) durationUnit() time.Duration {
+	if o == nil || o.DurationUnit == 0 {
+		return time.Second
+	}
+	return o.DurationUnit
+}
+
+func (o *Options) tagKey() string {
+	if o == nil || o.TagKey == "" {
+		return "caddyfile"
+	}
+	return o.TagKey
+}
+
+func (o *Options) maxTokenLength() int {
+	switch {
+	case o == nil || o.MaxTokenLength == 0:
+		return MaxTokenLength
+	case o.MaxTokenLength < 0:
+		return 0
+	default:
+		return o.MaxTokenLength
+	}
+}
+
+func (o *Options) replace(raw string) string {
+	if o == nil {
+		return raw
+	}
+	raw = replaceImportArgs(raw, o.ImportArgs)
+	if o.Replacer == nil {
+		return raw
+	}
+	return o.Replacer.ReplaceKnown(raw, "")
+}
+
+func (o *Options) tracer() Tracer {
+	switch {
+	case o == nil:
+		return nil
+	case o.Logger == nil:
+		return o.Tracer
+	case o.Tracer == nil:
+		return ZapTracer(o.Logger)
+	default:
+		logger := ZapTracer(o.Logger)
+		return TracerFunc(func(ev TraceEvent) {
+			o.Tracer.Trace(ev)
+			logger.Trace(ev)
+		})
+	}
+}
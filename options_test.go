@@ -0,0 +1,150 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+func unmarshalStringWithOptions[T any](input string, opts Options) (T, error) {
+	var v T
+
+	d := caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	err := UnmarshalWithOptions(d, &v, opts)
+	return v, err
+}
+
+func TestUnmarshalWithOptions(t *testing.T) {
+	t.Run("strict", func(t *testing.T) {
+		_, err := unmarshalStringWithOptions[thing2]("thing2 a {\n unknown x\n}", Options{Strict: true})
+		if err == nil || !strings.Contains(err.Error(), `unknown subdirective "unknown"`) {
+			t.Errorf("expected unknown subdirective error, got %v", err)
+		}
+	})
+
+	t.Run("naming and tag key", func(t *testing.T) {
+		type server struct {
+			Host    string `cfg:"$1"`
+			MaxConn int
+			Ignored int `caddyfile:"-"`
+		}
+
+		v, err := unmarshalStringWithOptions[server]("server a {\n maxconn 5\n ignored 1\n}", Options{
+			FieldName: strings.ToLower,
+			TagKey:    "cfg",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v != (server{Host: "a", MaxConn: 5, Ignored: 1}) {
+			t.Errorf("unexpected value: %+v", v)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		if _, err := unmarshalStringWithOptions[thing2]("thing2 abcdef", Options{MaxTokenLength: 4}); err == nil {
+			t.Error("expected error for long token")
+		}
+
+		long := strings.Repeat("a", MaxTokenLength+1)
+		if _, err := unmarshalStringWithOptions[thing2]("thing2 "+long, Options{MaxTokenLength: -1}); err != nil {
+			t.Errorf("unexpected error with disabled limit: %v", err)
+		}
+	})
+
+	t.Run("replacer", func(t *testing.T) {
+		repl := caddy.NewEmptyReplacer()
+		repl.Set("upstream", "localhost:8080")
+
+		v, err := unmarshalStringWithOptions[thing2]("thing2 {upstream} {http.request.host}", Options{Replacer: repl})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v.Arg1 != "localhost:8080" || v.Arg2 != "{http.request.host}" {
+			t.Errorf("unexpected value: %+v", v)
+		}
+	})
+}
+
+func TestTagKeyWithOptions(t *testing.T) {
+	type upstream struct {
+		Dial   string `cfg:"$1,widget=text"`
+		Header string `cfg:"header" caddyfile:"other"`
+	}
+
+	type route struct {
+		Matcher caddy.ModuleMap `cfg:"$matcher"`
+		To      string          `cfg:"$1"`
+	}
+
+	opts := Options{TagKey: "cfg"}
+
+	d := caddyfile.NewTestDispenser("upstream localhost {\n header {http.request.host}\n}")
+	d.Next()
+
+	var v upstream
+	if err := UnmarshalMultiWithOptions(d, opts, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Dial != "localhost" || v.Header != "{http.request.host}" {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	fields, err := ReplacerFieldsWithOptions(&v, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 1 || fields[0].Path != "header" {
+		t.Errorf("unexpected placeholder fields: %+v", fields)
+	}
+
+	api := caddy.ModuleMap{"path": json.RawMessage(`["/api/*"]`)}
+	groups, err := GroupByMatcherWithOptions([]route{{Matcher: api}, {}, {Matcher: api}}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || len(groups[0].Values) != 2 {
+		t.Errorf("unexpected groups: %+v", groups)
+	}
+
+	metadata, err := MetadataOfWithOptions[upstream]("widget", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := map[string]any{"Dial": "text"}; !reflect.DeepEqual(metadata, expect) {
+		t.Errorf("expected metadata %v, got %v", expect, metadata)
+	}
+
+	syntax, err := SyntaxOfWithOptions[upstream](opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(syntax.Subdirectives) != 1 || syntax.Subdirectives[0].Name != "header" {
+		t.Errorf("unexpected subdirectives: %+v", syntax.Subdirectives)
+	}
+
+	usage, err := UsageWithOptions[upstream]("upstream", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expectUsage = "upstream <dial> {\n\theader <string>\n}\n"
+	if usage != expectUsage {
+		t.Errorf("unexpected usage:\n%s\nwant:\n%s", usage, expectUsage)
+	}
+
+	docs, err := DocsWithOptions[upstream]("upstream", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(docs, "| `header` |") || strings.Contains(docs, "other") {
+		t.Errorf("unexpected docs:\n%s", docs)
+	}
+}
@@ -0,0 +1,72 @@
+package caddyunmarshal
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// UnmarshalParallel unmarshals each of the remaining segments of the given
+// dispenser into its own T, using at most the given number of goroutines. If
+// workers is zero or less, GOMAXPROCS goroutines are used. For example, given
+//
+//	route /a {
+//		...
+//	}
+//	route /b {
+//		...
+//	}
+//
+// one T is returned for each route. Segments are independent of each other,
+// so this speeds up very large Caddyfiles. The values are returned in the
+// order of their segments, and if several segments fail, the error of the
+// first one is returned. UnmarshalAll is the serial counterpart.
+//
+// T must be safe to unmarshal concurrently, which holds unless its
+// caddyfile.Unmarshaler or PostUnmarshaler implementations share state.
+func UnmarshalParallel[T any](d *caddyfile.Dispenser, workers int) ([]T, error) {
+	var segments []caddyfile.Segment
+	for d.Next() {
+		segments = append(segments, d.NextSegment())
+	}
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(segments) {
+		workers = len(segments)
+	}
+
+	values := make([]T, len(segments))
+	errs := make([]error, len(segments))
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ix := range next {
+				sd := caddyfile.NewDispenser(segments[ix])
+				sd.Next()
+				errs[ix] = Unmarshal(sd, &values[ix])
+			}
+		}()
+	}
+
+	for ix := range segments {
+		next <- ix
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
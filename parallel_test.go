@@ -0,0 +1,71 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type parallelRoute struct {
+	Path    string `caddyfile:"$1"`
+	Respond string `caddyfile:"respond"`
+	Status  int    `caddyfile:"status"`
+}
+
+func parallelRoutes(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "route /%d {\n\trespond %d\n\tstatus 200\n}\n", i, i)
+	}
+	return b.String()
+}
+
+func TestUnmarshalParallel(t *testing.T) {
+	for _, workers := range []int{0, 1, 3, 100} {
+		d := caddyfile.NewTestDispenser(parallelRoutes(20))
+
+		routes, err := UnmarshalParallel[parallelRoute](d, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(routes) != 20 {
+			t.Fatalf("workers=%d: expected 20 routes, got %d", workers, len(routes))
+		}
+		for i, route := range routes {
+			expect := parallelRoute{fmt.Sprintf("/%d", i), fmt.Sprint(i), 200}
+			if route != expect {
+				t.Errorf("workers=%d: expected route %d to be %+v, got %+v", workers, i, expect, route)
+			}
+		}
+	}
+
+	routes, err := UnmarshalParallel[parallelRoute](caddyfile.NewTestDispenser(""), 0)
+	if err != nil || len(routes) != 0 {
+		t.Errorf("expected no routes, got %v, %v", routes, err)
+	}
+
+	input := parallelRoutes(5) + "route /x {\n\tstatus a\n}\nroute /y {\n\tstatus b\n}\n"
+	_, err = UnmarshalParallel[parallelRoute](caddyfile.NewTestDispenser(input), 0)
+	if err == nil || !strings.Contains(err.Error(), "Testfile:22") {
+		t.Errorf("expected error of the first failing segment, got %v", err)
+	}
+}
+
+func BenchmarkUnmarshalParallel(b *testing.B) {
+	tokens, err := caddyfile.Tokenize([]byte(parallelRoutes(1000)), "Benchfile")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := UnmarshalParallel[parallelRoute](caddyfile.NewDispenser(tokens), 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
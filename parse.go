@@ -0,0 +1,116 @@
+package caddyunmarshal
+
+import (
+	"strings"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// UnmarshalCaddyfile parses a complete Caddyfile using caddyfile.Parse, which
+// expands environment variables, snippets and imports, and unmarshals it into
+// the given struct value. This lets standalone applications use the Caddyfile
+// as their config format.
+//
+// The whole file is treated as the block of a directive named after the file.
+// The lines of the global options block become subdirectives, and so does
+// every server block, whose first key is taken as the subdirective name and
+// the other keys as its arguments. For example,
+//
+//	type Config struct {
+//		Debug    bool     `caddyfile:"debug"`
+//		Servers  []Server `caddyfile:"server"`
+//		Database Database `caddyfile:"database"`
+//	}
+//
+//	type Server struct {
+//		Listen string `caddyfile:"$1"`
+//		Root   string `caddyfile:"root"`
+//	}
+//
+// is unmarshaled from:
+//
+//	{
+//		debug
+//	}
+//
+//	server :8080 {
+//		root /srv/a
+//	}
+//
+//	server :8081 {
+//		root /srv/b
+//	}
+//
+//	database {
+//		...
+//	}
+//
+// Since caddyfile.Parse doesn't keep where keys are, they're reported on the
+// line above the start of their block.
+func UnmarshalCaddyfile[T any](filename string, input []byte, v *T) error {
+	blocks, err := caddyfile.Parse(filename, input)
+	if err != nil {
+		return err
+	}
+
+	d := caddyfile.NewDispenser(serverBlockTokens(filename, blocks))
+	d.Next()
+
+	return Unmarshal(d, v)
+}
+
+// serverBlockTokens lays out the given server blocks as the block of a
+// directive named after the file. Since the dispenser tells arguments and
+// blocks apart by their lines, the tokens standing in for keys and braces are
+// placed on lines of their own.
+func serverBlockTokens(filename string, blocks []caddyfile.ServerBlock) []caddyfile.Token {
+	tokens := []caddyfile.Token{
+		{File: filename, Text: filename},
+		{File: filename, Text: "{"},
+	}
+
+	for _, block := range blocks {
+		if len(block.Keys) == 0 {
+			// The global options block, whose lines are subdirectives as
+			// they are.
+			for _, segment := range block.Segments {
+				tokens = append(tokens, segment...)
+			}
+			continue
+		}
+
+		pos := nextLine(tokens)
+		if len(block.Segments) > 0 {
+			first := block.Segments[0][0]
+			pos = caddyfile.Token{File: first.File, Line: first.Line - 1}
+		}
+
+		for _, key := range block.Keys {
+			tokens = append(tokens, caddyfile.Token{File: pos.File, Line: pos.Line, Text: key})
+		}
+
+		if len(block.Segments) == 0 {
+			continue
+		}
+
+		tokens = append(tokens, caddyfile.Token{File: pos.File, Line: pos.Line, Text: "{"})
+		for _, segment := range block.Segments {
+			tokens = append(tokens, segment...)
+		}
+
+		end := nextLine(tokens)
+		tokens = append(tokens, caddyfile.Token{File: end.File, Line: end.Line, Text: "}"})
+	}
+
+	end := nextLine(tokens)
+	return append(tokens, caddyfile.Token{File: end.File, Line: end.Line, Text: "}"})
+}
+
+// nextLine returns the position of the line following the last token.
+func nextLine(tokens []caddyfile.Token) caddyfile.Token {
+	last := tokens[len(tokens)-1]
+	return caddyfile.Token{
+		File: last.File,
+		Line: last.Line + strings.Count(last.Text, "\n") + 1,
+	}
+}
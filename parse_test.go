@@ -0,0 +1,93 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"testing"
+)
+
+type parseConfig struct {
+	Debug    bool          `caddyfile:"debug"`
+	Servers  []parseServer `caddyfile:"server"`
+	Database struct {
+		DSN string `caddyfile:"dsn"`
+	} `caddyfile:"database"`
+	Cache []string `caddyfile:"cache"`
+}
+
+type parseServer struct {
+	Listen []string `caddyfile:"$1"`
+	Root   string   `caddyfile:"root"`
+	Port   int      `caddyfile:"port"`
+}
+
+func TestUnmarshalCaddyfile(t *testing.T) {
+	const input = `{
+	debug
+}
+
+(common) {
+	root /srv/common
+}
+
+server :8080 {
+	root /srv/a
+}
+
+server :8081, :8082 {
+	import common
+}
+
+database {
+	dsn "postgres://localhost"
+}
+
+cache memory`
+
+	var v parseConfig
+	if err := UnmarshalCaddyfile("Caddyfile", []byte(input), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := parseConfig{
+		Debug: true,
+		Servers: []parseServer{
+			{Listen: []string{":8080"}, Root: "/srv/a"},
+			{Listen: []string{":8081", ":8082"}, Root: "/srv/common"},
+		},
+		Cache: []string{"memory"},
+	}
+	expect.Database.DSN = "postgres://localhost"
+
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("expected %+v, got %+v", expect, v)
+	}
+}
+
+func TestUnmarshalCaddyfileErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{
+			"value",
+			"server :80 {\n\troot /srv\n\tport x\n}",
+			`Caddyfile > server > port: Caddyfile:3 - Error during parsing: cannot parse int: strconv.ParseInt: parsing "x": invalid syntax`,
+		},
+		{
+			"parse",
+			"server :80 {\n\troot /srv\n",
+			"Caddyfile:2 - Syntax error: Unexpected token '/srv', expecting '}'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v parseConfig
+			err := UnmarshalCaddyfile("Caddyfile", []byte(test.input), &v)
+			if err == nil || err.Error() != test.expect {
+				t.Errorf("expected error %q, got %v", test.expect, err)
+			}
+		})
+	}
+}
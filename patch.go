@@ -0,0 +1,117 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PatchOp is a single JSON Patch (RFC 6902) operation.
+type PatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ConfigPatch converts the given parsed struct value into a list of JSON Patch
+// operations that set each of its JSON fields on the module config located at
+// path. The path is a JSON Pointer relative to the root of the Caddy config,
+// e.g. "/apps/http/servers/srv0/routes/0/handle/0".
+//
+// The value is encoded using encoding/json, so its json struct tags (which
+// Caddy modules always have) decide the field names. Fields omitted by the
+// encoder (e.g. through omitempty) are removed, so that they don't keep their
+// previous values. Since removing a member that doesn't exist is an error,
+// each removal is preceded by adding the member as null. Members that aren't
+// fields of the struct, such as the module name, are left as is.
+//
+// The operations can be applied by tooling to the document returned by the
+// admin API's GET /config/ endpoint, or individually by sending each value to
+// /config followed by its path.
+func ConfigPatch(path string, v any) ([]PatchOp, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("caddyunmarshal: cannot encode config: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("caddyunmarshal: config %T is not a JSON object: %w", v, err)
+	}
+
+	var keys []string
+	if rv := reflect.Indirect(reflect.ValueOf(v)); rv.Kind() == reflect.Struct {
+		keys = jsonKeys(rv.Type())
+	}
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	path = strings.TrimSuffix(path, "/")
+
+	ops := make([]PatchOp, 0, len(keys))
+	for i, key := range keys {
+		if i > 0 && keys[i-1] == key {
+			continue
+		}
+
+		keyPath := path + "/" + escapePointer(key)
+		if value, ok := fields[key]; ok {
+			ops = append(ops, PatchOp{Op: "add", Path: keyPath, Value: value})
+			continue
+		}
+
+		ops = append(ops,
+			PatchOp{Op: "add", Path: keyPath, Value: json.RawMessage("null")},
+			PatchOp{Op: "remove", Path: keyPath},
+		)
+	}
+
+	return ops, nil
+}
+
+// jsonKeys returns the object keys that encoding/json may encode the fields
+// of the given struct type as, including those of embedded structs.
+func jsonKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				keys = append(keys, jsonKeys(ft)...)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		keys = append(keys, name)
+	}
+	return keys
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// escapePointer escapes the given key for use as a JSON Pointer reference
+// token.
+func escapePointer(key string) string {
+	return pointerEscaper.Replace(key)
+}
@@ -0,0 +1,96 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestConfigPatch(t *testing.T) {
+	type handler struct {
+		Root    string   `json:"root,omitempty"`
+		Hide    []string `json:"hide,omitempty"`
+		Browse  bool     `json:"browse,omitempty"`
+		Weird   string   `json:"a/b~c,omitempty"`
+		Ignored string   `json:"-"`
+	}
+
+	ops, err := ConfigPatch("/apps/http/servers/srv0/routes/0/handle/0/", handler{
+		Root:    "/var/www",
+		Hide:    []string{".git"},
+		Weird:   "x",
+		Ignored: "y",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []PatchOp{
+		{"add", "/apps/http/servers/srv0/routes/0/handle/0/a~1b~0c", json.RawMessage(`"x"`)},
+		{"add", "/apps/http/servers/srv0/routes/0/handle/0/browse", json.RawMessage(`null`)},
+		{"remove", "/apps/http/servers/srv0/routes/0/handle/0/browse", nil},
+		{"add", "/apps/http/servers/srv0/routes/0/handle/0/hide", json.RawMessage(`[".git"]`)},
+		{"add", "/apps/http/servers/srv0/routes/0/handle/0/root", json.RawMessage(`"/var/www"`)},
+	}
+	if !reflect.DeepEqual(ops, expect) {
+		t.Fatalf("unexpected ops:\ngot  %s\nwant %s", mustJSON(ops), mustJSON(expect))
+	}
+
+	if _, err := ConfigPatch("/", []string{"not", "an", "object"}); err == nil {
+		t.Fatal("expected error for non-object config")
+	}
+}
+
+func TestConfigPatchUnset(t *testing.T) {
+	type Common struct {
+		Level string `json:"level,omitempty"`
+	}
+	type handler struct {
+		Common
+		Root string `json:"root,omitempty"`
+		Hide []string
+	}
+
+	// The module name isn't a field, so it must survive the patch.
+	doc := map[string]any{
+		"handler": "file_server",
+		"level":   "debug",
+		"root":    "/var/www",
+		"Hide":    []any{".git"},
+	}
+
+	ops, err := ConfigPatch("/", handler{Root: "/srv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, op := range ops {
+		key := op.Path[1:]
+		switch op.Op {
+		case "add":
+			var value any
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				t.Fatal(err)
+			}
+			doc[key] = value
+		case "remove":
+			if _, ok := doc[key]; !ok {
+				t.Fatalf("remove of missing member %s", key)
+			}
+			delete(doc, key)
+		}
+	}
+
+	expect := map[string]any{"handler": "file_server", "root": "/srv", "Hide": nil}
+	if !reflect.DeepEqual(doc, expect) {
+		t.Errorf("unexpected patched config:\ngot  %v\nwant %v", doc, expect)
+	}
+}
+
+func mustJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
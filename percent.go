@@ -0,0 +1,51 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// percentScale returns the value that 100% stands for in fields with the
+// percent option, which is 1 by default, e.g.
+//
+//	SampleRate float64 `caddyfile:"sample_rate,percent"`   // 50% is 0.5
+//	Threshold  float64 `caddyfile:"threshold,percent=100"` // 50% is 50
+func percentScale(opts []string) (float64, bool, error) {
+	value, ok := optValue(opts, "percent")
+	if !ok {
+		return 1, hasOpt(opts, "percent"), nil
+	}
+
+	scale, err := strconv.ParseFloat(value, 64)
+	if err != nil || scale <= 0 {
+		return 0, true, fmt.Errorf("caddyunmarshal: invalid percent option %q", value)
+	}
+	return scale, true, nil
+}
+
+// parsePercent parses a float that may be given as a percentage, e.g. "50%".
+// Numbers without a percent sign are taken as they are.
+func parsePercent(raw string, scale float64, bits int) (float64, error) {
+	num := strings.TrimSuffix(raw, "%")
+	f, err := strconv.ParseFloat(num, bits)
+	if err != nil || num == raw {
+		return f, err
+	}
+
+	f = f * scale / 100
+	if bits == 32 {
+		f = float64(float32(f))
+	}
+	return f, nil
+}
+
+// formatPercent formats a float as a percentage, or as a plain number if the
+// percentage doesn't parse back into the same value.
+func formatPercent(f, scale float64, bits int) string {
+	text := strconv.FormatFloat(f*100/scale, 'g', -1, bits) + "%"
+	if parsed, err := parsePercent(text, scale, bits); err != nil || parsed != f {
+		return strconv.FormatFloat(f, 'g', -1, bits)
+	}
+	return text
+}
@@ -0,0 +1,70 @@
+package caddyunmarshal
+
+import (
+	"strings"
+	"testing"
+)
+
+type percentSampler struct {
+	Rate      float64 `caddyfile:"rate,percent"`
+	Threshold float32 `caddyfile:"threshold,percent=100"`
+	Ratio     float64 `caddyfile:"ratio"`
+}
+
+func TestUnmarshalPercent(t *testing.T) {
+	v, err := unmarshalString[percentSampler]("sampler {\n rate 12.5%\n threshold 80%\n ratio 0.25\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != (percentSampler{0.125, 80, 0.25}) {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	v, err = unmarshalString[percentSampler]("sampler {\n rate 0.5\n threshold 12.5\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != (percentSampler{0.5, 12.5, 0}) {
+		t.Errorf("expected plain numbers to be kept, got %+v", v)
+	}
+
+	tests := []string{
+		"sampler {\n ratio 50%\n}",
+		"sampler {\n rate %\n}",
+		"sampler {\n rate 50%%\n}",
+	}
+	for _, input := range tests {
+		if _, err := unmarshalString[percentSampler](input); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+
+	type invalid struct {
+		Rate float64 `caddyfile:"rate,percent=0"`
+	}
+
+	_, err = unmarshalString[invalid]("invalid {\n rate 50%\n}")
+	if err == nil || !strings.Contains(err.Error(), `invalid percent option "0"`) {
+		t.Errorf("expected invalid option error, got %v", err)
+	}
+}
+
+func TestMarshalPercent(t *testing.T) {
+	v := percentSampler{Rate: 0.125, Threshold: 80, Ratio: 0.25}
+
+	b, err := Marshal("sampler", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "sampler {\n\trate 12.5%\n\tthreshold 80%\n\tratio 0.25\n}\n"
+	if string(b) != expect {
+		t.Errorf("unexpected output:\n%s", b)
+	}
+
+	if text := formatPercent(0.1, 1, 64); text != "10%" {
+		t.Errorf("unexpected percentage: %s", text)
+	}
+}
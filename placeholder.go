@@ -0,0 +1,169 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+// PlaceholderField is a string value within an unmarshaled struct that
+// contains placeholders, as returned by ReplacerFields.
+type PlaceholderField struct {
+	// Path is the Caddyfile path of the value, made of subdirective names and
+	// argument names such as $1, e.g. "upstream.headers[X-Host]".
+	Path string
+	// Value is the raw value containing the placeholders.
+	Value string
+	// Placeholders lists the keys of the placeholders within Value, without
+	// their braces, e.g. "http.request.host".
+	Placeholders []string
+
+	set func(string)
+}
+
+// Replace replaces the placeholders within the value using the given
+// replacer, and stores the result back into the struct.
+func (f PlaceholderField) Replace(repl *caddy.Replacer) {
+	f.set(repl.ReplaceAll(f.Value, ""))
+}
+
+// ReplacerFields lists all string values of the given unmarshaled struct
+// pointer that contain placeholders, including those nested in structs,
+// slices and maps. Provision code can use it to validate the placeholder keys
+// against known variables, or to replace them all at once:
+//
+//	fields, err := caddyunmarshal.ReplacerFields(h)
+//	...
+//	for _, f := range fields {
+//		f.Replace(repl)
+//	}
+//
+// Only fields that are part of the Caddyfile syntax are considered.
+func ReplacerFields(v any) ([]PlaceholderField, error) {
+	return ReplacerFieldsWithOptions(v, Options{})
+}
+
+// ReplacerFieldsWithOptions is like ReplacerFields, except the fields are
+// found and named using the TagKey and FieldName of the given options.
+func ReplacerFieldsWithOptions(v any, opts Options) ([]PlaceholderField, error) {
+	r, err := newReflectValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []PlaceholderField
+	if err := collectPlaceholders(&opts, r.v, "", &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func collectPlaceholders(o *Options, v reflect.Value, path string, fields *[]PlaceholderField) error {
+	switch v.Kind() {
+	case reflect.String:
+		keys := placeholderKeys(v.String())
+		if len(keys) > 0 {
+			*fields = append(*fields, PlaceholderField{
+				Path:         path,
+				Value:        v.String(),
+				Placeholders: keys,
+				set:          v.SetString,
+			})
+		}
+
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			return collectPlaceholders(o, v.Elem(), path, fields)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := collectPlaceholders(o, v.Index(i), fmt.Sprintf("%s[%d]", path, i), fields); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values aren't addressable, so they're copied out and stored
+			// back once replaced.
+			key := iter.Key()
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+
+			var nested []PlaceholderField
+			elemPath := fmt.Sprintf("%s[%v]", path, key)
+			if err := collectPlaceholders(o, elem, elemPath, &nested); err != nil {
+				return err
+			}
+
+			for _, f := range nested {
+				set := f.set
+				f.set = func(s string) {
+					set(s)
+					v.SetMapIndex(key, elem)
+				}
+				*fields = append(*fields, f)
+			}
+		}
+
+	case reflect.Struct:
+		if isScalar(v.Type()) {
+			return nil
+		}
+
+		info, err := extractFields(reflectValue{v, v.Type()}, o)
+		if err != nil {
+			return fmt.Errorf("cannot extract fields: %w", err)
+		}
+
+		for _, field := range append(info.otherFields, info.blockFields...) {
+			if err := collectPlaceholders(o, field.value.v, joinPath(path, field.key()), fields); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// placeholderKeys returns the keys of all placeholders within s, e.g.
+// "http.request.host" for "{http.request.host}". Escaped braces are skipped.
+func placeholderKeys(s string) []string {
+	var keys []string
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++ // skip the escaped character
+			continue
+		case '{':
+		default:
+			continue
+		}
+
+		end := strings.IndexByte(s[i+1:], '}')
+		if end == -1 {
+			break
+		}
+
+		key := s[i+1 : i+1+end]
+		if key != "" && !strings.ContainsAny(key, " \t{") {
+			keys = append(keys, key)
+			i += end + 1
+		}
+	}
+
+	return keys
+}
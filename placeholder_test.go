@@ -0,0 +1,100 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+func TestPlaceholderKeys(t *testing.T) {
+	tests := []struct {
+		in     string
+		expect []string
+	}{
+		{"plain", nil},
+		{"{http.request.host}", []string{"http.request.host"}},
+		{"{a}-{b}", []string{"a", "b"}},
+		{`\{escaped} {real}`, []string{"real"}},
+		{"{unclosed", nil},
+		{"{} { spaced }", nil},
+	}
+
+	for _, test := range tests {
+		if keys := placeholderKeys(test.in); !reflect.DeepEqual(keys, test.expect) {
+			t.Errorf("placeholderKeys(%q) = %q, want %q", test.in, keys, test.expect)
+		}
+	}
+}
+
+func TestReplacerFields(t *testing.T) {
+	type upstream struct {
+		Dial    string            `caddyfile:"$1"`
+		Headers map[string]string `caddyfile:"headers"`
+	}
+
+	type target struct {
+		Root      string     `caddyfile:"$1"`
+		Upstreams []upstream `caddyfile:"upstream"`
+		Ignored   string     `caddyfile:"-"`
+		Plain     string     `caddyfile:"plain"`
+	}
+
+	v, err := unmarshalString[target](`
+		target {env.ROOT} {
+			plain value
+			upstream {http.vars.host}:80 {
+				headers {
+					X-Host {http.request.host}
+					X-Static static
+				}
+			}
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Ignored = "{ignored}"
+
+	fields, err := ReplacerFields(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type field struct {
+		Path, Value  string
+		Placeholders []string
+	}
+
+	var got []field
+	for _, f := range fields {
+		got = append(got, field{f.Path, f.Value, f.Placeholders})
+	}
+
+	expect := []field{
+		{"$1", "{env.ROOT}", []string{"env.ROOT"}},
+		{"upstream[0].$1", "{http.vars.host}:80", []string{"http.vars.host"}},
+		{"upstream[0].headers[X-Host]", "{http.request.host}", []string{"http.request.host"}},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("unexpected fields:\ngot  %+v\nwant %+v", got, expect)
+	}
+
+	repl := caddy.NewEmptyReplacer()
+	repl.Set("env.ROOT", "/srv")
+	repl.Set("http.vars.host", "backend")
+	repl.Set("http.request.host", "example.com")
+	for _, f := range fields {
+		f.Replace(repl)
+	}
+
+	if v.Root != "/srv" {
+		t.Errorf("unexpected root %q", v.Root)
+	}
+	if v.Upstreams[0].Dial != "backend:80" {
+		t.Errorf("unexpected dial %q", v.Upstreams[0].Dial)
+	}
+	if host := v.Upstreams[0].Headers["X-Host"]; host != "example.com" {
+		t.Errorf("unexpected header %q", host)
+	}
+}
@@ -0,0 +1,49 @@
+package caddyunmarshal
+
+import "reflect"
+
+var typePresent = reflect.TypeOf(map[string]bool(nil))
+
+// markPresent records the Go names of the given fields into the present field
+// of the struct, if any, e.g.
+//
+//	Set map[string]bool `caddyfile:",present"`
+//
+// Fields that weren't given are absent from the map, so that Provision can
+// tell values left at their zero value apart from configured ones, e.g.
+//
+//	if !h.Set["Timeout"] {
+//		h.Timeout = app.DefaultTimeout
+//	}
+//
+// The map is only allocated once a field is given. With UnmarshalMulti, each
+// target may have its own present field, which only records its own fields.
+func (info structInfo) markPresent(given map[string]bool) {
+	for _, present := range info.present {
+		for _, fields := range [][]fieldInfo{info.otherFields, info.blockFields} {
+			for _, field := range fields {
+				if given[field.key()] && sameOwner(field, present) {
+					setPresent(present.value, field)
+				}
+			}
+		}
+
+		matcher := info.matcher
+		if matcher != nil && given[matcher.key()] && sameOwner(*matcher, present) {
+			setPresent(present.value, *matcher)
+		}
+	}
+}
+
+// sameOwner returns true if both fields belong to the same struct. Composed
+// structs never share a type, since their fields would conflict.
+func sameOwner(a, b fieldInfo) bool {
+	return a.owner.t == b.owner.t
+}
+
+func setPresent(r reflectValue, field fieldInfo) {
+	if r.v.IsNil() {
+		r.v.Set(reflect.MakeMap(r.t))
+	}
+	r.v.SetMapIndex(reflect.ValueOf(field.field.Name), reflect.ValueOf(true))
+}
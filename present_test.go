@@ -0,0 +1,89 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+type presentPool struct {
+	Size int             `caddyfile:"size"`
+	Idle int             `caddyfile:"idle"`
+	Set  map[string]bool `caddyfile:",present"`
+}
+
+type presentClient struct {
+	Addr    string          `caddyfile:"$1"`
+	Port    int             `caddyfile:"$2,optional"`
+	Timeout string          `caddyfile:"timeout"`
+	Retries int             `caddyfile:"retries,default=3"`
+	Pool    presentPool     `caddyfile:"pool"`
+	Set     map[string]bool `caddyfile:",present"`
+}
+
+func TestUnmarshalPresent(t *testing.T) {
+	const input = "client localhost {\n\ttimeout 0s\n\tpool {\n\t\tidle 0\n\t}\n}"
+
+	v, err := unmarshalString[presentClient](input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]bool{"Addr": true, "Timeout": true, "Pool": true}
+	if !reflect.DeepEqual(v.Set, expect) {
+		t.Errorf("expected present fields %v, got %v", expect, v.Set)
+	}
+	if expect := map[string]bool{"Idle": true}; !reflect.DeepEqual(v.Pool.Set, expect) {
+		t.Errorf("expected present pool fields %v, got %v", expect, v.Pool.Set)
+	}
+
+	dec := MustNewDecoder[presentClient](Options{})
+	for i := 0; i < 2; i++ {
+		var v presentClient
+
+		d := caddyfile.NewTestDispenser("client localhost 80")
+		d.Next()
+
+		if err := dec.Decode(d, &v); err != nil {
+			t.Fatal(err)
+		}
+		if expect := map[string]bool{"Addr": true, "Port": true}; !reflect.DeepEqual(v.Set, expect) {
+			t.Errorf("decode %d: expected present fields %v, got %v", i, expect, v.Set)
+		}
+	}
+
+	type invalid struct {
+		Set map[string]struct{} `caddyfile:",present"`
+	}
+	_, err = unmarshalString[invalid]("client")
+	if err == nil || !strings.Contains(err.Error(), "must be a map[string]bool") {
+		t.Errorf("expected invalid present field error, got %v", err)
+	}
+}
+
+func TestUnmarshalPresentMatcher(t *testing.T) {
+	type handler struct {
+		Matcher caddyhttp.RawMatcherSets `caddyfile:"$matcher"`
+		Body    string                   `caddyfile:"$1"`
+		Set     map[string]bool          `caddyfile:",present"`
+	}
+
+	v, err := unmarshalHTTPString[handler]("respond /api/* hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := map[string]bool{"Matcher": true, "Body": true}; !reflect.DeepEqual(v.Set, expect) {
+		t.Errorf("expected present fields %v, got %v", expect, v.Set)
+	}
+
+	v, err = unmarshalHTTPString[handler]("respond hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := map[string]bool{"Body": true}; !reflect.DeepEqual(v.Set, expect) {
+		t.Errorf("expected present fields %v, got %v", expect, v.Set)
+	}
+}
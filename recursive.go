@@ -0,0 +1,48 @@
+package caddyunmarshal
+
+import "reflect"
+
+// isStructPointer returns true if the given type is a pointer to a struct that
+// is unmarshaled from its own line, such as a subdirective of type *T or the
+// elements of a []*T. These allow self-referential types like
+//
+//	type Rule struct {
+//		Match  string  `caddyfile:"match"`
+//		Nested []*Rule `caddyfile:"rule"`
+//		Else   *Rule   `caddyfile:"else"`
+//	}
+//
+// where the pointer is only allocated if the subdirective is given.
+func isStructPointer(t reflect.Type) bool {
+	return t.Kind() == reflect.Pointer &&
+		t.Elem().Kind() == reflect.Struct &&
+		!isScalar(t) && !isScalar(t.Elem())
+}
+
+// allocPointer allocates the struct that the given pointer points to if it's
+// nil, and returns the struct.
+func allocPointer(r reflectValue) reflectValue {
+	if r.v.IsNil() {
+		r.v.Set(reflect.New(r.t.Elem()))
+	}
+	return reflectValue{r.v.Elem(), r.t.Elem()}
+}
+
+// derefStructPointer returns the struct type that t points to if it's a
+// struct pointer, or t itself otherwise.
+func derefStructPointer(t reflect.Type) reflect.Type {
+	if isStructPointer(t) {
+		return t.Elem()
+	}
+	return t
+}
+
+// containsType returns true if t is in types.
+func containsType(types []reflect.Type, t reflect.Type) bool {
+	for _, seen := range types {
+		if seen == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package caddyunmarshal
+
+import "testing"
+
+type recursiveRule struct {
+	Match  string           `caddyfile:"match"`
+	Nested []*recursiveRule `caddyfile:"rule"`
+	Else   *recursiveRule   `caddyfile:"else"`
+}
+
+func TestUnmarshalRecursive(t *testing.T) {
+	v, err := unmarshalString[recursiveRule]("rules {\n" +
+		" match a\n" +
+		" rule {\n" +
+		"  match b\n" +
+		"  rule {\n" +
+		"   match c\n" +
+		"  }\n" +
+		" }\n" +
+		" else {\n" +
+		"  match d\n" +
+		" }\n" +
+		"}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Match != "a" || len(v.Nested) != 1 || v.Else == nil {
+		t.Fatalf("unexpected rules %+v", v)
+	}
+	if b := v.Nested[0]; b.Match != "b" || len(b.Nested) != 1 || b.Nested[0].Match != "c" || b.Else != nil {
+		t.Errorf("unexpected nested rule %+v", b)
+	}
+	if v.Else.Match != "d" || v.Else.Nested != nil {
+		t.Errorf("unexpected else rule %+v", v.Else)
+	}
+
+	v, err = unmarshalString[recursiveRule]("rules {\n match a\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Else != nil {
+		t.Errorf("expected else to be left nil, got %+v", v.Else)
+	}
+
+	_, err = unmarshalString[recursiveRule]("rules {\n rule {\n  else {\n   match a b\n  }\n }\n}")
+	if err == nil || err.Error() != "rules > rule > else > match: Testfile:4 - Error during parsing: unexpected argument at \"match\": b" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestMarshalRecursive(t *testing.T) {
+	v := recursiveRule{
+		Match:  "a",
+		Nested: []*recursiveRule{{Match: "b", Else: &recursiveRule{Match: "c"}}},
+	}
+
+	b, err := Marshal("rules", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "rules {\n" +
+		"\tmatch a\n" +
+		"\trule {\n" +
+		"\t\tmatch b\n" +
+		"\t\telse {\n" +
+		"\t\t\tmatch c\n" +
+		"\t\t}\n" +
+		"\t}\n" +
+		"}\n"
+	if string(b) != expect {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", b, expect)
+	}
+}
+
+func TestUsageRecursive(t *testing.T) {
+	usage, err := Usage[recursiveRule]("rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "rules {\n" +
+		"\tmatch <string>\n" +
+		"\trule ...\n" +
+		"\telse ...\n" +
+		"}\n"
+	if usage != expect {
+		t.Errorf("unexpected usage:\n%s\nwant:\n%s", usage, expect)
+	}
+
+	syntax, err := SyntaxOf[recursiveRule]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule := syntax.Subdirectives[1]; rule.Ref == "" || !rule.Repeated {
+		t.Errorf("expected rule to refer to its parent, got %+v", rule)
+	}
+}
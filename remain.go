@@ -0,0 +1,61 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+var typeRemain = reflect.TypeOf(map[string][]string(nil))
+
+// unmarshalRemain unmarshals an unknown subdirective into the map of the
+// remain field, e.g.
+//
+//	Options map[string][]string `caddyfile:",remain"`
+//
+// The subdirective name is the key, and its arguments are appended to the
+// value, so that plugins may forward options to an underlying library as they
+// were given. Subdirectives with a block can't be collected.
+func unmarshalRemain(d dispenser, r reflectValue) error {
+	name := d.Val()
+
+	args := d.RemainingArgs()
+	for i, arg := range args {
+		args[i] = d.options.replace(arg)
+	}
+
+	if d.openBlock() {
+		return d.WrapErr(fmt.Errorf("%w at unknown subdirective %q", ErrUnexpectedBlock, name))
+	}
+
+	if r.v.IsNil() {
+		r.v.Set(reflect.MakeMap(r.t))
+	}
+
+	m := r.v.Interface().(map[string][]string)
+	m[name] = append(m[name], args...)
+
+	return nil
+}
+
+// remain emits a line for each subdirective collected by the remain field,
+// sorted by name.
+func (e *encoder) remain(info structInfo) {
+	if info.remain == nil {
+		return
+	}
+
+	m := info.remain.value.v.Interface().(map[string][]string)
+
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		e.words(name)
+		e.words(m[name]...)
+		e.newline()
+	}
+}
@@ -0,0 +1,111 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type remainClient struct {
+	Addr    string              `caddyfile:"$1"`
+	Timeout string              `caddyfile:"timeout"`
+	Options map[string][]string `caddyfile:",remain"`
+}
+
+func TestUnmarshalRemain(t *testing.T) {
+	const input = "client localhost {\n" +
+		"\ttimeout 5s\n" +
+		"\tpool_size 10\n" +
+		"\tread_only\n" +
+		"\ttag a b\n" +
+		"\ttag c\n" +
+		"}"
+
+	expect := remainClient{
+		Addr:    "localhost",
+		Timeout: "5s",
+		Options: map[string][]string{
+			"pool_size": {"10"},
+			"read_only": nil,
+			"tag":       {"a", "b", "c"},
+		},
+	}
+
+	v, err := unmarshalStringWithOptions[remainClient](input, Options{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("expected %+v, got %+v", expect, v)
+	}
+
+	dec := MustNewDecoder[remainClient](Options{})
+	for i := 0; i < 2; i++ {
+		var v remainClient
+
+		d := caddyfile.NewTestDispenser(input)
+		d.Next()
+
+		if err := dec.Decode(d, &v); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, expect) {
+			t.Errorf("decode %d: expected %+v, got %+v", i, expect, v)
+		}
+	}
+
+	_, err = unmarshalString[remainClient]("client localhost {\n\tpool {\n\t\tsize 10\n\t}\n}")
+	if !errors.Is(err, ErrUnexpectedBlock) {
+		t.Errorf("expected unexpected block error, got %v", err)
+	}
+}
+
+func TestUnmarshalRemainInvalid(t *testing.T) {
+	type wrongType struct {
+		Options map[string]string `caddyfile:",remain"`
+	}
+	_, err := unmarshalString[wrongType]("client")
+	if err == nil || !strings.Contains(err.Error(), "must be a map[string][]string") {
+		t.Errorf("expected invalid remain field error, got %v", err)
+	}
+
+	type duplicate struct {
+		A map[string][]string `caddyfile:",remain"`
+		B map[string][]string `caddyfile:",remain"`
+	}
+	_, err = unmarshalString[duplicate]("client")
+	if err == nil || !strings.Contains(err.Error(), "duplicate remain field B") {
+		t.Errorf("expected duplicate remain field error, got %v", err)
+	}
+}
+
+func TestMarshalRemain(t *testing.T) {
+	out, err := Marshal("client", &remainClient{
+		Addr:    "localhost",
+		Options: map[string][]string{"tag": {"a", "b"}, "read_only": nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "client localhost {\n\tread_only\n\ttag a b\n}\n"
+	if string(out) != expect {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out, expect)
+	}
+
+	usage, err := Usage[remainClient]("client")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expectUsage = "client <addr> {\n" +
+		"\ttimeout <string>\n" +
+		"\t<subdirective> [<args...>]\n" +
+		"}\n"
+	if usage != expectUsage {
+		t.Errorf("unexpected usage:\n%s\nwant:\n%s", usage, expectUsage)
+	}
+}
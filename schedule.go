@@ -0,0 +1,238 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypeSchedule is the type of cron schedules, see Schedule.
+var TypeSchedule = reflect.TypeOf(Schedule{})
+
+// Schedule is a cron schedule, parsed from a standard five-field expression
+// such as "*/15 9-17 * * mon-fri" (minute, hour, day of month, month and day
+// of week) or one of @yearly, @annually, @monthly, @weekly, @daily, @midnight
+// and @hourly. Since the expression has spaces, it must be quoted as a single
+// argument, e.g.
+//
+//	Backup caddyunmarshal.Schedule `caddyfile:"backup"`
+//
+// is written as:
+//
+//	backup "0 3 * * *"
+//
+// As with cron, if both the day of month and the day of week are restricted,
+// then a day matching either of them is scheduled. Schedules are encoded as
+// text, so they can be kept in the JSON config of a module.
+type Schedule struct {
+	expr   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	anyDay bool // either dom or dow starts with *, so both must match
+}
+
+// scheduleField describes one of the fields of a cron expression.
+type scheduleField struct {
+	name     string
+	min, max int
+	names    []string // names of the values starting at min, if any
+}
+
+var scheduleFields = [5]scheduleField{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{
+		"jan", "feb", "mar", "apr", "may", "jun",
+		"jul", "aug", "sep", "oct", "nov", "dec",
+	}},
+	{"day of week", 0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+var scheduleDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSchedule parses a cron expression, see Schedule.
+func ParseSchedule(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 1 && strings.HasPrefix(fields[0], "@") {
+		descriptor, ok := scheduleDescriptors[strings.ToLower(fields[0])]
+		if !ok {
+			return Schedule{}, fmt.Errorf("unknown schedule %s", fields[0])
+		}
+		fields = strings.Fields(descriptor)
+	}
+
+	if len(fields) != len(scheduleFields) {
+		return Schedule{}, fmt.Errorf(
+			"expected %d fields (minute, hour, day of month, month, day of week), got %d",
+			len(scheduleFields), len(fields))
+	}
+
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := scheduleFields[i].parse(field)
+		if err != nil {
+			return Schedule{}, err
+		}
+		bits[i] = b
+	}
+
+	// Sunday may be given as either 0 or 7.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return Schedule{
+		expr:   strings.Join(strings.Fields(expr), " "),
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		anyDay: strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parse parses a comma-separated list of values, ranges and steps into a
+// bitset of the matching values.
+func (f scheduleField) parse(field string) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid %s step %q", f.name, stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			if lo, err = f.value(from); err != nil {
+				return 0, err
+			}
+			switch {
+			case isRange:
+				if hi, err = f.value(to); err != nil {
+					return 0, err
+				}
+			case !hasStep:
+				hi = lo
+			}
+
+			if lo > hi {
+				return 0, fmt.Errorf("invalid %s range %q", f.name, rangePart)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+// value parses a single number or name within the field's range.
+func (f scheduleField) value(raw string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(raw, name) {
+			return f.min + i, nil
+		}
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("invalid %s %q, must be between %d and %d", f.name, raw, f.min, f.max)
+	}
+	return n, nil
+}
+
+// Next returns the first time after t that the schedule matches, in the
+// location of t. It returns the zero time if the schedule never matches, e.g.
+// for February 30th.
+func (s Schedule) Next(t time.Time) time.Time {
+	if s.expr == "" {
+		return time.Time{}
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (s Schedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// IsZero returns true if the schedule was never set.
+func (s Schedule) IsZero() bool {
+	return s.expr == ""
+}
+
+// String returns the expression of the schedule.
+func (s Schedule) String() string {
+	return s.expr
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Schedule) MarshalText() ([]byte, error) {
+	return []byte(s.expr), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Schedule) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = Schedule{}
+		return nil
+	}
+
+	parsed, err := ParseSchedule(string(text))
+	if err != nil {
+		return err
+	}
+
+	*s = parsed
+	return nil
+}
@@ -0,0 +1,110 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2024, time.January, 15, 10, 7, 30, 0, time.UTC) // a Monday
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 1, 16, 3, 0, 0, 0, time.UTC)},
+		{"30 9-17 * * mon-fri", time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)},
+		{"0 0 * * sat,7", time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 */3 *", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * fri", time.Date(2024, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"5/20 10 * * *", time.Date(2024, 1, 15, 10, 25, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@Weekly", time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		s, err := ParseSchedule(test.expr)
+		if err != nil {
+			t.Errorf("%q: %v", test.expr, err)
+			continue
+		}
+		if next := s.Next(base); !next.Equal(test.next) {
+			t.Errorf("%q: next is %v, want %v", test.expr, next, test.next)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@sometimes",
+	}
+	for _, expr := range invalid {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
+
+func TestUnmarshalSchedule(t *testing.T) {
+	type job struct {
+		Name     string   `caddyfile:"$1"`
+		Schedule Schedule `caddyfile:"schedule"`
+	}
+
+	v, err := unmarshalString[job]("job backup {\n schedule \"0  3 * * *\"\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Schedule.String() != "0 3 * * *" {
+		t.Errorf("unexpected schedule: %q", v.Schedule)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded job
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != v {
+		t.Errorf("unexpected JSON round trip: %s", b)
+	}
+
+	out, err := Marshal("job", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "\tschedule `0 3 * * *`\n") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	_, err = unmarshalString[job]("job backup {\n schedule \"0 3 * *\"\n}")
+	if err == nil || !strings.Contains(err.Error(), "cannot parse schedule") {
+		t.Errorf("expected schedule error, got %v", err)
+	}
+
+	usage, err := Usage[job]("job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(usage, "schedule <schedule>") {
+		t.Errorf("unexpected usage:\n%s", usage)
+	}
+}
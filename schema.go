@@ -0,0 +1,103 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// Schema is the combined syntax of several structs, which allows a directive
+// to reuse a shared schema (e.g. common options) alongside its own without Go
+// embedding. Use ComposeSchemas to create one.
+type Schema struct {
+	types []reflect.Type
+}
+
+// ComposeSchemas composes the schemas of the given structs, which may be
+// given as values or pointers, e.g.
+//
+//	var schema = caddyunmarshal.MustComposeSchemas(CommonOptions{}, ExecOptions{})
+//
+// The schemas are composed the same way as UnmarshalMulti does, so an error is
+// returned if they conflict, e.g. if two structs declare the same
+// subdirective or argument index. Since this is usually done at registration
+// time, conflicts are caught before any Caddyfile is parsed.
+func ComposeSchemas(structs ...any) (*Schema, error) {
+	schema := &Schema{types: make([]reflect.Type, len(structs))}
+
+	for i, v := range structs {
+		t := reflect.TypeOf(v)
+		if t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("caddyunmarshal: expected struct value, got %T", v)
+		}
+		schema.types[i] = t
+	}
+
+	if _, err := schema.info(nil); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// MustComposeSchemas is like ComposeSchemas, except it panics on error.
+func MustComposeSchemas(structs ...any) *Schema {
+	schema, err := ComposeSchemas(structs...)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// info extracts the composed fields of the given targets. If targets is nil,
+// then zero values are used.
+func (s *Schema) info(targets []any) (structInfo, error) {
+	if targets == nil {
+		targets = make([]any, len(s.types))
+		for i, t := range s.types {
+			targets[i] = reflect.New(t).Interface()
+		}
+	}
+
+	if len(targets) != len(s.types) {
+		return structInfo{}, fmt.Errorf(
+			"caddyunmarshal: schema has %d structs, got %d targets", len(s.types), len(targets))
+	}
+
+	for i, target := range targets {
+		if t := reflect.TypeOf(target); t != reflect.PtrTo(s.types[i]) {
+			return structInfo{}, fmt.Errorf(
+				"caddyunmarshal: target %d must be *%s, got %T", i, s.types[i], target)
+		}
+	}
+
+	return extractMulti(targets, nil)
+}
+
+// Unmarshal unmarshals the given Caddyfile dispenser into the given targets,
+// which must be pointers to the schema's structs in the same order.
+func (s *Schema) Unmarshal(d *caddyfile.Dispenser, targets ...any) error {
+	if _, err := s.info(targets); err != nil {
+		return err
+	}
+	return UnmarshalMulti(d, targets...)
+}
+
+// Usage is like the top-level Usage, except the composed syntax is rendered.
+func (s *Schema) Usage(directive string) (string, error) {
+	info, err := s.info(nil)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := writeUsageInfo(&b, nil, 0, directive, info, nil); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,71 @@
+package caddyunmarshal
+
+import (
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type commonOptions struct {
+	Timeout string `caddyfile:"timeout"`
+	Verbose bool
+}
+
+type execOptions struct {
+	Command string   `caddyfile:"$1"`
+	Args    []string `caddyfile:"args"`
+}
+
+func TestComposeSchemas(t *testing.T) {
+	schema, err := ComposeSchemas(commonOptions{}, &execOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := caddyfile.NewTestDispenser("exec ls {\n args -l -a\n timeout 5s\n verbose\n}")
+	d.Next()
+
+	var common commonOptions
+	var exec execOptions
+	if err := schema.Unmarshal(d, &common, &exec); err != nil {
+		t.Fatal(err)
+	}
+
+	if common != (commonOptions{"5s", true}) {
+		t.Errorf("unexpected common options: %+v", common)
+	}
+	if exec.Command != "ls" || len(exec.Args) != 2 {
+		t.Errorf("unexpected exec options: %+v", exec)
+	}
+
+	if err := schema.Unmarshal(d, &exec, &common); err == nil {
+		t.Error("expected error for targets in the wrong order")
+	}
+
+	usage, err := schema.Usage("exec")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = `exec <command> {
+	timeout <string>
+	verbose
+	args <string>
+}
+`
+	if usage != expect {
+		t.Errorf("unexpected usage:\n%s\nwant:\n%s", usage, expect)
+	}
+
+	type conflicting struct {
+		Verbose string
+	}
+
+	if _, err := ComposeSchemas(commonOptions{}, conflicting{}); err == nil {
+		t.Error("expected error for conflicting subdirectives")
+	}
+
+	if _, err := ComposeSchemas(commonOptions{}, "not a struct"); err == nil {
+		t.Error("expected error for non-struct schema")
+	}
+}
@@ -0,0 +1,153 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TypeSchemaless is the type of schemaless values, which accept any
+// arguments and subdirectives. Either the target of Unmarshal or any of its
+// fields may be of this type.
+//
+// The arguments of a directive are stored as a []any of strings under the
+// "$args" key. Each subdirective within its block is stored under its name:
+// subdirectives without a block become a []any of their arguments, while
+// subdirectives with a block become a nested map, which again has its
+// arguments under "$args". For example,
+//
+//	mydir a b {
+//		key v1 v2
+//		flag
+//		sub x {
+//			nested 1
+//		}
+//	}
+//
+// becomes
+//
+//	map[string]any{
+//		"$args": []any{"a", "b"},
+//		"key":   []any{"v1", "v2"},
+//		"flag":  []any{},
+//		"sub": map[string]any{
+//			"$args":  []any{"x"},
+//			"nested": []any{"1"},
+//		},
+//	}
+//
+// Repeated subdirectives are merged: their arguments are appended, and their
+// blocks are merged key by key.
+var TypeSchemaless = reflect.TypeOf(map[string]any{})
+
+// argsKey is the key of the arguments within a schemaless value.
+const argsKey = "$args"
+
+// unmarshalSchemaless unmarshals the rest of the current line and its block
+// into the given map[string]any.
+func unmarshalSchemaless(d dispenser, r reflectValue) error {
+	if r.v.IsNil() {
+		r.v.Set(reflect.MakeMap(r.t))
+	}
+
+	m := r.v.Interface().(map[string]any)
+
+	args, block, err := parseSchemaless(d)
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		mergeSchemaless(m, argsKey, args)
+	}
+	for key, value := range block {
+		mergeSchemaless(m, key, value)
+	}
+
+	return nil
+}
+
+// parseSchemaless parses the arguments on the rest of the current line, as
+// well as the block that follows them. The block is nil if there's none.
+func parseSchemaless(d dispenser) (args []any, block map[string]any, err error) {
+	args = []any{}
+
+	for {
+		if d.openBlock() {
+			block = make(map[string]any)
+			break
+		}
+		if !d.NextArg() {
+			return args, nil, nil
+		}
+		if err := d.checkLength(d.Val(), nil); err != nil {
+			return nil, nil, d.WrapErr(err)
+		}
+		args = append(args, d.options.replace(d.Val()))
+	}
+
+	if err := parseSchemalessBlock(d, block); err != nil {
+		return nil, nil, err
+	}
+
+	if d.NextArg() {
+		return nil, nil, d.WrapErr(fmt.Errorf("%w after block: %s", ErrUnexpectedArgument, d.Val()))
+	}
+
+	return args, block, nil
+}
+
+// parseSchemalessBlock parses the block opened by openBlock into the given
+// map.
+func parseSchemalessBlock(d dispenser, block map[string]any) error {
+	if d.inEmptyBlock() {
+		return nil
+	}
+
+	for d.nextInBlock() {
+		name := d.Val()
+		if err := d.checkLength(name, nil); err != nil {
+			return d.WrapErr(err)
+		}
+
+		d.trace("entry", nil)
+
+		subArgs, subBlock, err := parseSchemaless(d)
+		if err != nil {
+			return errorAtName(name, err)
+		}
+
+		if subBlock == nil {
+			mergeSchemaless(block, name, subArgs)
+			continue
+		}
+
+		if len(subArgs) > 0 {
+			subBlock[argsKey] = subArgs
+		}
+		mergeSchemaless(block, name, subBlock)
+	}
+
+	return nil
+}
+
+// mergeSchemaless sets m[key] to value. If both the existing and the new
+// values are argument lists, then they are appended, and if both are maps,
+// then they are merged.
+func mergeSchemaless(m map[string]any, key string, value any) {
+	switch existing := m[key].(type) {
+	case []any:
+		if args, ok := value.([]any); ok {
+			m[key] = append(existing, args...)
+			return
+		}
+	case map[string]any:
+		if block, ok := value.(map[string]any); ok {
+			for k, v := range block {
+				mergeSchemaless(existing, k, v)
+			}
+			return
+		}
+	}
+
+	m[key] = value
+}
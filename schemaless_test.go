@@ -0,0 +1,69 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalSchemaless(t *testing.T) {
+	const input = `
+		mydir a b {
+			key v1 v2
+			key v3
+			flag
+			sub x {
+				nested 1
+			}
+			sub {
+				other 2
+			}
+			empty {}
+		}
+	`
+
+	v, err := unmarshalString[map[string]any](input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]any{
+		"$args": []any{"a", "b"},
+		"key":   []any{"v1", "v2", "v3"},
+		"flag":  []any{},
+		"sub": map[string]any{
+			"$args":  []any{"x"},
+			"nested": []any{"1"},
+			"other":  []any{"2"},
+		},
+		"empty": map[string]any{},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %#v\nwant %#v", v, expect)
+	}
+
+	type plugin struct {
+		Name   string         `caddyfile:"$1"`
+		Extra  map[string]any `caddyfile:"{2},optional"`
+		Config map[string]any `caddyfile:"config"`
+	}
+
+	p, err := unmarshalString[plugin]("plugin a {\n x 1\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Extra, map[string]any{"x": []any{"1"}}) {
+		t.Errorf("unexpected indexed block: %#v", p.Extra)
+	}
+
+	type wrapped struct {
+		Config map[string]any `caddyfile:"config"`
+	}
+
+	w, err := unmarshalString[wrapped]("wrapped {\n config c {\n y 2\n }\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(w.Config, map[string]any{"$args": []any{"c"}, "y": []any{"2"}}) {
+		t.Errorf("unexpected subdirective: %#v", w.Config)
+	}
+}
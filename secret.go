@@ -0,0 +1,152 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Redacted replaces the values of fields with the secret option, e.g.
+// `caddyfile:"password,secret"`, wherever they would otherwise appear, such as
+// in errors and traces.
+const Redacted = "REDACTED"
+
+// isSecret returns true if the given options mark the field as secret.
+func isSecret(opts []string) bool {
+	return hasOpt(opts, "secret")
+}
+
+// secretValueError is returned in place of any error from parsing the value
+// of a secret field. The value may have been read from a file or expanded
+// from a placeholder, so the original error isn't kept at all.
+type secretValueError struct {
+	t reflect.Type
+}
+
+func (err secretValueError) Error() string {
+	return fmt.Sprintf("invalid %s value %s", err.t, Redacted)
+}
+
+// unmarshalSecretValue is like unmarshalValue, except errors never contain
+// the value, whichever form it was given in.
+func unmarshalSecretValue(d dispenser, r reflectValue, raw string, opts []string) error {
+	plain := make([]string, 0, len(opts))
+	for _, opt := range opts {
+		if opt != "secret" {
+			plain = append(plain, opt)
+		}
+	}
+
+	if err := unmarshalValue(d, r, raw, plain); err != nil {
+		return d.WrapErr(secretValueError{r.t})
+	}
+	return nil
+}
+
+// redactedError replaces the secret values within the message of the wrapped
+// error. The wrapped error isn't exposed through Unwrap, since it may carry
+// the values elsewhere; only errors.Is sees through it.
+type redactedError struct {
+	err     error
+	secrets []string
+}
+
+func (err redactedError) Error() string {
+	secrets := append([]string(nil), err.secrets...)
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+
+	msg := err.err.Error()
+	for _, secret := range secrets {
+		if secret != "" {
+			msg = redactToken(msg, secret)
+		}
+	}
+	return msg
+}
+
+func (err redactedError) Is(target error) bool {
+	return errors.Is(err.err, target)
+}
+
+// redact wraps err so that the given secrets are redacted from its message.
+func redact(err error, secrets []string) error {
+	if err == nil || len(secrets) == 0 {
+		return err
+	}
+	return redactedError{err, secrets}
+}
+
+// redactToken replaces the occurrences of secret in msg that aren't part of
+// a longer word, so that short secrets don't mangle the rest of the message.
+func redactToken(msg, secret string) string {
+	var b strings.Builder
+	last := 0
+	for i := 0; i+len(secret) <= len(msg); {
+		j := strings.Index(msg[i:], secret)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(secret)
+		if (start == 0 || !isWordByte(msg[start-1]) || !isWordByte(secret[0])) &&
+			(end == len(msg) || !isWordByte(msg[end]) || !isWordByte(secret[len(secret)-1])) {
+			b.WriteString(msg[last:start])
+			b.WriteString(Redacted)
+			last = end
+			i = end
+		} else {
+			i = start + 1
+		}
+	}
+	b.WriteString(msg[last:])
+	return b.String()
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= utf8.RuneSelf ||
+		'0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// argSecrets returns the argument at the cursor as a secret if the field is
+// secret. Fixed-size arrays and variadic slices also take the arguments
+// following it.
+func (d dispenser) argSecrets(field fieldInfo) []string {
+	if !isSecret(field.opts) {
+		return nil
+	}
+
+	secrets := []string{d.Val()}
+	switch {
+	case field.value.t.Kind() == reflect.Array:
+		args := d.lineArgs()
+		if n := field.value.t.Len() - 1; len(args) > n {
+			args = args[:n]
+		}
+		secrets = append(secrets, args...)
+	case isVariadic(field):
+		secrets = append(secrets, d.lineArgs()...)
+	}
+
+	return secrets
+}
+
+// segmentSecrets returns the tokens following the subdirective name at the
+// cursor, including those of its block, if the field is secret.
+func (d dispenser) segmentSecrets(field fieldInfo) []string {
+	if !isSecret(field.opts) {
+		return nil
+	}
+
+	var secrets []string
+	for _, token := range d.segment()[1:] {
+		if token.Text != "{" && token.Text != "}" {
+			secrets = append(secrets, token.Text)
+		}
+	}
+
+	return secrets
+}
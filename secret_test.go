@@ -0,0 +1,129 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type secretTarget struct {
+	Token    string         `caddyfile:"$1,secret"`
+	Port     int            `caddyfile:"port,secret"`
+	Password string         `caddyfile:"password,secret,enum=a|b"`
+	Headers  map[string]int `caddyfile:"headers,secret"`
+	Plain    string         `caddyfile:"plain"`
+}
+
+func TestUnmarshalSecretErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		secret string
+	}{
+		{"subdirective", "target tok {\n port hunter2\n}", "hunter2"},
+		{"extra argument", "target tok {\n password a hunter2\n}", "hunter2"},
+		{"enum", "target tok {\n password hunter2\n}", "hunter2"},
+		{"block", "target tok {\n headers {\n  X-Key hunter2\n }\n}", "hunter2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := unmarshalString[secretTarget](test.input)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if strings.Contains(err.Error(), test.secret) {
+				t.Errorf("secret leaked into error: %v", err)
+			}
+			if !strings.Contains(err.Error(), Redacted) {
+				t.Errorf("error is not redacted: %v", err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalSecretResolvedErrors(t *testing.T) {
+	type target struct {
+		Pin  int    `caddyfile:"pin,allowfile,secret"`
+		Code int    `caddyfile:"code,secret"`
+		Key  string `caddyfile:"key,secret"`
+	}
+
+	path := filepath.Join(t.TempDir(), "pin")
+	if err := os.WriteFile(path, []byte("hunter2-secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CADDYUNMARSHAL_TEST_CODE", "hunter3-secret")
+
+	opts := Options{Replacer: caddy.NewReplacer()}
+
+	tests := []struct {
+		name   string
+		input  string
+		secret string
+	}{
+		{"file", "target {\n pin file:" + path + "\n}", "hunter2-secret"},
+		{"placeholder", "target {\n code {env.CADDYUNMARSHAL_TEST_CODE}\n}", "hunter3-secret"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := unmarshalStringWithOptions[target](test.input, opts)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if strings.Contains(err.Error(), test.secret) {
+				t.Errorf("secret leaked into error: %v", err)
+			}
+			if !strings.Contains(err.Error(), "invalid int value "+Redacted) {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			var numErr *strconv.NumError
+			if errors.As(err, &numErr) {
+				t.Errorf("unredacted error is exposed: %v", numErr)
+			}
+		})
+	}
+
+	t.Run("short", func(t *testing.T) {
+		_, err := unmarshalString[target]("target {\n key a b\n}")
+		if !errors.Is(err, ErrUnexpectedArgument) {
+			t.Fatalf("expected unexpected argument error, got %v", err)
+		}
+		if msg := err.Error(); strings.Contains(msg, " b") ||
+			!strings.Contains(msg, "parsing") || !strings.Contains(msg, "argument") {
+			t.Errorf("unexpected redaction: %v", err)
+		}
+	})
+}
+
+func TestUnmarshalSecretTrace(t *testing.T) {
+	var buf strings.Builder
+
+	d := caddyfile.NewTestDispenser("target s3cr3t {\n plain visible\n password a\n}")
+	d.Next()
+
+	var v secretTarget
+	if err := UnmarshalTrace(d, &v, TextTracer(&buf)); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Token != "s3cr3t" {
+		t.Errorf("unexpected token %q", v.Token)
+	}
+
+	trace := buf.String()
+	if strings.Contains(trace, "s3cr3t") {
+		t.Errorf("secret leaked into trace:\n%s", trace)
+	}
+	if !strings.Contains(trace, `argument "REDACTED"`) || !strings.Contains(trace, `subdirective "password"`) {
+		t.Errorf("trace is missing non-secret tokens:\n%s", trace)
+	}
+}
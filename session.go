@@ -0,0 +1,217 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+)
+
+// Position describes a location within a Caddyfile.
+type Position struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+func tokenPosition(d *caddyfile.Dispenser) Position {
+	return Position{d.File(), d.Line()}
+}
+
+// Session allows directives to refer to entities defined by other directives.
+// All directives are first unmarshaled using the session, then Resolve is
+// called to resolve the references between them.
+//
+// A definition is declared on a string field using the def option, e.g.
+//
+//	type Pool struct {
+//		Name string `caddyfile:"$1,def=pool"`
+//	}
+//
+// The struct containing the field is then defined under the field's value for
+// the given kind. A reference is declared using the ref option, e.g.
+//
+//	type Route struct {
+//		Pool *Pool `caddyfile:"use_pool,ref=pool"`
+//	}
+//
+// Reference fields take exactly one argument, which is the name of the
+// definition. They may either be a string, in which case they are only
+// validated, or a pointer to the defining struct, in which case they are set
+// to point to the defined value on Resolve.
+type Session struct {
+	defs      map[string]map[string]definition
+	refs      []reference
+	directive Position
+}
+
+type definition struct {
+	value reflect.Value // pointer to the defining struct
+	pos   Position
+}
+
+type reference struct {
+	kind      string
+	name      string
+	value     reflectValue
+	pos       Position
+	directive Position
+}
+
+// NewSession creates a new Session.
+func NewSession() *Session {
+	return &Session{
+		defs: make(map[string]map[string]definition),
+	}
+}
+
+// Unmarshal is like the top-level Unmarshal, except definitions and references
+// are recorded into the session.
+func (s *Session) Unmarshal(d *caddyfile.Dispenser, v any) error {
+	r, err := newReflectValue(v)
+	if err != nil {
+		return err
+	}
+	s.directive = tokenPosition(d)
+	return withPath(d.Val(), unmarshal(dispenser{Dispenser: d, session: s}, r))
+}
+
+// UnmarshalForHTTP is like the top-level UnmarshalForHTTP, except definitions
+// and references are recorded into the session.
+func (s *Session) UnmarshalForHTTP(h *httpcaddyfile.Helper, v any) error {
+	r, err := newReflectValue(v)
+	if err != nil {
+		return err
+	}
+	s.directive = tokenPosition(h.Dispenser)
+	return withPath(h.Val(), unmarshal(dispenser{Dispenser: h.Dispenser, http: h, session: s}, r))
+}
+
+// Resolve resolves all references recorded so far. All unresolved references
+// are reported as a ReferenceErrors, joined with the errors of references to
+// definitions of the wrong type. Resolved references are forgotten, so
+// Resolve may be called again once more directives are unmarshaled, in which
+// case only the unresolved references are retried.
+func (s *Session) Resolve() error {
+	var unresolved ReferenceErrors
+	var errs []error
+	var pending []reference
+
+	for _, ref := range s.refs {
+		def, ok := s.defs[ref.kind][ref.name]
+		if !ok {
+			unresolved = append(unresolved, &ReferenceError{
+				Kind:      ref.kind,
+				Name:      ref.name,
+				Pos:       ref.pos,
+				Directive: ref.directive,
+			})
+			pending = append(pending, ref)
+			continue
+		}
+
+		if ref.value.v.Kind() != reflect.Pointer {
+			continue
+		}
+
+		if !def.value.Type().AssignableTo(ref.value.t) {
+			errs = append(errs, fmt.Errorf(
+				"%s - Error during parsing: cannot assign %s %q defined at %s to %s",
+				ref.pos, ref.kind, ref.name, def.pos, ref.value.t))
+			continue
+		}
+
+		ref.value.v.Set(def.value)
+	}
+
+	s.refs = pending
+
+	if len(unresolved) > 0 {
+		errs = append([]error{unresolved}, errs...)
+	}
+
+	return joinErrors(errs...)
+}
+
+// ReferenceError is returned for a reference that has no matching definition.
+type ReferenceError struct {
+	Kind string
+	Name string
+	// Pos is the position of the reference.
+	Pos Position
+	// Directive is the position of the directive containing the reference.
+	Directive Position
+}
+
+func (err *ReferenceError) Error() string {
+	return fmt.Sprintf(
+		"%s - Error during parsing: unresolved %s reference %q (in directive at %s)",
+		err.Pos, err.Kind, err.Name, err.Directive)
+}
+
+// ReferenceErrors is a list of ReferenceErrors.
+type ReferenceErrors []*ReferenceError
+
+func (errs ReferenceErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// reference records a reference to the entity of the given kind named name
+// into r. If no session is present, only string references are allowed.
+func (d dispenser) reference(kind string, r reflectValue, name string) error {
+	switch r.v.Kind() {
+	case reflect.String:
+		r.v.SetString(name)
+	case reflect.Pointer:
+		if d.session == nil {
+			return d.WrapErr(fmt.Errorf("cannot resolve %s reference %q without a Session", kind, name))
+		}
+	default:
+		return fmt.Errorf("reference must be a string or pointer, got %s", r.t)
+	}
+
+	if d.session != nil {
+		d.session.refs = append(d.session.refs, reference{
+			kind:      kind,
+			name:      name,
+			value:     r,
+			pos:       tokenPosition(d.Dispenser),
+			directive: d.session.directive,
+		})
+	}
+
+	return nil
+}
+
+// define records the struct value r as the entity of the given kind named
+// after the current token. Definitions are ignored if there is no session.
+func (d dispenser) define(kind string, r reflectValue) error {
+	if d.session == nil {
+		return nil
+	}
+
+	name := d.Val()
+	pos := tokenPosition(d.Dispenser)
+
+	defs, ok := d.session.defs[kind]
+	if !ok {
+		defs = make(map[string]definition)
+		d.session.defs[kind] = defs
+	}
+
+	if def, ok := defs[name]; ok {
+		return d.WrapErr(fmt.Errorf("duplicate %s definition %q (first defined at %s)", kind, name, def.pos))
+	}
+
+	defs[name] = definition{r.v.Addr(), pos}
+	return nil
+}
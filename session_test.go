@@ -0,0 +1,200 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type testPool struct {
+	Name string `caddyfile:"$1,def=pool"`
+	Size int    `caddyfile:"size"`
+}
+
+type testRoute struct {
+	Path     string    `caddyfile:"$1"`
+	Pool     *testPool `caddyfile:"use_pool,ref=pool"`
+	Fallback string    `caddyfile:"fallback,ref=pool"`
+}
+
+func unmarshalSession(t *testing.T, s *Session, input string) []*testRoute {
+	t.Helper()
+
+	var routes []*testRoute
+
+	d := caddyfile.NewTestDispenser(input)
+	for d.Next() {
+		var v any
+		switch d.Val() {
+		case "pool":
+			v = &testPool{}
+		case "route":
+			route := &testRoute{}
+			routes = append(routes, route)
+			v = route
+		default:
+			t.Fatalf("unexpected directive %q", d.Val())
+		}
+
+		if err := s.Unmarshal(d, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return routes
+}
+
+func TestSession(t *testing.T) {
+	s := NewSession()
+	routes := unmarshalSession(t, s, `
+		route /api {
+			use_pool backends
+			fallback spare
+		}
+		pool backends {
+			size 10
+		}
+		pool spare {
+			size 1
+		}
+	`)
+
+	if err := s.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+
+	route := routes[0]
+	if route.Pool == nil || route.Pool.Name != "backends" || route.Pool.Size != 10 {
+		t.Errorf("unexpected resolved pool: %+v", route.Pool)
+	}
+	if route.Fallback != "spare" {
+		t.Errorf("unexpected fallback: %q", route.Fallback)
+	}
+}
+
+func TestSessionUnresolved(t *testing.T) {
+	s := NewSession()
+	unmarshalSession(t, s, `
+		pool backends
+		route /api {
+			use_pool missing
+		}
+	`)
+
+	err := s.Resolve()
+
+	var refErrs ReferenceErrors
+	if !errors.As(err, &refErrs) || len(refErrs) != 1 {
+		t.Fatalf("expected one reference error, got %v", err)
+	}
+
+	refErr := refErrs[0]
+	if refErr.Name != "missing" || refErr.Pos.Line != 4 || refErr.Directive.Line != 3 {
+		t.Errorf("unexpected reference error: %v", refErr)
+	}
+}
+
+func TestSessionDuplicate(t *testing.T) {
+	s := NewSession()
+
+	d := caddyfile.NewTestDispenser(`
+		pool backends
+		pool backends
+	`)
+
+	var pool testPool
+	d.Next()
+	if err := s.Unmarshal(d, &pool); err != nil {
+		t.Fatal(err)
+	}
+	d.Next()
+	if err := s.Unmarshal(d, &pool); err == nil {
+		t.Fatal("expected duplicate definition error")
+	}
+}
+
+func TestSessionResolveErrors(t *testing.T) {
+	type cache struct {
+		Pool *testRoute `caddyfile:"$1,ref=pool"`
+	}
+
+	s := NewSession()
+	unmarshalSession(t, s, `
+		pool backends
+		route /api {
+			use_pool missing
+		}
+		route /other {
+			use_pool gone
+		}
+	`)
+
+	d := caddyfile.NewTestDispenser("cache backends")
+	d.Next()
+
+	var c cache
+	if err := s.Unmarshal(d, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.Resolve()
+
+	var refErrs ReferenceErrors
+	if !errors.As(err, &refErrs) || len(refErrs) != 2 {
+		t.Fatalf("expected two reference errors, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `cannot assign pool "backends"`) {
+		t.Errorf("expected type mismatch error, got %v", err)
+	}
+}
+
+func TestSessionResolveTwice(t *testing.T) {
+	s := NewSession()
+
+	var route testRoute
+	unmarshalInto := func(input string) {
+		t.Helper()
+		d := caddyfile.NewTestDispenser(input)
+		for d.Next() {
+			var v any = &testPool{}
+			if d.Val() == "route" {
+				route = testRoute{}
+				v = &route
+			}
+			if err := s.Unmarshal(d, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	unmarshalInto("pool backends\nroute /api {\n use_pool backends\n}")
+	if err := s.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	if route.Pool == nil || route.Pool.Name != "backends" {
+		t.Fatalf("unexpected resolved pool: %+v", route.Pool)
+	}
+
+	unmarshalInto("route /other")
+	if err := s.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	if route.Pool != nil {
+		t.Errorf("stale reference was applied again: %+v", route.Pool)
+	}
+
+	unmarshalInto("route /late {\n use_pool late\n}")
+	if err := s.Resolve(); err == nil {
+		t.Fatal("expected unresolved reference error")
+	}
+
+	unmarshalInto("pool late")
+	if err := s.Resolve(); err != nil {
+		t.Fatalf("expected unresolved reference to be retried, got %v", err)
+	}
+	if route.Pool == nil || route.Pool.Name != "late" {
+		t.Errorf("unexpected resolved pool: %+v", route.Pool)
+	}
+}
@@ -0,0 +1,119 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// isVariadic returns true if the positional argument field is a slice of
+// scalars, which takes the rest of the arguments on its line.
+func isVariadic(field fieldInfo) bool {
+	if _, ok := field.kind.(argumentKind); !ok {
+		return false
+	}
+
+	t := field.value.t
+	return t.Kind() == reflect.Slice && !isScalar(t) && isScalar(t.Elem())
+}
+
+// unmarshalVariadic unmarshals the current argument and the ones following it
+// on the same line, up to a block, into the slice r, e.g. "hosts a b c" into
+// a []string. Each element is unmarshaled on its own, so that e.g. a
+// []time.Duration takes durations. Flags and key=value arguments of the
+// struct described by info are left to the caller.
+func unmarshalVariadic(d dispenser, info structInfo, r reflectValue, opts []string) error {
+	for i := 0; ; i++ {
+		if err := appendElems(d, r, d.Val(), opts); err != nil {
+			return errorAtIndex(i, err)
+		}
+
+		if !d.NextArg() {
+			return nil
+		}
+		if d.Val() == "{" || d.inEmptyBlock() || info.isNamedArg(d) {
+			// The block is left to the caller.
+			d.Prev()
+			return nil
+		}
+	}
+}
+
+// isVariadicBlock returns true if the positional block field is tagged like
+// {2}..., which appends an element to its slice for each block from its
+// position on, e.g. a []map[string]string or a []Struct.
+func isVariadicBlock(field fieldInfo) bool {
+	kind, ok := field.kind.(blockKind)
+	return ok && kind.variadic
+}
+
+// unmarshalVariadicBlock unmarshals the block opened by openBlock into a new
+// element of the slice r.
+func unmarshalVariadicBlock(d dispenser, r reflectValue) error {
+	elem := reflect.New(r.t.Elem()).Elem()
+	value := reflectValue{elem, elem.Type()}
+
+	if elem.Kind() == reflect.Struct {
+		info, err := d.fields(value)
+		if err != nil {
+			return fmt.Errorf("cannot extract fields: %w", err)
+		}
+		if len(info.otherFields) > 0 || info.matcher != nil {
+			return fmt.Errorf("block of type %s cannot have positional fields", value.t)
+		}
+	}
+
+	if err := unmarshalBlock(d, value); err != nil {
+		return errorAtIndex(r.v.Len(), err)
+	}
+
+	r.v.Set(reflect.Append(r.v, elem))
+	return nil
+}
+
+// splitSeparator returns the separator given by the split option, which
+// splits each argument of a slice into several elements, e.g. "GET,POST"
+// with `caddyfile:"methods,split"`. The separator defaults to a comma, which
+// may also be given as split=, since the tag is cut at the comma anyway.
+// Other separators are given like split=';' or split=|.
+func splitSeparator(opts []string) (string, bool) {
+	sep, ok := optValue(opts, "split")
+	if !ok && !hasOpt(opts, "split") {
+		return "", false
+	}
+	if sep == "" {
+		sep = ","
+	}
+	return sep, true
+}
+
+// appendElems unmarshals the argument raw into a new element of the slice r,
+// or into several if it's split by the split option. Empty elements, e.g. of
+// a trailing comma, are skipped.
+func appendElems(d dispenser, r reflectValue, raw string, opts []string) error {
+	parts := []string{raw}
+	if sep, ok := splitSeparator(opts); ok {
+		parts = strings.Split(raw, sep)
+	}
+
+	for i, part := range parts {
+		if len(parts) > 1 {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+		}
+
+		elem := reflect.New(r.t.Elem()).Elem()
+		if err := unmarshalValue(d, reflectValue{elem, elem.Type()}, part, opts); err != nil {
+			if len(parts) > 1 {
+				return errorAtIndex(i, err)
+			}
+			return err
+		}
+
+		r.v.Set(reflect.Append(r.v, elem))
+	}
+
+	return nil
+}
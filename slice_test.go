@@ -0,0 +1,233 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+func TestUnmarshalVariadic(t *testing.T) {
+	type upstreams struct {
+		Policy string                 `caddyfile:"$1"`
+		To     []caddy.NetworkAddress `caddyfile:"$2"`
+		Retry  int                    `caddyfile:"retry"`
+	}
+
+	v, err := unmarshalString[upstreams]("upstreams first :80 localhost:8080 {\n retry 2\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Policy != "first" || len(v.To) != 2 || v.To[1].Host != "localhost" || v.To[1].StartPort != 8080 || v.Retry != 2 {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	_, err = unmarshalString[upstreams]("upstreams first :80 localhost:abc")
+	if err == nil || !strings.Contains(err.Error(), "upstreams > [1] > [1]: ") {
+		t.Errorf("expected element error, got %v", err)
+	}
+
+	_, err = unmarshalString[upstreams]("upstreams first")
+	if err == nil {
+		t.Error("expected error for missing variadic argument")
+	}
+
+	usage, err := Usage[upstreams]("upstreams")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(usage, "upstreams <policy> <to...> {") {
+		t.Errorf("unexpected usage:\n%s", usage)
+	}
+}
+
+func TestUnmarshalVariadicOptional(t *testing.T) {
+	type waits struct {
+		Waits []time.Duration `caddyfile:"$1,optional"`
+	}
+
+	v, err := unmarshalString[waits]("waits")
+	if err != nil || v.Waits != nil {
+		t.Fatalf("unexpected value %+v, error %v", v, err)
+	}
+
+	v, err = unmarshalString[waits]("waits 1s 2m {}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v.Waits, []time.Duration{time.Second, 2 * time.Minute}) {
+		t.Errorf("unexpected waits: %v", v.Waits)
+	}
+}
+
+func TestUnmarshalVariadicNotLast(t *testing.T) {
+	type invalid struct {
+		Hosts []string `caddyfile:"$1"`
+		Port  int      `caddyfile:"$2"`
+	}
+
+	_, err := unmarshalString[invalid]("invalid a b")
+	if err == nil || !strings.Contains(err.Error(), "illegal argument field 2 follows variadic field") {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
+
+func TestUnmarshalSliceSubdirectiveElements(t *testing.T) {
+	type timeouts struct {
+		Waits []time.Duration        `caddyfile:"waits"`
+		Ports []int                  `caddyfile:"ports"`
+		Binds []caddy.NetworkAddress `caddyfile:"bind"`
+	}
+
+	v, err := unmarshalString[timeouts]("timeouts {\n waits 1s 2m\n ports 80\n ports 443\n bind :80 udp/:443\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(v.Waits, []time.Duration{time.Second, 2 * time.Minute}) ||
+		!reflect.DeepEqual(v.Ports, []int{80, 443}) ||
+		len(v.Binds) != 2 || v.Binds[1].Network != "udp" {
+		t.Errorf("unexpected value: %+v", v)
+	}
+}
+
+func TestUnmarshalSplit(t *testing.T) {
+	type cors struct {
+		Origins []string        `caddyfile:"$1,split=,"`
+		Methods []string        `caddyfile:"methods,split"`
+		MaxAge  []time.Duration `caddyfile:"max_age,split=|"`
+		Headers []string        `caddyfile:"headers"`
+	}
+
+	v, err := unmarshalString[cors](
+		"cors a.com,b.com c.com {\n methods GET,POST, \"PUT, DELETE\"\n max_age 1m|2m\n headers X-A,X-B\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := cors{
+		Origins: []string{"a.com", "b.com", "c.com"},
+		Methods: []string{"GET", "POST", "PUT", "DELETE"},
+		MaxAge:  []time.Duration{time.Minute, 2 * time.Minute},
+		Headers: []string{"X-A,X-B"},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\n got %+v\nwant %+v", v, expect)
+	}
+
+	_, err = unmarshalString[cors]("cors a.com {\n max_age 1m|2x\n}")
+	if err == nil || !strings.Contains(err.Error(), `cors > max_age > [0] > [1]: `) {
+		t.Errorf("expected element error, got %v", err)
+	}
+
+	b, err := Marshal("cors", &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const output = "cors a.com,b.com,c.com {\n\tmethods GET,POST,PUT,DELETE\n\tmax_age 1m0s|2m0s\n\theaders X-A,X-B\n}\n"
+	if string(b) != output {
+		t.Errorf("unexpected output:\n%s", b)
+	}
+}
+
+func TestUnmarshalVariadicBlocks(t *testing.T) {
+	type stage struct {
+		Run  string `caddyfile:"run"`
+		Wait string `caddyfile:"wait"`
+	}
+	type pipeline struct {
+		Name   string            `caddyfile:"$1"`
+		Env    map[string]string `caddyfile:"{2}"`
+		Stages []stage           `caddyfile:"{3}...,optional"`
+	}
+
+	const input = "pipeline build {\n\tCGO_ENABLED 0\n} {\n\trun test\n} {\n\trun deploy\n\twait 5s\n}"
+
+	v, err := unmarshalString[pipeline](input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := pipeline{
+		Name:   "build",
+		Env:    map[string]string{"CGO_ENABLED": "0"},
+		Stages: []stage{{Run: "test"}, {Run: "deploy", Wait: "5s"}},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("expected %+v, got %+v", expect, v)
+	}
+
+	v, err = unmarshalString[pipeline]("pipeline build {\n\tCGO_ENABLED 0\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Stages) != 0 {
+		t.Errorf("expected no stages, got %+v", v.Stages)
+	}
+
+	_, err = unmarshalString[pipeline]("pipeline build {\n\tCGO_ENABLED 0\n} {\n\trun test\n} {\n\tunknown\n}")
+	if err != nil {
+		t.Errorf("expected unknown subdirective to be skipped, got %v", err)
+	}
+
+	out, err := Marshal("pipeline", &expect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != input+"\n" {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out, input)
+	}
+
+	usage, err := Usage[pipeline]("pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage != "pipeline <name> {...} [{...}...]\n" {
+		t.Errorf("unexpected usage %q", usage)
+	}
+}
+
+func TestUnmarshalVariadicBlocksMaps(t *testing.T) {
+	type headers struct {
+		Sets []map[string]string `caddyfile:"{1}..."`
+	}
+
+	v, err := unmarshalString[headers]("headers {\n\tA 1\n} {\n\tB 2\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []map[string]string{{"A": "1"}, {"B": "2"}}
+	if !reflect.DeepEqual(v.Sets, expect) {
+		t.Errorf("expected %v, got %v", expect, v.Sets)
+	}
+
+	_, err = unmarshalString[headers]("headers")
+	if !errors.Is(err, ErrMissingRequired) {
+		t.Errorf("expected missing required error, got %v", err)
+	}
+}
+
+func TestUnmarshalVariadicBlocksInvalid(t *testing.T) {
+	type notLast struct {
+		Sets []map[string]string `caddyfile:"{1}..."`
+		Last map[string]string   `caddyfile:"{2}"`
+	}
+	_, err := unmarshalString[notLast]("headers")
+	if err == nil || !strings.Contains(err.Error(), "illegal field 2 follows variadic block field") {
+		t.Errorf("expected variadic block not last error, got %v", err)
+	}
+
+	type notSlice struct {
+		Set map[string]string `caddyfile:"{1}..."`
+	}
+	_, err = unmarshalString[notSlice]("headers")
+	if err == nil || !strings.Contains(err.Error(), "must be a slice of blocks") {
+		t.Errorf("expected variadic block type error, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+var (
+	typeRouteList = reflect.TypeOf(caddyhttp.RouteList(nil))
+	typeSubroute  = reflect.TypeOf((*caddyhttp.Subroute)(nil))
+)
+
+// isSubroute returns true if the field is a block of HTTP handler directives,
+// given with the subroute option, e.g.
+//
+//	Routes caddyhttp.RouteList `caddyfile:"{2},subroute"`
+//
+// The block is parsed by httpcaddyfile.ParseSegmentAsSubroute, just like the
+// blocks of handle and route are, so named matchers may be defined within it.
+// The field is either a caddyhttp.RouteList or a *caddyhttp.Subroute, and
+// UnmarshalForHTTP must be given the Helper of the Caddyfile adapter.
+func isSubroute(opts []string) bool {
+	return hasOpt(opts, "subroute")
+}
+
+// unmarshalSubrouteLine unmarshals the block following a subroute
+// subdirective, which takes no arguments.
+func unmarshalSubrouteLine(d dispenser, r reflectValue) error {
+	name := d.Val()
+
+	if !d.openBlock() {
+		if d.NextArg() {
+			return d.WrapErr(fmt.Errorf("%w at %q: %s", ErrUnexpectedArgument, name, d.Val()))
+		}
+		return d.WrapErr(fmt.Errorf("expected block of directives after %q", name))
+	}
+
+	return unmarshalSubroute(d, r)
+}
+
+// unmarshalSubroute unmarshals the block opened by openBlock as a subroute.
+// The cursor is left at the closing brace.
+func unmarshalSubroute(d dispenser, r reflectValue) error {
+	if d.http == nil {
+		return fmt.Errorf("cannot unmarshal subroute: UnmarshalForHTTP was not called")
+	}
+	if r.t != typeRouteList && r.t != typeSubroute {
+		return fmt.Errorf(
+			"cannot unmarshal subroute: expected caddyhttp.RouteList or *caddyhttp.Subroute, got %s", r.t)
+	}
+
+	// ParseSegmentAsSubroute takes a segment of its own, so the block is
+	// given a name token on the line of its opening brace.
+	open := d.Token()
+	tokens := []caddyfile.Token{{File: open.File, Line: open.Line, Text: "subroute"}}
+
+	if !d.inEmptyBlock() {
+		tokens = append(tokens, open)
+		for nesting := 1; d.Next(); {
+			if !d.Token().Quoted() {
+				switch d.Val() {
+				case "{":
+					nesting++
+				case "}":
+					nesting--
+				}
+			}
+			tokens = append(tokens, d.Token())
+			if nesting == 0 {
+				break
+			}
+		}
+	}
+
+	handler, err := httpcaddyfile.ParseSegmentAsSubroute(d.http.WithDispenser(caddyfile.NewDispenser(tokens)))
+	if err != nil {
+		return err
+	}
+
+	subroute := handler.(*caddyhttp.Subroute)
+	if r.t == typeRouteList {
+		r.v.Set(reflect.ValueOf(subroute.Routes))
+	} else {
+		r.v.Set(reflect.ValueOf(subroute))
+	}
+
+	return nil
+}
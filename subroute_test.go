@@ -0,0 +1,116 @@
+package caddyunmarshal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+type subrouteWrap struct {
+	Name   string              `caddyfile:"$1"`
+	Routes caddyhttp.RouteList `caddyfile:"{2},subroute"`
+}
+
+type subrouteFallback struct {
+	Routes  *caddyhttp.Subroute `caddyfile:"routes,subroute"`
+	Timeout string              `caddyfile:"timeout"`
+}
+
+// subrouteTestParse is called by the caddyunmarshal_subroute_test directive.
+// Subroutes can only be parsed with a Helper given by the Caddyfile adapter,
+// so tests go through a whole Caddyfile.
+var subrouteTestParse func(h httpcaddyfile.Helper) error
+
+func init() {
+	httpcaddyfile.RegisterHandlerDirective("caddyunmarshal_subroute_test",
+		func(h httpcaddyfile.Helper) (caddyhttp.MiddlewareHandler, error) {
+			return new(testHandler), subrouteTestParse(h)
+		})
+}
+
+// adaptSubroute unmarshals the directive in input, which is placed within a
+// route block of a site, into a T using UnmarshalForHTTP.
+func adaptSubroute[T any](input string) (T, error) {
+	var v T
+	subrouteTestParse = func(h httpcaddyfile.Helper) error {
+		h.Next()
+		return UnmarshalForHTTP(&h, &v)
+	}
+
+	input = ":8080 {\n\troute {\n\t\tcaddyunmarshal_subroute_test " + input + "\n\t}\n}"
+
+	adapter := caddyfile.Adapter{ServerType: httpcaddyfile.ServerType{}}
+	_, _, err := adapter.Adapt([]byte(input), nil)
+	return v, err
+}
+
+func TestUnmarshalSubroute(t *testing.T) {
+	wrap, err := adaptSubroute[subrouteWrap](`a {
+		@api path /api/*
+		respond @api "api" {
+			close
+		}
+		respond "hi"
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wrap.Name != "a" || len(wrap.Routes) != 2 {
+		t.Fatalf("unexpected value %+v", wrap)
+	}
+	if len(wrap.Routes[0].MatcherSetsRaw) != 1 || len(wrap.Routes[1].MatcherSetsRaw) != 0 {
+		t.Errorf("expected only the first route to be matched, got %+v", wrap.Routes)
+	}
+
+	fallback, err := adaptSubroute[subrouteFallback]("{\n\troutes {\n\t\trespond 404\n\t}\n\ttimeout 5s\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fallback.Routes == nil || len(fallback.Routes.Routes) != 1 || fallback.Timeout != "5s" {
+		t.Errorf("unexpected value %+v", fallback)
+	}
+
+	_, err = unmarshalString[subrouteWrap]("wrap a {\n\trespond hi\n}")
+	if err == nil || !strings.Contains(err.Error(), "UnmarshalForHTTP was not called") {
+		t.Errorf("expected error without UnmarshalForHTTP, got %v", err)
+	}
+
+	_, err = adaptSubroute[subrouteWrap]("a {\n\tunknown\n}")
+	if err == nil || !strings.Contains(err.Error(), "unrecognized directive: unknown") {
+		t.Errorf("expected error for unknown directive, got %v", err)
+	}
+}
+
+func TestUsageSubroute(t *testing.T) {
+	usage, err := Usage[subrouteFallback]("fallback")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = "fallback {\n" +
+		"\troutes {\n" +
+		"\t\t<directives...>\n" +
+		"\t}\n" +
+		"\ttimeout <string>\n" +
+		"}\n"
+	if usage != expect {
+		t.Errorf("unexpected usage:\n%s\nwant:\n%s", usage, expect)
+	}
+
+	syntax, err := SyntaxOf[subrouteWrap]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arg := syntax.Arguments[1]; arg.Type != "subroute" {
+		t.Errorf("expected subroute argument, got %+v", arg)
+	}
+
+	if _, err := Marshal("fallback", &subrouteFallback{Routes: &caddyhttp.Subroute{}}); err == nil {
+		t.Error("expected error marshaling a subroute")
+	}
+}
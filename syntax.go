@@ -0,0 +1,315 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Syntax is a machine-readable description of the Caddyfile syntax of a
+// directive, an argument or a subdirective, as derived from struct tags. It is
+// meant to be consumed by tooling such as editors and language servers.
+type Syntax struct {
+	// Name is the name of the subdirective, or the placeholder name of the
+	// argument. It is empty for the directive itself.
+	Name string `json:"name,omitempty"`
+	// Type is the type of the value. It is one of the scalar types (string,
+	// int, uint, float, bool, duration, address, network_address, bytes,
+	// curve, public_key_algorithm, any), or:
+	//
+	//   - directive: the top-level directive
+	//   - matcher: an optional matcher token
+	//   - flag: a subdirective without arguments, whose occurrences are
+	//     counted if Repeated
+	//   - struct: a subdirective with its own arguments and subdirectives
+	//   - block: an indexed block, which only has subdirectives
+	//   - map: a block of key-value subdirectives, see Key and Value
+	//   - kv: like map, except the order and duplicates are kept
+	//   - keyed: a map whose entries are repeated subdirectives, taking the
+	//     key as their first argument, see Key and Value
+	//   - variant: one of Variants, selected by the first argument
+	//   - schemaless: any arguments and subdirectives
+	//   - subroute: a block of HTTP handler directives
+	//   - custom: parsed by a caddyfile.Unmarshaler
+	Type string `json:"type"`
+	// Ref is the name of the Go type if it was already described by a parent,
+	// in which case only Name, Type and Ref are set.
+	Ref string `json:"ref,omitempty"`
+	// Doc is the documentation given using the doc option.
+	Doc string `json:"doc,omitempty"`
+	// Default is the documented default value given using the default
+	// option.
+	Default string `json:"default,omitempty"`
+	// Deprecated is set if the deprecated option is given. It contains the
+	// note given to the option, or "deprecated" if there's none.
+	Deprecated string `json:"deprecated,omitempty"`
+	// Optional is true for optional arguments and blocks.
+	Optional bool `json:"optional,omitempty"`
+	// Required is true for subdirectives with the required option.
+	Required bool `json:"required,omitempty"`
+	// Aliases lists the other accepted names of a subdirective, given as
+	// e.g. `caddyfile:"name|alias"`.
+	Aliases []string `json:"aliases,omitempty"`
+	// Repeated is true for slice values, which take multiple arguments or
+	// occurrences.
+	Repeated bool `json:"repeated,omitempty"`
+	// Separator splits each argument of a repeated value into several
+	// elements, given using the split option.
+	Separator string `json:"separator,omitempty"`
+	// Length is the exact number of arguments of array values, or the exact
+	// number of bytes of encoded byte arrays.
+	Length int `json:"length,omitempty"`
+	// None is the argument that clears the value, given using the allownone
+	// option.
+	None string `json:"none,omitempty"`
+	// Enum lists the allowed values, if restricted.
+	Enum []string `json:"enum,omitempty"`
+	// Arguments lists the positional arguments and blocks in order.
+	Arguments []Syntax `json:"arguments,omitempty"`
+	// Primary is the name of the subdirective that trailing arguments are
+	// the shortcut for.
+	Primary string `json:"primary,omitempty"`
+	// KeyValue is true if subdirectives may also be given as name=value
+	// arguments, given using the keyvalue option.
+	KeyValue bool `json:"keyvalue,omitempty"`
+	// Flags is true if subdirectives may also be given as --name value
+	// arguments, given using the flags option.
+	Flags bool `json:"flags,omitempty"`
+	// Subdirectives lists the subdirectives within the block.
+	Subdirectives []Syntax `json:"subdirectives,omitempty"`
+	// Remain is true if unknown subdirectives are collected rather than
+	// rejected, given using the remain or extras option.
+	Remain bool `json:"remain,omitempty"`
+	// Key and Value describe the entries of map, kv and keyed types.
+	Key   *Syntax `json:"key,omitempty"`
+	Value *Syntax `json:"value,omitempty"`
+	// Variants maps variant names to their syntax.
+	Variants map[string]Syntax `json:"variants,omitempty"`
+	// Examples lists the example lines of a struct, see Exampler.
+	Examples []string `json:"examples,omitempty"`
+}
+
+// SyntaxJSON returns the Syntax of the directive described by T encoded as
+// indented JSON. The output follows the layout of Syntax and is not a JSON
+// Schema.
+func SyntaxJSON[T any]() ([]byte, error) {
+	return SyntaxJSONWithOptions[T](Options{})
+}
+
+// SyntaxJSONWithOptions is like SyntaxJSON, except the tags are read as
+// described by the given options, see SyntaxOfWithOptions.
+func SyntaxJSONWithOptions[T any](opts Options) ([]byte, error) {
+	syntax, err := SyntaxOfWithOptions[T](opts)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(syntax, "", "\t")
+}
+
+// SyntaxOf returns the Syntax of the directive described by T.
+func SyntaxOf[T any]() (Syntax, error) {
+	return SyntaxOfWithOptions[T](Options{})
+}
+
+// SyntaxOfWithOptions is like SyntaxOf, except the tags are read using the
+// TagKey and FieldName of the given options. The other options don't affect
+// the syntax.
+func SyntaxOfWithOptions[T any](opts Options) (Syntax, error) {
+	desc := syntaxDescriber{options: &opts}
+	return desc.directive(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+type syntaxDescriber struct {
+	options *Options
+	seen    []reflect.Type // struct types currently being described
+}
+
+func (desc *syntaxDescriber) directive(t reflect.Type) (Syntax, error) {
+	syntax, err := desc.structSyntax(t)
+	syntax.Type = "directive"
+	return syntax, err
+}
+
+func (desc *syntaxDescriber) structSyntax(t reflect.Type) (Syntax, error) {
+	if t == TypeSchemaless {
+		return Syntax{Type: "schemaless"}, nil
+	}
+
+	if containsType(desc.seen, t) {
+		return Syntax{Type: "struct", Ref: t.String()}, nil
+	}
+
+	desc.seen = append(desc.seen, t)
+	defer func() { desc.seen = desc.seen[:len(desc.seen)-1] }()
+
+	info, err := extractFields(zeroValue(t), desc.options)
+	if err != nil {
+		return Syntax{}, fmt.Errorf("cannot extract fields of %s: %w", t, err)
+	}
+
+	syntax := Syntax{Type: "struct", Examples: info.examples}
+
+	if info.matcher != nil {
+		syntax.Arguments = append(syntax.Arguments, Syntax{
+			Name:     "matcher",
+			Type:     "matcher",
+			Optional: true,
+		})
+	}
+
+	for _, field := range info.otherFields {
+		if kind, ok := field.kind.(argumentKind); ok && kind.matcher {
+			syntax.Arguments = append(syntax.Arguments, Syntax{
+				Name:     "matcher",
+				Type:     "matcher",
+				Optional: true,
+			})
+			continue
+		}
+
+		arg, err := desc.field(field)
+		if err != nil {
+			return Syntax{}, err
+		}
+
+		arg.Name = snakeCase(field.field.Name)
+		arg.Optional = field.optional()
+		if isBlockKind(field.kind) && arg.Type == "struct" {
+			arg.Type = "block"
+		}
+
+		syntax.Arguments = append(syntax.Arguments, arg)
+	}
+
+	if info.primary != nil {
+		syntax.Primary = info.primary.kind.(blockFieldKind).name
+	}
+
+	syntax.Remain = info.remain != nil || info.extras != nil
+	syntax.KeyValue = isKeyValue(info)
+	syntax.Flags = isFlags(info)
+
+	for _, field := range info.blockFields {
+		sub, err := desc.field(field)
+		if err != nil {
+			return Syntax{}, err
+		}
+
+		sub.Name = field.kind.(blockFieldKind).name
+		sub.Aliases = field.kind.(blockFieldKind).aliases
+		sub.Required = hasOpt(field.opts, "required")
+		if sub.Type == "bool" {
+			sub.Type = "flag"
+		}
+		if isCountFlag(optionalElem(field.field.Type), field.opts) {
+			sub.Type = "flag"
+			sub.Repeated = true
+		}
+
+		syntax.Subdirectives = append(syntax.Subdirectives, sub)
+	}
+
+	return syntax, nil
+}
+
+func (desc *syntaxDescriber) field(field fieldInfo) (Syntax, error) {
+	syntax := Syntax{Type: "subroute"}
+	if !isSubroute(field.opts) {
+		var err error
+		if syntax, err = desc.value(field.field.Type, field.opts); err != nil {
+			return Syntax{}, err
+		}
+	}
+
+	syntax.Doc, _ = optValue(field.opts, "doc")
+	syntax.Default, _ = optValue(field.opts, "default")
+	syntax.None, _ = noneSentinel(field.opts)
+	syntax.Separator, _ = splitSeparator(field.opts)
+
+	if note, ok := optValue(field.opts, "deprecated"); ok {
+		syntax.Deprecated = note
+	} else if hasOpt(field.opts, "deprecated") {
+		syntax.Deprecated = "deprecated"
+	}
+
+	return syntax, nil
+}
+
+func (desc *syntaxDescriber) value(t reflect.Type, opts []string) (Syntax, error) {
+	t = derefScalarPointer(derefStructPointer(optionalElem(t)))
+
+	switch {
+	case isUnmarshaler(t):
+		return Syntax{Type: "custom"}, nil
+
+	case isKVSlice(t):
+		value, err := desc.value(t.Elem().Field(1).Type, nil)
+		if err != nil {
+			return Syntax{}, err
+		}
+		return Syntax{Type: "kv", Key: &Syntax{Type: "string"}, Value: &value}, nil
+
+	case t == TypeSchemaless:
+		return Syntax{Type: "schemaless"}, nil
+
+	case isEncodedArray(t, opts):
+		return Syntax{Type: "bytes", Length: t.Len()}, nil
+
+	case t.Kind() == reflect.Array:
+		syntax, err := desc.value(t.Elem(), opts)
+		syntax.Length = t.Len()
+		return syntax, err
+
+	case t.Kind() == reflect.Slice && !isScalar(t):
+		syntax, err := desc.value(t.Elem(), opts)
+		syntax.Repeated = true
+		return syntax, err
+
+	case t.Kind() == reflect.Map:
+		key := Syntax{Type: typeName(t.Key())}
+		if !isTextKey(t.Key()) {
+			var err error
+			if key, err = desc.value(t.Key(), nil); err != nil {
+				return Syntax{}, err
+			}
+		}
+		value, err := desc.value(t.Elem(), nil)
+		if err != nil {
+			return Syntax{}, err
+		}
+		if hasOpt(opts, "keyed") {
+			return Syntax{Type: "keyed", Key: &key, Value: &value}, nil
+		}
+		return Syntax{Type: "map", Key: &key, Value: &value}, nil
+
+	case hasVariants(t):
+		syntax := Syntax{Type: "variant", Variants: make(map[string]Syntax)}
+		for _, name := range variantNames(t) {
+			impl, _ := lookupVariant(t, name)
+			if impl.Kind() == reflect.Pointer {
+				impl = impl.Elem()
+			}
+
+			variant, err := desc.structSyntax(impl)
+			if err != nil {
+				return Syntax{}, err
+			}
+			syntax.Variants[name] = variant
+		}
+		return syntax, nil
+
+	case t.Kind() == reflect.Struct && !isScalar(t):
+		return desc.structSyntax(t)
+	}
+
+	syntax := Syntax{Type: typeName(t)}
+	if enum, ok := optValue(opts, "enum"); ok && t.Kind() == reflect.String {
+		syntax.Enum = strings.Split(enum, "|")
+	}
+	if kind, ok := optValue(opts, "tls"); ok && t.Kind() == reflect.String {
+		syntax.Enum, _ = tlsNames(kind)
+	}
+
+	return syntax, nil
+}
@@ -0,0 +1,79 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSyntaxOf(t *testing.T) {
+	type health struct {
+		URI      string        `caddyfile:"$1"`
+		Interval time.Duration `caddyfile:",doc='time between checks'"`
+	}
+
+	type proxy struct {
+		To      string            `caddyfile:"$1"`
+		Extra   map[string]string `caddyfile:"{2},optional"`
+		Policy  string            `caddyfile:"lb_policy,enum=first|random"`
+		Health  health            `caddyfile:"health"`
+		Hide    []string          `caddyfile:"hide,deprecated=use hidden"`
+		Verbose bool
+	}
+
+	syntax, err := SyntaxOf[proxy]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := Syntax{
+		Type: "directive",
+		Arguments: []Syntax{
+			{Name: "to", Type: "string"},
+			{Name: "extra", Type: "map", Optional: true, Key: &Syntax{Type: "string"}, Value: &Syntax{Type: "string"}},
+		},
+		Subdirectives: []Syntax{
+			{Name: "lb_policy", Type: "string", Enum: []string{"first", "random"}},
+			{Name: "health", Type: "struct",
+				Arguments:     []Syntax{{Name: "uri", Type: "string"}},
+				Subdirectives: []Syntax{{Name: "interval", Type: "duration", Doc: "time between checks"}},
+			},
+			{Name: "hide", Type: "string", Repeated: true, Deprecated: "use hidden"},
+			{Name: "verbose", Type: "flag"},
+		},
+	}
+	if !reflect.DeepEqual(syntax, expect) {
+		t.Errorf("unexpected syntax:\ngot  %+v\nwant %+v", syntax, expect)
+	}
+
+	b, err := SyntaxJSON[proxy]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Syntax
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, expect) {
+		t.Errorf("JSON does not round-trip:\n%s", b)
+	}
+}
+
+type recursiveRoute struct {
+	Path   string           `caddyfile:"$1"`
+	Routes []recursiveRoute `caddyfile:"route"`
+}
+
+func TestSyntaxOfRecursive(t *testing.T) {
+	syntax, err := SyntaxOf[recursiveRoute]()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	route := syntax.Subdirectives[0]
+	if route.Type != "struct" || route.Ref == "" || !route.Repeated {
+		t.Errorf("unexpected recursive syntax: %+v", route)
+	}
+}
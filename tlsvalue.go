@@ -0,0 +1,120 @@
+package caddyunmarshal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2/modules/caddytls"
+)
+
+// TLS value types that are parsed from their names, e.g. "x25519" for a
+// tls.CurveID and "ecdsa" for a caddytls.PublicKeyAlgorithm.
+var (
+	TypeTLSCurve              = reflect.TypeOf(tls.CurveID(0))
+	TypeTLSPublicKeyAlgorithm = reflect.TypeOf(caddytls.PublicKeyAlgorithm(0))
+)
+
+// tlsClientAuthModes are the modes of caddytls.ClientAuthentication.
+var tlsClientAuthModes = []string{"request", "require", "verify_if_given", "require_and_verify"}
+
+// tlsNames returns the known names of the given kind of TLS value, as used by
+// the tls option on string fields, e.g. `caddyfile:"curves,tls=curve"`.
+func tlsNames(kind string) ([]string, error) {
+	var names []string
+
+	switch kind {
+	case "curve":
+		for name := range caddytls.SupportedCurves {
+			names = append(names, name)
+		}
+	case "cipher_suite":
+		for _, suite := range caddytls.SupportedCipherSuites() {
+			names = append(names, suite.Name)
+		}
+	case "protocol":
+		for name := range caddytls.SupportedProtocols {
+			names = append(names, name)
+		}
+	case "client_auth":
+		names = append(names, tlsClientAuthModes...)
+	default:
+		return nil, fmt.Errorf("unknown tls option %q", kind)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// validateTLSValue validates the raw value of a string field against the
+// known names given by its tls option, if any. The option takes curve,
+// cipher_suite, protocol or client_auth, matching the names that the caddytls
+// JSON config accepts.
+func validateTLSValue(raw string, opts []string) error {
+	kind, ok := optValue(opts, "tls")
+	if !ok {
+		return nil
+	}
+
+	names, err := tlsNames(kind)
+	if err != nil {
+		return err
+	}
+
+	if !hasOpt(names, raw) {
+		return fmt.Errorf("unknown %s %q, expected one of: %s",
+			strings.ReplaceAll(kind, "_", " "), raw, strings.Join(names, ", "))
+	}
+
+	return nil
+}
+
+// parseTLSValue parses the raw value into r, which is of one of the TLS value
+// types.
+func parseTLSValue(r reflectValue, raw string) error {
+	switch {
+	case r.t.AssignableTo(TypeTLSCurve):
+		curve, ok := caddytls.SupportedCurves[raw]
+		if !ok {
+			names, _ := tlsNames("curve")
+			return fmt.Errorf("unknown curve %q, expected one of: %s", raw, strings.Join(names, ", "))
+		}
+		r.v.Set(reflect.ValueOf(curve))
+
+	case r.t.AssignableTo(TypeTLSPublicKeyAlgorithm):
+		// PublicKeyAlgorithm only knows its names through JSON.
+		var algo caddytls.PublicKeyAlgorithm
+		if err := json.Unmarshal([]byte(strconv.Quote(raw)), &algo); err != nil {
+			return err
+		}
+		r.v.Set(reflect.ValueOf(algo))
+	}
+
+	return nil
+}
+
+// formatTLSValue returns the name of r, which is of one of the TLS value
+// types.
+func formatTLSValue(r reflectValue) (string, error) {
+	if r.t.AssignableTo(TypeTLSCurve) {
+		curve := tls.CurveID(r.v.Uint())
+		for name, id := range caddytls.SupportedCurves {
+			if id == curve {
+				return name, nil
+			}
+		}
+		return "", fmt.Errorf("unknown curve %d", curve)
+	}
+
+	// The names of PublicKeyAlgorithm are the lowercase x509 ones.
+	algo := x509.PublicKeyAlgorithm(r.v.Int())
+	if algo == x509.UnknownPublicKeyAlgorithm {
+		return "", fmt.Errorf("unknown public key algorithm")
+	}
+	return strings.ToLower(algo.String()), nil
+}
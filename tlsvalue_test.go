@@ -0,0 +1,66 @@
+package caddyunmarshal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/modules/caddytls"
+)
+
+func TestUnmarshalTLSValues(t *testing.T) {
+	type target struct {
+		Curves    []tls.CurveID               `caddyfile:"curves"`
+		Ciphers   []string                    `caddyfile:"ciphers,tls=cipher_suite"`
+		Protocols []string                    `caddyfile:"protocols,tls=protocol"`
+		Mode      string                      `caddyfile:"mode,tls=client_auth"`
+		Algorithm caddytls.PublicKeyAlgorithm `caddyfile:"algorithm"`
+	}
+
+	v, err := unmarshalString[target](`
+		target {
+			curves x25519 secp256r1
+			ciphers TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256
+			protocols tls1.2 tls1.3
+			mode require_and_verify
+			algorithm ecdsa
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := target{
+		Curves:    []tls.CurveID{tls.X25519, tls.CurveP256},
+		Ciphers:   []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+		Protocols: []string{"tls1.2", "tls1.3"},
+		Mode:      "require_and_verify",
+		Algorithm: caddytls.PublicKeyAlgorithm(x509.ECDSA),
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %+v\nwant %+v", v, expect)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"curve", "target {\n curves p256\n}", `unknown curve "p256", expected one of: secp256r1, secp384r1, secp521r1, x25519`},
+		{"protocol", "target {\n protocols tls1.0\n}", `unknown protocol "tls1.0", expected one of: tls1.2, tls1.3`},
+		{"client auth", "target {\n mode always\n}", `unknown client auth "always", expected one of: request, require, require_and_verify, verify_if_given`},
+		{"cipher suite", "target {\n ciphers RC4\n}", `unknown cipher suite "RC4"`},
+		{"algorithm", "target {\n algorithm ed448\n}", `unrecognized public key algorithm: ed448`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := unmarshalString[target](test.input)
+			if err == nil || !strings.Contains(err.Error(), "Testfile:2 - Error during parsing: "+test.err) {
+				t.Errorf("unexpected error:\ngot  %v\nwant %s", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package caddyunmarshal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"go.uber.org/zap"
+)
+
+// TraceEvent describes a single decision made while unmarshaling.
+type TraceEvent struct {
+	// Token is the token that the decision was made on.
+	Token string `json:"token"`
+	// Action is what was done with the token, e.g. "argument",
+	// "subdirective" or "skip".
+	Action string `json:"action"`
+	// Field is the struct field that the token was bound to, in the form of
+	// Type.Field. It is empty if the token was not bound to a field.
+	Field string `json:"field,omitempty"`
+	// Pos is the position of the token.
+	Pos Position `json:"position"`
+}
+
+// Tracer receives TraceEvents while unmarshaling.
+type Tracer interface {
+	Trace(TraceEvent)
+}
+
+// TracerFunc is a function that implements Tracer.
+type TracerFunc func(TraceEvent)
+
+// Trace implements Tracer.
+func (f TracerFunc) Trace(ev TraceEvent) { f(ev) }
+
+// TextTracer returns a Tracer that writes each event to w as a line of human
+// readable text.
+func TextTracer(w io.Writer) Tracer {
+	return TracerFunc(func(ev TraceEvent) {
+		if ev.Field == "" {
+			fmt.Fprintf(w, "%s: %s %q\n", ev.Pos, ev.Action, ev.Token)
+		} else {
+			fmt.Fprintf(w, "%s: %s %q -> %s\n", ev.Pos, ev.Action, ev.Token, ev.Field)
+		}
+	})
+}
+
+// JSONTracer returns a Tracer that writes each event to w as a line of JSON,
+// which is meant to be consumed by tooling.
+func JSONTracer(w io.Writer) Tracer {
+	enc := json.NewEncoder(w)
+	return TracerFunc(func(ev TraceEvent) { enc.Encode(ev) })
+}
+
+// ZapTracer returns a Tracer that logs each event to the given logger at the
+// debug level.
+func ZapTracer(logger *zap.Logger) Tracer {
+	return TracerFunc(func(ev TraceEvent) {
+		if ce := logger.Check(zap.DebugLevel, ev.Action); ce != nil {
+			ce.Write(
+				zap.String("token", ev.Token),
+				zap.String("field", ev.Field),
+				zap.String("file", ev.Pos.File),
+				zap.Int("line", ev.Pos.Line),
+			)
+		}
+	})
+}
+
+// UnmarshalTrace is like Unmarshal, except every decision is reported to the
+// given tracer. It is a shorthand for UnmarshalWithOptions with only the
+// Tracer option set.
+func UnmarshalTrace[T any](d *caddyfile.Dispenser, v *T, tracer Tracer) error {
+	return UnmarshalWithOptions(d, v, Options{Tracer: tracer})
+}
+
+// trace reports the given action on the current token to the tracer, if any.
+func (d dispenser) trace(action string, field *fieldInfo) {
+	tracer := d.options.tracer()
+	if tracer == nil {
+		return
+	}
+
+	ev := TraceEvent{
+		Token:  d.Val(),
+		Action: action,
+		Pos:    tokenPosition(d.Dispenser),
+	}
+	if field != nil {
+		ev.Field = field.owner.t.Name() + "." + field.field.Name
+		if isSecret(field.opts) && action != "subdirective" {
+			// the token is the value, rather than the subdirective name
+			ev.Token = Redacted
+		}
+	}
+
+	tracer.Trace(ev)
+}
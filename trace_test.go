@@ -0,0 +1,80 @@
+package caddyunmarshal
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestUnmarshalTrace(t *testing.T) {
+	const input = "thing2 arg1 {\n number 100\n unknown x\n}"
+
+	var buf bytes.Buffer
+
+	d := caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	var v thing2
+	if err := UnmarshalTrace(d, &v, JSONTracer(&buf)); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []TraceEvent
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var ev TraceEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+
+	expect := []TraceEvent{
+		{"arg1", "argument", "thing2.Arg1", Position{"Testfile", 1}},
+		{"{", "block", "", Position{"Testfile", 1}},
+		{"number", "subdirective", "thing2.Number", Position{"Testfile", 2}},
+		{"unknown", "skip", "", Position{"Testfile", 3}},
+	}
+	if !reflect.DeepEqual(events, expect) {
+		t.Errorf("unexpected events:\ngot  %+v\nwant %+v", events, expect)
+	}
+
+	buf.Reset()
+
+	d = caddyfile.NewTestDispenser(input)
+	d.Next()
+
+	if err := UnmarshalTrace(d, &v, TextTracer(&buf)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "Testfile:2: subdirective \"number\" -> thing2.Number\n") {
+		t.Errorf("unexpected text trace:\n%s", buf.String())
+	}
+}
+
+func TestUnmarshalZapLogger(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+
+	_, err := unmarshalStringWithOptions[thing2]("thing2 arg1 {\n number 100\n unknown x\n}", Options{
+		Logger: zap.New(core),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	skipped := logs.FilterMessage("skip").All()
+	if len(skipped) != 1 || skipped[0].ContextMap()["token"] != "unknown" {
+		t.Errorf("expected skipped subdirective to be logged, got %+v", skipped)
+	}
+
+	bound := logs.FilterMessage("subdirective").All()
+	if len(bound) != 1 || bound[0].ContextMap()["field"] != "thing2.Number" {
+		t.Errorf("expected bound subdirective to be logged, got %+v", bound)
+	}
+}
@@ -0,0 +1,250 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Usage returns the Caddyfile syntax of the given directive as described by
+// T, in the style of the Caddy documentation. Documentation given to fields
+// using the doc option (e.g. doc='timeout for each request') is rendered as a
+// comment above each subdirective.
+func Usage[T any](directive string) (string, error) {
+	return UsageWithOptions[T](directive, Options{})
+}
+
+// UsageWithOptions is like Usage, except the tags are read using the TagKey
+// and FieldName of the given options.
+func UsageWithOptions[T any](directive string, opts Options) (string, error) {
+	var b strings.Builder
+	if err := writeUsage(&b, &opts, 0, directive, reflect.TypeOf((*T)(nil)).Elem(), nil); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// zeroValue returns an addressable zero value of the given type.
+func zeroValue(t reflect.Type) reflectValue {
+	return reflectValue{reflect.New(t).Elem(), t}
+}
+
+// writeUsage writes the usage of the struct type t, whose tags are read using
+// the given options. seen contains the struct types of the enclosing blocks,
+// which are not expanded again so that recursive types terminate.
+func writeUsage(b *strings.Builder, o *Options, depth int, name string, t reflect.Type, seen []reflect.Type) error {
+	info, err := extractFields(zeroValue(t), o)
+	if err != nil {
+		return fmt.Errorf("cannot extract fields of %s: %w", t, err)
+	}
+	return writeUsageInfo(b, o, depth, name, info, append(seen, t))
+}
+
+func writeUsageInfo(b *strings.Builder, o *Options, depth int, name string, info structInfo, seen []reflect.Type) error {
+	indent := strings.Repeat("\t", depth)
+
+	// Documentation for arguments is written above the line that they're on.
+	for _, field := range info.otherFields {
+		if doc, ok := optValue(field.opts, "doc"); ok {
+			fmt.Fprintf(b, "%s# <%s>: %s\n", indent, snakeCase(field.field.Name), doc)
+		}
+	}
+
+	b.WriteString(indent)
+	b.WriteString(name)
+
+	if info.matcher != nil {
+		b.WriteString(" [<matcher>]")
+	}
+
+	for _, field := range info.otherFields {
+		var placeholder string
+		switch kind := field.kind.(type) {
+		case argumentKind:
+			placeholder = "<" + snakeCase(field.field.Name) + ">"
+			if kind.matcher {
+				placeholder = "<matcher>"
+			}
+			if isVariadic(field) {
+				placeholder = "<" + snakeCase(field.field.Name) + "...>"
+			}
+		case blockKind:
+			placeholder = "{...}"
+			if kind.variadic {
+				placeholder = "{...}..."
+			}
+		}
+
+		if field.optional() {
+			placeholder = "[" + placeholder + "]"
+		}
+
+		b.WriteString(" ")
+		b.WriteString(placeholder)
+	}
+
+	if isKeyValue(info) || isFlags(info) {
+		for _, field := range info.blockFields {
+			t := derefScalarPointer(optionalElem(field.field.Type))
+			if t.Kind() == reflect.Slice && !isScalar(t) {
+				t = t.Elem()
+			}
+
+			name := field.kind.(blockFieldKind).name
+			switch {
+			case !isScalar(t):
+			case isKeyValue(info):
+				fmt.Fprintf(b, " [%s=<%s>]", name, typeName(t))
+			case t.Kind() == reflect.Bool:
+				fmt.Fprintf(b, " [--%s]", name)
+			default:
+				fmt.Fprintf(b, " [--%s <%s>]", name, typeName(t))
+			}
+		}
+	}
+
+	if info.primary != nil {
+		placeholder := "<" + info.primary.kind.(blockFieldKind).name + ">"
+		if t := info.primary.value.t; t.Kind() == reflect.Slice && !isScalar(t) {
+			placeholder += "..."
+		}
+		b.WriteString(" [" + placeholder + "]")
+	}
+
+	if !info.hasBlock() {
+		b.WriteString("\n")
+		return nil
+	}
+
+	b.WriteString(" {\n")
+
+	for _, field := range info.blockFields {
+		if doc, ok := optValue(field.opts, "doc"); ok {
+			fmt.Fprintf(b, "%s\t# %s\n", indent, doc)
+		}
+
+		name := field.kind.(blockFieldKind).name
+		t := optionalElem(field.field.Type)
+
+		if isKVSlice(t) {
+			fmt.Fprintf(b, "%s\t%s {\n", indent, name)
+			fmt.Fprintf(b, "%s\t\t<key> <%s>\n", indent, typeName(t.Elem().Field(1).Type))
+			fmt.Fprintf(b, "%s\t\t...\n", indent)
+			fmt.Fprintf(b, "%s\t}\n", indent)
+			continue
+		}
+
+		if t.Kind() == reflect.Slice && !isScalar(t) {
+			t = t.Elem()
+		}
+		t = derefScalarPointer(derefStructPointer(t))
+
+		switch {
+		case isSubroute(field.opts):
+			fmt.Fprintf(b, "%s\t%s {\n", indent, name)
+			fmt.Fprintf(b, "%s\t\t<directives...>\n", indent)
+			fmt.Fprintf(b, "%s\t}\n", indent)
+		case t.Kind() == reflect.Struct && !isScalar(t) && containsType(seen, t):
+			// The block is the same as an enclosing one.
+			fmt.Fprintf(b, "%s\t%s ...\n", indent, name)
+		case t.Kind() == reflect.Struct && !isScalar(t):
+			if err := writeUsage(b, o, depth+1, name, t, seen); err != nil {
+				return err
+			}
+		case t == TypeSchemaless:
+			fmt.Fprintf(b, "%s\t%s ...\n", indent, name)
+		case t.Kind() == reflect.Map && hasOpt(field.opts, "keyed"):
+			keyed := name + " <" + typeName(t.Key()) + ">"
+			if elem := derefStructPointer(t.Elem()); containsType(seen, elem) {
+				fmt.Fprintf(b, "%s\t%s ...\n", indent, keyed)
+			} else if elem.Kind() == reflect.Struct && !isScalar(elem) {
+				if err := writeUsage(b, o, depth+1, keyed, elem, seen); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintf(b, "%s\t%s <%s>\n", indent, keyed, typeName(elem))
+			}
+		case t.Kind() == reflect.Map:
+			fmt.Fprintf(b, "%s\t%s {\n", indent, name)
+			fmt.Fprintf(b, "%s\t\t<%s> <%s>\n", indent, typeName(t.Key()), typeName(t.Elem()))
+			fmt.Fprintf(b, "%s\t}\n", indent)
+		case hasVariants(t):
+			fmt.Fprintf(b, "%s\t%s <%s> ...\n", indent, name, strings.Join(variantNames(t), "|"))
+		case isEncodedArray(t, field.opts):
+			fmt.Fprintf(b, "%s\t%s <bytes>\n", indent, name)
+		case t.Kind() == reflect.Array:
+			fmt.Fprintf(b, "%s\t%s%s\n", indent, name,
+				strings.Repeat(" <"+typeName(t.Elem())+">", t.Len()))
+		case t.Kind() == reflect.Bool, isCountFlag(t, field.opts):
+			fmt.Fprintf(b, "%s\t%s\n", indent, name)
+		case t.Kind() == reflect.String && hasEnum(field.opts):
+			enum, _ := optValue(field.opts, "enum")
+			fmt.Fprintf(b, "%s\t%s <%s>\n", indent, name, enum)
+		default:
+			fmt.Fprintf(b, "%s\t%s <%s>\n", indent, name, typeName(t))
+		}
+	}
+
+	switch {
+	case info.remain != nil:
+		fmt.Fprintf(b, "%s\t<subdirective> [<args...>]\n", indent)
+	case info.extras != nil:
+		fmt.Fprintf(b, "%s\t<subdirective> ...\n", indent)
+	}
+
+	b.WriteString(indent)
+	b.WriteString("}\n")
+
+	return nil
+}
+
+// hasEnum returns true if the given options restrict the value using the enum
+// option.
+func hasEnum(opts []string) bool {
+	_, ok := optValue(opts, "enum")
+	return ok
+}
+
+// typeName returns a short human-readable name of the given value type.
+func typeName(t reflect.Type) string {
+	t = derefScalarPointer(optionalElem(t))
+
+	switch {
+	case t.AssignableTo(TypeCaddyDuration), t.AssignableTo(TypeDuration):
+		return "duration"
+	case t.AssignableTo(TypeCaddyAddress):
+		return "address"
+	case t.AssignableTo(TypeCaddyNetworkAddress):
+		return "network_address"
+	case t.AssignableTo(TypeBytes):
+		return "bytes"
+	case t.AssignableTo(TypeTLSCurve):
+		return "curve"
+	case t.AssignableTo(TypeTLSPublicKeyAlgorithm):
+		return "public_key_algorithm"
+	case t.AssignableTo(TypeSchedule):
+		return "schedule"
+	case t.AssignableTo(TypeLocation):
+		return "time_zone"
+	case isAny(t):
+		return "any"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "uint"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	}
+
+	if t.Name() != "" {
+		return snakeCase(t.Name())
+	}
+	return "value"
+}
@@ -0,0 +1,61 @@
+package caddyunmarshal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsage(t *testing.T) {
+	type healthCheck struct {
+		URI      string        `caddyfile:"$1,doc='path to probe, relative to the upstream'"`
+		Interval time.Duration `caddyfile:",doc=\"time between checks\""`
+	}
+
+	type proxy struct {
+		To      string            `caddyfile:"$1"`
+		Port    int               `caddyfile:"$2,optional"`
+		Health  healthCheck       `caddyfile:"health"`
+		Headers map[string]string `caddyfile:"header"`
+		Verbose bool
+		Hide    []string `caddyfile:"hide,doc='files to hide'"`
+		Policy  string   `caddyfile:"lb_policy,enum=first|random"`
+	}
+
+	usage, err := Usage[proxy]("proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const expect = `proxy <to> [<port>] {
+	# <uri>: path to probe, relative to the upstream
+	health <uri> {
+		# time between checks
+		interval <duration>
+	}
+	header {
+		<string> <string>
+	}
+	verbose
+	# files to hide
+	hide <string>
+	lb_policy <first|random>
+}
+`
+	if usage != expect {
+		t.Errorf("unexpected usage:\n%s\nwant:\n%s", usage, expect)
+	}
+}
+
+func TestSplitTag(t *testing.T) {
+	parts := splitTag(`name,optional,doc='a, b',other="c, d"`)
+	if len(parts) != 4 {
+		t.Fatalf("unexpected parts: %q", parts)
+	}
+
+	if doc, _ := optValue(parts[1:], "doc"); doc != "a, b" {
+		t.Errorf("unexpected doc: %q", doc)
+	}
+	if other, _ := optValue(parts[1:], "other"); other != "c, d" {
+		t.Errorf("unexpected other: %q", other)
+	}
+}
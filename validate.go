@@ -0,0 +1,27 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+// validateValue calls the Validate method of the given struct value if it
+// implements caddy.Validator. The error is reported at the given position,
+// which should be where the struct's directive or subdirective begins.
+//
+// Note that Caddy also calls Validate on modules after provisioning them, so
+// modules whose Validate depends on provisioned state should not rely on
+// this.
+func validateValue(r reflectValue, pos Position) error {
+	validator, ok := r.v.Addr().Interface().(caddy.Validator)
+	if !ok {
+		return nil
+	}
+
+	if err := validator.Validate(); err != nil {
+		return fmt.Errorf("%s - Error during parsing: invalid %s: %w", pos, r.t, err)
+	}
+
+	return nil
+}
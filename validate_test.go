@@ -0,0 +1,47 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type validatedRange struct {
+	Min int `caddyfile:"min"`
+	Max int `caddyfile:"max"`
+}
+
+func (r validatedRange) Validate() error {
+	if r.Min > r.Max {
+		return errors.New("min must not exceed max")
+	}
+	return nil
+}
+
+type validatedLimiter struct {
+	Zone  string         `caddyfile:"$1"`
+	Range validatedRange `caddyfile:"range"`
+}
+
+func (l *validatedLimiter) Validate() error {
+	if l.Zone == "default" {
+		return errors.New("zone name is reserved")
+	}
+	return nil
+}
+
+func TestUnmarshalValidate(t *testing.T) {
+	if _, err := unmarshalString[validatedLimiter]("limiter a {\n range {\n min 1\n max 2\n }\n}"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := unmarshalString[validatedLimiter]("limiter default")
+	if err == nil || !strings.Contains(err.Error(), "Testfile:1 - Error during parsing: invalid") {
+		t.Errorf("expected validation error with position, got %v", err)
+	}
+
+	_, err = unmarshalString[validatedLimiter]("limiter a {\n range {\n min 3\n max 2\n }\n}")
+	if err == nil || !strings.Contains(err.Error(), "min must not exceed max") {
+		t.Errorf("expected nested validation error, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// ValueParser parses a single argument into a value of the type it was
+// registered for. The dispenser is positioned at the argument, which is given
+// as raw after placeholders and files are resolved.
+type ValueParser func(d *caddyfile.Dispenser, raw string) (any, error)
+
+var (
+	valueParsersMu sync.RWMutex
+	valueParsers   = map[reflect.Type]ValueParser{}
+	namedParsers   = map[string]ValueParser{}
+)
+
+// RegisterValueParser registers a parser for arguments of the given type, which
+// is then treated like any other scalar type, e.g.
+//
+//	RegisterValueParser(reflect.TypeOf(netip.Prefix{}), func(d *caddyfile.Dispenser, raw string) (any, error) {
+//		return netip.ParsePrefix(raw)
+//	})
+//
+// Registered parsers take precedence over the built-in parsing of the type's
+// kind, but not over caddyfile.Unmarshaler. RegisterValueParser panics if the
+// type is already registered.
+func RegisterValueParser(t reflect.Type, parse ValueParser) {
+	valueParsersMu.Lock()
+	defer valueParsersMu.Unlock()
+
+	if _, ok := valueParsers[t]; ok {
+		panic(fmt.Sprintf("caddyunmarshal: value parser for %s already registered", t))
+	}
+
+	valueParsers[t] = parse
+}
+
+func lookupValueParser(t reflect.Type) (ValueParser, bool) {
+	valueParsersMu.RLock()
+	defer valueParsersMu.RUnlock()
+
+	parse, ok := valueParsers[t]
+	return parse, ok
+}
+
+// hasValueParser returns true if a parser is registered for the given type.
+func hasValueParser(t reflect.Type) bool {
+	_, ok := lookupValueParser(t)
+	return ok
+}
+
+// parseRegisteredValue parses raw into r using the parser registered for its
+// type. It returns false if there's none.
+func parseRegisteredValue(d dispenser, r reflectValue, raw string) (bool, error) {
+	parse, ok := lookupValueParser(r.t)
+	if !ok {
+		return false, nil
+	}
+
+	v, err := parse(d.Dispenser, raw)
+	if err != nil {
+		return true, d.WrapErr(fmt.Errorf("cannot parse %s: %w", typeName(r.t), err))
+	}
+
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || !rv.Type().AssignableTo(r.t) {
+		return true, fmt.Errorf("value parser for %s returned %T", r.t, v)
+	}
+
+	r.v.Set(rv)
+	return true, nil
+}
+
+// RegisterParser registers a parser that fields select by name using the
+// parser option, e.g. `caddyfile:"listen,parser=listen_addr"`, for one-off
+// formats that don't merit their own type. RegisterParser panics if the name
+// is already registered.
+func RegisterParser(name string, parse ValueParser) {
+	valueParsersMu.Lock()
+	defer valueParsersMu.Unlock()
+
+	if _, ok := namedParsers[name]; ok {
+		panic(fmt.Sprintf("caddyunmarshal: parser %q already registered", name))
+	}
+
+	namedParsers[name] = parse
+}
+
+var typeError = reflect.TypeOf((*error)(nil)).Elem()
+
+// unmarshalParserValue parses raw into r using the parser selected by the
+// parser option. The parser is either a method of the struct containing the
+// field, of the form func(raw string) (T, error), or a parser registered using
+// RegisterParser.
+func unmarshalParserValue(d dispenser, r reflectValue, raw, name string) error {
+	var v any
+	var err error
+
+	if method, ok := parserMethod(d.owner, name); ok {
+		out := method.Call([]reflect.Value{reflect.ValueOf(raw)})
+		v = out[0].Interface()
+		err, _ = out[1].Interface().(error)
+	} else {
+		valueParsersMu.RLock()
+		parse, ok := namedParsers[name]
+		valueParsersMu.RUnlock()
+
+		if !ok {
+			return fmt.Errorf("unknown parser %q: not a method of %s nor registered", name, d.owner.t)
+		}
+
+		v, err = parse(d.Dispenser, raw)
+	}
+
+	if err != nil {
+		return d.WrapErr(fmt.Errorf("cannot parse %q: %w", raw, err))
+	}
+
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || !rv.Type().AssignableTo(r.t) {
+		return fmt.Errorf("parser %q returned %T, expected %s", name, v, r.t)
+	}
+
+	r.v.Set(rv)
+	return nil
+}
+
+// parserMethod returns the method of the given struct that is named by the
+// parser option, if it has the signature func(string) (T, error).
+func parserMethod(owner reflectValue, name string) (reflect.Value, bool) {
+	if !owner.v.IsValid() {
+		return reflect.Value{}, false
+	}
+
+	v := owner.v
+	if v.CanAddr() {
+		v = v.Addr()
+	}
+
+	method := v.MethodByName(name)
+	if !method.IsValid() {
+		return reflect.Value{}, false
+	}
+
+	t := method.Type()
+	if t.NumIn() != 1 || t.In(0).Kind() != reflect.String ||
+		t.NumOut() != 2 || t.Out(1) != typeError {
+		return reflect.Value{}, false
+	}
+
+	return method, true
+}
@@ -0,0 +1,109 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type testColor uint32
+
+func init() {
+	RegisterValueParser(reflect.TypeOf(netip.Prefix{}), func(d *caddyfile.Dispenser, raw string) (any, error) {
+		return netip.ParsePrefix(raw)
+	})
+	RegisterValueParser(reflect.TypeOf(testColor(0)), func(d *caddyfile.Dispenser, raw string) (any, error) {
+		var c testColor
+		if _, err := fmt.Sscanf(raw, "%06x", &c); err != nil {
+			return nil, err
+		}
+		return c, nil
+	})
+}
+
+func TestRegisterValueParser(t *testing.T) {
+	type firewall struct {
+		Source netip.Prefix   `caddyfile:"$1"`
+		Allow  []netip.Prefix `caddyfile:"allow"`
+		Color  testColor      `caddyfile:"color"`
+	}
+
+	v, err := unmarshalString[firewall]("firewall 10.0.0.0/8 {\n allow 192.168.0.0/16 fd00::/8\n color ff0000\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Source != netip.MustParsePrefix("10.0.0.0/8") {
+		t.Errorf("unexpected source: %v", v.Source)
+	}
+	if len(v.Allow) != 2 || v.Allow[1] != netip.MustParsePrefix("fd00::/8") {
+		t.Errorf("unexpected prefixes: %v", v.Allow)
+	}
+	if v.Color != 0xff0000 {
+		t.Errorf("unexpected color: %x", v.Color)
+	}
+
+	_, err = unmarshalString[firewall]("firewall 10.0.0.0")
+	if err == nil || !strings.Contains(err.Error(), "cannot parse prefix") {
+		t.Errorf("expected parse error, got %v", err)
+	}
+
+	if !isScalar(reflect.TypeOf(netip.Prefix{})) {
+		t.Error("expected registered struct type to be a scalar")
+	}
+}
+
+func TestRegisterValueParserDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate registration")
+		}
+	}()
+
+	RegisterValueParser(reflect.TypeOf(testColor(0)), nil)
+}
+
+type listener struct {
+	Listen []int  `caddyfile:"listen,parser=ParsePort"`
+	Host   string `caddyfile:"$1,parser=lower"`
+	Bad    string `caddyfile:"bad,parser=Unknown"`
+}
+
+func (listener) ParsePort(raw string) (int, error) {
+	var port int
+	if _, err := fmt.Sscanf(raw, ":%d", &port); err != nil {
+		return 0, err
+	}
+	return port, nil
+}
+
+func init() {
+	RegisterParser("lower", func(d *caddyfile.Dispenser, raw string) (any, error) {
+		return strings.ToLower(raw), nil
+	})
+}
+
+func TestUnmarshalParserOption(t *testing.T) {
+	v, err := unmarshalString[listener]("listener Example.COM {\n listen :80 :443\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Host != "example.com" || !reflect.DeepEqual(v.Listen, []int{80, 443}) {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	_, err = unmarshalString[listener]("listener a {\n listen 80\n}")
+	if err == nil || !strings.Contains(err.Error(), `cannot parse "80"`) {
+		t.Errorf("expected parse error, got %v", err)
+	}
+
+	_, err = unmarshalString[listener]("listener a {\n bad x\n}")
+	if err == nil || !strings.Contains(err.Error(), `unknown parser "Unknown"`) {
+		t.Errorf("expected unknown parser error, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package caddyunmarshal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	variantsMu sync.RWMutex
+	variants   = map[reflect.Type]map[string]reflect.Type{}
+)
+
+// RegisterVariant registers the struct type T as an implementation of the
+// interface I, selected by the given name. Fields of type I are then
+// unmarshaled by taking the first argument as the variant name, allocating
+// the matching T, and unmarshaling the rest of the line and its block into
+// it. For example, with
+//
+//	RegisterVariant[LBPolicy, RoundRobin]("round_robin")
+//	RegisterVariant[LBPolicy, Weighted]("weighted")
+//
+// a field `Policy LBPolicy` accepts both "policy round_robin" and
+// "policy weighted { ... }".
+//
+// If T implements I, then the field is set to a T. Otherwise, *T must
+// implement I, and the field is set to a *T. RegisterVariant panics if
+// neither does, if I is not an interface, or if the name is already
+// registered for I.
+func RegisterVariant[I, T any](name string) {
+	iface := reflect.TypeOf((*I)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("caddyunmarshal: variant of non-interface type %s", iface))
+	}
+
+	impl := reflect.TypeOf((*T)(nil)).Elem()
+	if impl.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("caddyunmarshal: variant %s must be a struct", impl))
+	}
+
+	switch {
+	case impl.Implements(iface):
+	case reflect.PtrTo(impl).Implements(iface):
+		impl = reflect.PtrTo(impl)
+	default:
+		panic(fmt.Sprintf("caddyunmarshal: variant %s does not implement %s", impl, iface))
+	}
+
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+
+	named, ok := variants[iface]
+	if !ok {
+		named = make(map[string]reflect.Type)
+		variants[iface] = named
+	}
+
+	if _, ok := named[name]; ok {
+		panic(fmt.Sprintf("caddyunmarshal: variant %q of %s already registered", name, iface))
+	}
+
+	named[name] = impl
+}
+
+// variantNames returns the sorted names of all variants registered for the
+// given interface type. It returns nil if there are none.
+func variantNames(iface reflect.Type) []string {
+	variantsMu.RLock()
+	defer variantsMu.RUnlock()
+
+	named := variants[iface]
+	if len(named) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func lookupVariant(iface reflect.Type, name string) (reflect.Type, bool) {
+	variantsMu.RLock()
+	defer variantsMu.RUnlock()
+
+	impl, ok := variants[iface][name]
+	return impl, ok
+}
+
+// variantName returns the name that the given implementation is registered
+// under for the given interface type.
+func variantName(iface, impl reflect.Type) (string, bool) {
+	variantsMu.RLock()
+	defer variantsMu.RUnlock()
+
+	for name, t := range variants[iface] {
+		if t == impl {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// hasVariants returns true if the given type is an interface with registered
+// variants.
+func hasVariants(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface && len(variantNames(t)) > 0
+}
+
+// unmarshalVariant unmarshals the rest of the current line into the variant
+// named by the next argument.
+func unmarshalVariant(d dispenser, r reflectValue) error {
+	if !d.NextArg() {
+		return d.ArgErr()
+	}
+
+	name := d.Val()
+
+	impl, ok := lookupVariant(r.t, name)
+	if !ok {
+		return d.WrapErr(fmt.Errorf(
+			"unknown variant %q, expected one of: %s",
+			name, strings.Join(variantNames(r.t), ", ")))
+	}
+
+	d.trace("variant", nil)
+
+	var value reflect.Value
+	if impl.Kind() == reflect.Pointer {
+		value = reflect.New(impl.Elem())
+	} else {
+		value = reflect.New(impl)
+	}
+
+	elem := value.Elem()
+	if err := unmarshal(d, reflectValue{elem, elem.Type()}); err != nil {
+		return errorAtName(name, err)
+	}
+
+	if impl.Kind() == reflect.Pointer {
+		r.v.Set(value)
+	} else {
+		r.v.Set(elem)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package caddyunmarshal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testPolicy interface {
+	testPolicy()
+}
+
+type testRoundRobin struct{}
+
+type testWeighted struct {
+	Weights []int `caddyfile:"weight"`
+}
+
+type testHeader struct {
+	Field string `caddyfile:"$1"`
+}
+
+func (*testRoundRobin) testPolicy() {}
+func (*testWeighted) testPolicy()   {}
+func (testHeader) testPolicy()      {}
+
+func init() {
+	RegisterVariant[testPolicy, testRoundRobin]("round_robin")
+	RegisterVariant[testPolicy, testWeighted]("weighted")
+	RegisterVariant[testPolicy, testHeader]("header")
+}
+
+func TestUnmarshalVariant(t *testing.T) {
+	type proxy struct {
+		Policy    testPolicy   `caddyfile:"policy"`
+		Fallbacks []testPolicy `caddyfile:"fallback"`
+	}
+
+	v, err := unmarshalString[proxy](`
+		proxy {
+			policy weighted {
+				weight 1
+				weight 3
+			}
+			fallback header X-Upstream
+			fallback round_robin
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := proxy{
+		Policy: &testWeighted{Weights: []int{1, 3}},
+		Fallbacks: []testPolicy{
+			testHeader{Field: "X-Upstream"},
+			&testRoundRobin{},
+		},
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Errorf("unexpected value:\ngot  %#v\nwant %#v", v, expect)
+	}
+
+	_, err = unmarshalString[proxy]("proxy {\n policy random\n}")
+	if err == nil || !strings.Contains(err.Error(), "expected one of: header, round_robin, weighted") {
+		t.Errorf("expected unknown variant error, got %v", err)
+	}
+}
+
+func TestRegisterVariantPanics(t *testing.T) {
+	tests := map[string]func(){
+		"duplicate":     func() { RegisterVariant[testPolicy, testRoundRobin]("round_robin") },
+		"not interface": func() { RegisterVariant[testHeader, testRoundRobin]("x") },
+		"unimplemented": func() { RegisterVariant[testPolicy, struct{}]("x") },
+	}
+
+	for name, register := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			register()
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package caddyunmarshal
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unmarshalWeakValue applies the coercions of Options.WeaklyTyped to raw. It
+// returns false if none apply, in which case raw is parsed as usual:
+//
+//   - numbers may be surrounded by spaces, e.g. from a quoted " 8080 "
+//   - integers may be written as whole floats, e.g. "8080.0" or "1e3"
+//   - booleans may be written as 1 or 0, even if restricted by the bool option
+//   - durations may be written as plain numbers in Options.DurationUnit
+func unmarshalWeakValue(d dispenser, r reflectValue, raw string, opts []string) (bool, error) {
+	trimmed := strings.TrimSpace(raw)
+
+	switch {
+	case r.t.AssignableTo(TypeCaddyDuration), r.t.AssignableTo(TypeDuration):
+		n, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return false, nil
+		}
+
+		dura := time.Duration(n * float64(d.options.durationUnit()))
+		if err := checkDurationRange(dura, opts); err != nil {
+			return true, d.WrapErr(err)
+		}
+
+		r.v.SetInt(int64(dura))
+		return true, nil
+	}
+
+	switch r.v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, err := parseInt(trimmed, r.t.Bits()); err == nil {
+			r.v.SetInt(i)
+			return true, nil
+		}
+
+		f, ok := wholeFloat(trimmed)
+		if !ok || f < math.MinInt64 || f >= math.MaxInt64 || r.v.OverflowInt(int64(f)) {
+			return false, nil
+		}
+
+		r.v.SetInt(int64(f))
+		return true, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u, err := parseUint(trimmed, r.t.Bits()); err == nil {
+			r.v.SetUint(u)
+			return true, nil
+		}
+
+		f, ok := wholeFloat(trimmed)
+		if !ok || f < 0 || f >= math.MaxUint64 || r.v.OverflowUint(uint64(f)) {
+			return false, nil
+		}
+
+		r.v.SetUint(uint64(f))
+		return true, nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(trimmed, r.t.Bits())
+		if err != nil {
+			return false, nil
+		}
+
+		r.v.SetFloat(f)
+		return true, nil
+
+	case reflect.Bool:
+		switch trimmed {
+		case "1":
+			r.v.SetBool(true)
+		case "0":
+			r.v.SetBool(false)
+		default:
+			return false, nil
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// wholeFloat parses a float that has no fractional part.
+func wholeFloat(raw string) (float64, bool) {
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || f != math.Trunc(f) {
+		return 0, false
+	}
+	return f, true
+}
@@ -0,0 +1,65 @@
+package caddyunmarshal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+)
+
+type weakServer struct {
+	Port     int            `caddyfile:"port"`
+	Workers  uint8          `caddyfile:"workers"`
+	Ratio    float64        `caddyfile:"ratio"`
+	Compress bool           `caddyfile:"compress,bool=on|off"`
+	Timeout  caddy.Duration `caddyfile:"timeout"`
+	Interval time.Duration  `caddyfile:"interval"`
+}
+
+func TestUnmarshalWeaklyTyped(t *testing.T) {
+	const input = "server {\n port \" 8080 \"\n workers 4.0\n ratio \" 0.5\"\n compress 1\n timeout 30\n interval 1.5\n}"
+
+	_, err := unmarshalString[weakServer](input)
+	if err == nil {
+		t.Fatal("expected error without WeaklyTyped")
+	}
+
+	v, err := unmarshalStringWithOptions[weakServer](input, Options{WeaklyTyped: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := weakServer{
+		Port:     8080,
+		Workers:  4,
+		Ratio:    0.5,
+		Compress: true,
+		Timeout:  caddy.Duration(30 * time.Second),
+		Interval: 1500 * time.Millisecond,
+	}
+	if v != expect {
+		t.Errorf("unexpected value:\n got %+v\nwant %+v", v, expect)
+	}
+
+	v, err = unmarshalStringWithOptions[weakServer](
+		"server {\n timeout 250\n compress off\n}",
+		Options{WeaklyTyped: true, DurationUnit: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Timeout != caddy.Duration(250*time.Millisecond) || v.Compress {
+		t.Errorf("unexpected value: %+v", v)
+	}
+
+	tests := []string{
+		"server {\n port 80.5\n}",
+		"server {\n workers 256.0\n}",
+		"server {\n workers -1.0\n}",
+		"server {\n compress 2\n}",
+	}
+	for _, input := range tests {
+		if _, err := unmarshalStringWithOptions[weakServer](input, Options{WeaklyTyped: true}); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package caddyunmarshal
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// Wrap is an adapter that implements caddyfile.Unmarshaler for a bound *T
+// using Unmarshal. Use Bind to create one.
+//
+// Wrap is meant to be embedded into a module struct, which cuts the
+// UnmarshalCaddyfile boilerplate entirely:
+//
+//	type MyModule struct {
+//		caddyunmarshal.Wrap[MyModule] `json:"-"`
+//
+//		Root string `json:"root" caddyfile:"$1"`
+//	}
+//
+//	func (MyModule) CaddyModule() caddy.ModuleInfo {
+//		return caddy.ModuleInfo{
+//			ID: "http.handlers.my_module",
+//			New: func() caddy.Module {
+//				m := new(MyModule)
+//				m.Wrap = caddyunmarshal.Bind(m)
+//				return m
+//			},
+//		}
+//	}
+//
+// Note that the binding is to the pointer given to Bind, so copying the
+// module struct does not rebind it. Embedded Wrap fields are ignored when
+// unmarshaling.
+type Wrap[T any] struct {
+	v *T
+}
+
+// Bind binds the given pointer to a new Wrap.
+func Bind[T any](v *T) Wrap[T] {
+	return Wrap[T]{v}
+}
+
+// UnmarshalCaddyfile implements caddyfile.Unmarshaler. It consumes the
+// directive name for each occurrence of the directive, then unmarshals the
+// rest of its segment into the bound value.
+func (w Wrap[T]) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	if w.v == nil {
+		return errors.New("caddyunmarshal: Wrap is not bound, use Bind")
+	}
+
+	for d.Next() {
+		if err := Unmarshal(d, w.v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w Wrap[T]) wrap() {}
+
+var typeWrap = reflect.TypeOf((*interface{ wrap() })(nil)).Elem()
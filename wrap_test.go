@@ -0,0 +1,45 @@
+package caddyunmarshal
+
+import (
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+type testWrapModule struct {
+	Wrap[testWrapModule] `json:"-"`
+
+	Root string `caddyfile:"$1"`
+	Size int    `caddyfile:"size"`
+}
+
+func newTestWrapModule() *testWrapModule {
+	m := new(testWrapModule)
+	m.Wrap = Bind(m)
+	return m
+}
+
+func TestWrap(t *testing.T) {
+	m := newTestWrapModule()
+
+	var unmarshaler caddyfile.Unmarshaler = m
+	d := caddyfile.NewTestDispenser(`
+		my_module /var/www {
+			size 5
+		}
+	`)
+	if err := unmarshaler.UnmarshalCaddyfile(d); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Root != "/var/www" || m.Size != 5 {
+		t.Errorf("unexpected value: %+v", m)
+	}
+}
+
+func TestWrapUnbound(t *testing.T) {
+	var m testWrapModule
+	if err := m.UnmarshalCaddyfile(caddyfile.NewTestDispenser(`my_module a`)); err == nil {
+		t.Fatal("expected error for unbound Wrap")
+	}
+}